@@ -2,16 +2,39 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
 )
 
-const algoliaBaseURL = "https://hn.algolia.com/api/v1"
-
 // AlgoliaResponse is the search response from the Algolia HN API.
 type AlgoliaResponse struct {
-	Hits []AlgoliaHit `json:"hits"`
+	Hits        []AlgoliaHit `json:"hits"`
+	NbHits      int          `json:"nbHits"`
+	Page        int          `json:"page"`
+	NbPages     int          `json:"nbPages"`
+	HitsPerPage int          `json:"hitsPerPage"`
+}
+
+// SearchParams describes a full-text search against Algolia.
+type SearchParams struct {
+	Query          string
+	Tags           string // e.g. "story", "comment" or "(story,comment)"
+	NumericFilters string // e.g. "points>100,created_at_i>1700000000"
+	ByDate         bool   // use /search_by_date instead of relevance ranking
+	AdvancedSyntax bool   // honour "quoted phrases" in Query
+	RestrictTo     string // restrictSearchableAttributes, e.g. "url"
+	Page           int    // 0-indexed
+	HitsPerPage    int
+}
+
+// SearchResult is one page of search hits.
+type SearchResult struct {
+	Items   []*Item
+	Page    int
+	NbPages int
+	NbHits  int
 }
 
 // AlgoliaHit is a single search result.
@@ -71,7 +94,7 @@ func (c *Client) GetPastStories(ctx context.Context, limit int) ([]*Item, error)
 	params.Set("tags", "front_page")
 	params.Set("numericFilters", fmt.Sprintf("created_at_i>%d,created_at_i<%d", startOfYesterday.Unix(), endOfYesterday.Unix()))
 	params.Set("hitsPerPage", fmt.Sprintf("%d", limit))
-	reqURL := algoliaBaseURL + "/search?" + params.Encode()
+	reqURL := c.endpoints.Algolia + "/search?" + params.Encode()
 
 	var resp AlgoliaResponse
 	if err := c.get(ctx, reqURL, &resp); err != nil {
@@ -85,11 +108,60 @@ func (c *Client) GetPastStories(ctx context.Context, limit int) ([]*Item, error)
 	return items, nil
 }
 
+// Search runs a full-text query against Algolia and returns one page of
+// story and comment hits. Comment hits carry their story's title and ID in
+// StoryTitle and StoryID.
+func (c *Client) Search(ctx context.Context, p SearchParams) (*SearchResult, error) {
+	endpoint := "/search"
+	if p.ByDate {
+		endpoint = "/search_by_date"
+	}
+
+	params := url.Values{}
+	params.Set("query", p.Query)
+	if p.Tags != "" {
+		params.Set("tags", p.Tags)
+	}
+	if p.NumericFilters != "" {
+		params.Set("numericFilters", p.NumericFilters)
+	}
+	if p.RestrictTo != "" {
+		params.Set("restrictSearchableAttributes", p.RestrictTo)
+	}
+	if p.AdvancedSyntax {
+		params.Set("advancedSyntax", "true")
+	}
+	if p.HitsPerPage > 0 {
+		params.Set("hitsPerPage", fmt.Sprintf("%d", p.HitsPerPage))
+	}
+	params.Set("page", fmt.Sprintf("%d", p.Page))
+	reqURL := c.endpoints.Algolia + endpoint + "?" + params.Encode()
+
+	var resp AlgoliaResponse
+	if err := c.get(ctx, reqURL, &resp); err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+
+	items := make([]*Item, 0, len(resp.Hits))
+	for _, hit := range resp.Hits {
+		item := hit.ToItem()
+		item.StoryTitle = hit.StoryTitle
+		item.StoryID = hit.StoryID
+		items = append(items, item)
+	}
+	return &SearchResult{
+		Items:   items,
+		Page:    resp.Page,
+		NbPages: resp.NbPages,
+		NbHits:  resp.NbHits,
+	}, nil
+}
+
 // GetNewestComments fetches the newest comments site-wide via Algolia.
 // page is 0-indexed for Algolia pagination.
 func (c *Client) GetNewestComments(ctx context.Context, limit int, page int) ([]*Item, error) {
 	url := fmt.Sprintf("%s/search_by_date?tags=comment&hitsPerPage=%d&page=%d",
-		algoliaBaseURL, limit, page)
+		c.endpoints.Algolia, limit, page)
 
 	var resp AlgoliaResponse
 	if err := c.get(ctx, url, &resp); err != nil {
@@ -112,7 +184,7 @@ func (c *Client) GetNewestComments(ctx context.Context, limit int, page int) ([]
 // /threads?id=username page. Each comment includes the parent story title.
 func (c *Client) GetUserThreads(ctx context.Context, username string, limit int) ([]*Item, error) {
 	url := fmt.Sprintf("%s/search_by_date?tags=comment,author_%s&hitsPerPage=%d",
-		algoliaBaseURL, username, limit)
+		c.endpoints.Algolia, username, limit)
 
 	var resp AlgoliaResponse
 	if err := c.get(ctx, url, &resp); err != nil {
@@ -130,3 +202,82 @@ func (c *Client) GetUserThreads(ctx context.Context, username string, limit int)
 	}
 	return items, nil
 }
+
+// AlgoliaItem is an item with its whole reply tree, as returned by
+// Algolia's /items/{id} endpoint.
+type AlgoliaItem struct {
+	ID         int           `json:"id"`
+	Type       string        `json:"type"`
+	Author     string        `json:"author"`
+	CreatedAtI int64         `json:"created_at_i"`
+	Title      string        `json:"title"`
+	URL        string        `json:"url"`
+	Text       string        `json:"text"`
+	Points     int           `json:"points"`
+	ParentID   int           `json:"parent_id"`
+	Children   []AlgoliaItem `json:"children"`
+}
+
+// GetItemTree fetches an item and every reply under it in one request,
+// returning them as Items with the root first and each item before its
+// replies. Kids keep Algolia's order (oldest first), not HN's ranking,
+// and the tree can lag HN by a few minutes. Like Firebase's, the root's
+// Descendants doesn't count deleted comments.
+func (c *Client) GetItemTree(ctx context.Context, id int) ([]*Item, error) {
+	var root AlgoliaItem
+	if err := c.get(ctx, fmt.Sprintf("%s/items/%d", c.endpoints.Algolia, id), &root); err != nil {
+		return nil, fmt.Errorf("fetching item tree: %w", err)
+	}
+	if root.ID == 0 {
+		return nil, fmt.Errorf("fetching item tree: item %d not found", id)
+	}
+
+	var items []*Item
+	var walk func(a *AlgoliaItem) int
+	walk = func(a *AlgoliaItem) int {
+		item := a.toItem()
+		items = append(items, item)
+		descendants := 0
+		for i := range a.Children {
+			if !a.Children[i].deleted() {
+				descendants++
+			}
+			descendants += walk(&a.Children[i])
+		}
+		if item.Type != "comment" {
+			item.Descendants = descendants
+		}
+		return descendants
+	}
+	walk(&root)
+	return items, nil
+}
+
+// deleted reports whether a is a deleted comment. They keep their place
+// in the tree but lose their author and text.
+func (a *AlgoliaItem) deleted() bool {
+	return a.Type == "comment" && a.Author == "" && a.Text == ""
+}
+
+func (a *AlgoliaItem) toItem() *Item {
+	item := &Item{
+		ID:     a.ID,
+		Type:   a.Type,
+		By:     a.Author,
+		Time:   a.CreatedAtI,
+		Title:  a.Title,
+		URL:    a.URL,
+		Text:   a.Text,
+		Score:  a.Points,
+		Parent: a.ParentID,
+	}
+	item.Deleted = a.deleted()
+	if len(a.Children) > 0 {
+		kids := make([]int, len(a.Children))
+		for i, child := range a.Children {
+			kids[i] = child.ID
+		}
+		item.RawKids, _ = json.Marshal(kids)
+	}
+	return item
+}
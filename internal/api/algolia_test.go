@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// itemTreeJSON is shaped like Algolia's /items/{id}: null fields on
+// comments, children oldest first, and a deleted comment (no author, no
+// text) that still has a live reply.
+const itemTreeJSON = `{
+	"id": 100, "type": "story", "author": "pg", "created_at_i": 1000,
+	"title": "Ask HN: Anything", "url": null, "text": null, "points": 42, "parent_id": null,
+	"children": [
+		{"id": 103, "type": "comment", "author": "a", "created_at_i": 1003, "title": null, "url": null,
+		 "text": "<p>first</p>", "points": null, "parent_id": 100, "children": [
+			{"id": 110, "type": "comment", "author": "b", "created_at_i": 1010,
+			 "text": "nested", "parent_id": 103, "children": []}
+		]},
+		{"id": 101, "type": "comment", "author": null, "created_at_i": 1001,
+		 "text": null, "parent_id": 100, "children": [
+			{"id": 111, "type": "comment", "author": "c", "created_at_i": 1011,
+			 "text": "under a deleted one", "parent_id": 101, "children": []}
+		]},
+		{"id": 102, "type": "comment", "author": "d", "created_at_i": 1002,
+		 "text": "", "parent_id": 100, "children": []}
+	]
+}`
+
+func TestAlgoliaItemToItem(t *testing.T) {
+	tests := []struct {
+		name string
+		in   AlgoliaItem
+		want Item
+	}{
+		{
+			"story",
+			AlgoliaItem{ID: 1, Type: "story", Author: "pg", CreatedAtI: 5, Title: "T", URL: "https://e.com", Points: 3},
+			Item{ID: 1, Type: "story", By: "pg", Time: 5, Title: "T", URL: "https://e.com", Score: 3},
+		},
+		{
+			"story without text isn't deleted",
+			AlgoliaItem{ID: 1, Type: "story", Title: "T"},
+			Item{ID: 1, Type: "story", Title: "T"},
+		},
+		{
+			"comment",
+			AlgoliaItem{ID: 2, Type: "comment", Author: "a", Text: "hi", ParentID: 1},
+			Item{ID: 2, Type: "comment", By: "a", Text: "hi", Parent: 1},
+		},
+		{
+			"deleted comment",
+			AlgoliaItem{ID: 3, Type: "comment", ParentID: 1},
+			Item{ID: 3, Type: "comment", Parent: 1, Deleted: true},
+		},
+		{
+			"comment with an author but no text",
+			AlgoliaItem{ID: 4, Type: "comment", Author: "a", ParentID: 1},
+			Item{ID: 4, Type: "comment", By: "a", Parent: 1},
+		},
+		{
+			"comment with text but no author",
+			AlgoliaItem{ID: 5, Type: "comment", Text: "hi", ParentID: 1},
+			Item{ID: 5, Type: "comment", Text: "hi", Parent: 1},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.in.toItem()
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: toItem() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	parent := AlgoliaItem{ID: 1, Type: "story", Children: []AlgoliaItem{{ID: 9}, {ID: 7}, {ID: 8}}}
+	if kids := parent.toItem().Kids(); !reflect.DeepEqual(kids, []int{9, 7, 8}) {
+		t.Errorf("Kids() = %v, want children in Algolia's order", kids)
+	}
+}
+
+func TestGetItemTree(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items/100" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Not Found","status":404}`))
+			return
+		}
+		w.Write([]byte(itemTreeJSON))
+	}))
+	defer srv.Close()
+	c := NewClient(Options{Endpoints: Endpoints{Algolia: srv.URL}, Transport: http.DefaultTransport})
+
+	items, err := c.GetItemTree(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Root first, then depth first with each item before its replies.
+	var order []int
+	byID := make(map[int]*Item)
+	for _, it := range items {
+		order = append(order, it.ID)
+		byID[it.ID] = it
+	}
+	if want := []int{100, 103, 110, 101, 111, 102}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	root := byID[100]
+	if root.Title != "Ask HN: Anything" || root.Score != 42 || root.By != "pg" {
+		t.Errorf("root = %+v", root)
+	}
+	if kids := root.Kids(); !reflect.DeepEqual(kids, []int{103, 101, 102}) {
+		t.Errorf("root kids = %v, want [103 101 102]", kids)
+	}
+	// Five replies, one of them deleted.
+	if root.Descendants != 4 {
+		t.Errorf("root descendants = %d, want 4", root.Descendants)
+	}
+
+	for id, deleted := range map[int]bool{103: false, 110: false, 101: true, 111: false, 102: false} {
+		if byID[id].Deleted != deleted {
+			t.Errorf("item %d Deleted = %v, want %v", id, byID[id].Deleted, deleted)
+		}
+	}
+	if kids := byID[101].Kids(); !reflect.DeepEqual(kids, []int{111}) {
+		t.Errorf("deleted comment's kids = %v, want its live reply", kids)
+	}
+	if it := byID[110]; it.Parent != 103 || it.Text != "nested" || it.Time != 1010 || it.Descendants != 0 {
+		t.Errorf("item 110 = %+v", it)
+	}
+
+	if _, err := c.GetItemTree(context.Background(), 5); err == nil {
+		t.Error("GetItemTree of a missing item succeeded")
+	}
+}
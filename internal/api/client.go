@@ -3,35 +3,97 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"sync"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
+
+	"github.com/fragmede/nitpick/internal/transport"
 )
 
 const (
-	baseURL        = "https://hacker-news.firebaseio.com/v0"
 	requestTimeout = 10 * time.Second
 	maxConcurrent  = 10
 )
 
+// Endpoints are the base URLs of the services nitpick talks to, without
+// trailing slashes.
+type Endpoints struct {
+	Firebase string // the official HN API
+	Algolia  string // HN Search, for search, past stories and comment trees
+	HN       string // the website, scraped for threads and used to log in
+}
+
+// DefaultEndpoints are the production services.
+var DefaultEndpoints = Endpoints{
+	Firebase: "https://hacker-news.firebaseio.com/v0",
+	Algolia:  "https://hn.algolia.com/api/v1",
+	HN:       "https://news.ycombinator.com",
+}
+
+// ItemURL returns the item's page on the HN website.
+func (e Endpoints) ItemURL(id int) string {
+	return fmt.Sprintf("%s/item?id=%d", e.HN, id)
+}
+
+// withDefaults fills in empty endpoints from DefaultEndpoints.
+func (e Endpoints) withDefaults() Endpoints {
+	e.Firebase = orDefault(e.Firebase, DefaultEndpoints.Firebase)
+	e.Algolia = orDefault(e.Algolia, DefaultEndpoints.Algolia)
+	e.HN = orDefault(e.HN, DefaultEndpoints.HN)
+	return e
+}
+
+func orDefault(url, def string) string {
+	if url = strings.TrimRight(url, "/"); url == "" {
+		return def
+	}
+	return url
+}
+
+// Options configures a Client. Zero fields take the defaults.
+type Options struct {
+	Endpoints Endpoints         // empty endpoints default to DefaultEndpoints
+	Transport http.RoundTripper // default transport.Default
+}
+
 // Client is the HN API client.
 type Client struct {
-	http *http.Client
+	http      *http.Client
+	endpoints Endpoints
+	conn      connectivity
+	bytesRead atomic.Int64
 }
 
 // NewClient creates a new HN API client.
-func NewClient() *Client {
+func NewClient(opts Options) *Client {
+	rt := opts.Transport
+	if rt == nil {
+		rt = transport.Default
+	}
 	return &Client{
-		http: &http.Client{
-			Timeout: requestTimeout,
-		},
+		// Timeouts, retries and rate limits are the transport's.
+		http:      &http.Client{Transport: rt},
+		endpoints: opts.Endpoints.withDefaults(),
 	}
 }
 
+// Endpoints returns the base URLs the client uses.
+func (c *Client) Endpoints() Endpoints {
+	return c.endpoints
+}
+
+// Transport returns the client's round tripper, for an auth.Session that
+// should share it.
+func (c *Client) Transport() http.RoundTripper {
+	return c.http.Transport
+}
+
 // get fetches a URL and decodes the JSON response into dst.
 func (c *Client) get(ctx context.Context, url string, dst interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -40,7 +102,7 @@ func (c *Client) get(ctx context.Context, url string, dst interface{}) error {
 	}
 	req.Header.Set("User-Agent", "nitpick/1.0")
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("fetching %s: %w", url, err)
 	}
@@ -57,21 +119,47 @@ func (c *Client) get(ctx context.Context, url string, dst interface{}) error {
 	return nil
 }
 
+// BytesRead returns the total size of response bodies read so far.
+func (c *Client) BytesRead() int64 {
+	return c.bytesRead.Load()
+}
+
+// countingBody adds the bytes read from a response body to a counter.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (b countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// ErrNotFound is returned for an item HN doesn't have. Firebase answers
+// those with null rather than a 404.
+var ErrNotFound = errors.New("not found")
+
 // GetItem fetches a single item by ID.
 func (c *Client) GetItem(ctx context.Context, id int) (*Item, error) {
-	url := fmt.Sprintf("%s/item/%d.json", baseURL, id)
+	url := fmt.Sprintf("%s/item/%d.json", c.endpoints.Firebase, id)
 	var item Item
 	if err := c.get(ctx, url, &item); err != nil {
 		return nil, err
 	}
+	if item.ID == 0 {
+		return nil, fmt.Errorf("item %d: %w", id, ErrNotFound)
+	}
 	return &item, nil
 }
 
 // BatchGetItems fetches multiple items concurrently with a concurrency limit.
-// Returns items in the same order as the input IDs. Failed fetches are nil.
+// Returns items in the same order as the input IDs. Failed fetches are nil,
+// and reported in a *BatchError alongside the items that did load. Items
+// that don't exist are nil too, but aren't failures.
 func (c *Client) BatchGetItems(ctx context.Context, ids []int) ([]*Item, error) {
 	results := make([]*Item, len(ids))
-	var mu sync.Mutex
+	errs := make([]error, len(ids))
 
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(maxConcurrent)
@@ -79,27 +167,57 @@ func (c *Client) BatchGetItems(ctx context.Context, ids []int) ([]*Item, error)
 	for i, id := range ids {
 		i, id := i, id
 		g.Go(func() error {
-			item, err := c.GetItem(ctx, id)
-			if err != nil {
-				// Non-fatal: individual items can fail.
-				return nil
-			}
-			mu.Lock()
-			results[i] = item
-			mu.Unlock()
+			// Non-fatal: individual items can fail.
+			results[i], errs[i] = c.GetItem(ctx, id)
 			return nil
 		})
 	}
-
-	if err := g.Wait(); err != nil {
-		return nil, err
+	g.Wait()
+
+	batchErr := &BatchError{Total: len(ids)}
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			batchErr.Failed = append(batchErr.Failed, ids[i])
+			batchErr.Errs = append(batchErr.Errs, err)
+		}
+	}
+	if len(batchErr.Failed) > 0 {
+		return results, batchErr
 	}
 	return results, nil
 }
 
+// BatchError lists the items a BatchGetItems call couldn't fetch.
+type BatchError struct {
+	Failed []int   // item IDs, in request order
+	Errs   []error // why each failed, parallel to Failed
+	Total  int     // items requested
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d items failed to load: %v", len(e.Failed), e.Total, e.Errs[0])
+}
+
+// Unwrap exposes the individual errors, e.g. to errors.Is(err, ErrOffline).
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// Partial reports whether some of the items loaded despite the failures.
+func (e *BatchError) Partial() bool {
+	return len(e.Failed) < e.Total
+}
+
+// IsPartial reports whether err is a BatchError with some items loaded,
+// so the results are worth using.
+func IsPartial(err error) bool {
+	var be *BatchError
+	return errors.As(err, &be) && be.Partial()
+}
+
 // GetUser fetches a user profile by username.
 func (c *Client) GetUser(ctx context.Context, username string) (*User, error) {
-	url := fmt.Sprintf("%s/user/%s.json", baseURL, username)
+	url := fmt.Sprintf("%s/user/%s.json", c.endpoints.Firebase, username)
 	var user User
 	if err := c.get(ctx, url, &user); err != nil {
 		return nil, err
@@ -109,7 +227,7 @@ func (c *Client) GetUser(ctx context.Context, username string) (*User, error) {
 
 // GetMaxItem returns the current largest item ID.
 func (c *Client) GetMaxItem(ctx context.Context) (int, error) {
-	url := baseURL + "/maxitem.json"
+	url := c.endpoints.Firebase + "/maxitem.json"
 	var maxID int
 	if err := c.get(ctx, url, &maxID); err != nil {
 		return 0, err
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/transport"
+)
+
+// ErrOffline is returned without touching the network while the client
+// considers itself offline. Callers should fall back to cached data.
+var ErrOffline = errors.New("offline: no network connection")
+
+// offlineAfter is how many consecutive network failures mark the client
+// offline. A single timeout on a flaky link shouldn't flip the indicator.
+const offlineAfter = 3
+
+// connectivity tracks whether HN is reachable.
+type connectivity struct {
+	mu          sync.Mutex
+	failures    int
+	offline     bool
+	lastSuccess time.Time
+	onChange    func(offline bool)
+}
+
+// record updates the state after a request. err is the transport error,
+// nil if the server answered at all (even with an error status).
+func (c *connectivity) record(err error) {
+	c.mu.Lock()
+	var changed bool
+	if err == nil {
+		c.failures = 0
+		c.lastSuccess = time.Now()
+		changed = c.offline
+		c.offline = false
+	} else {
+		c.failures++
+		if c.failures >= offlineAfter && !c.offline {
+			c.offline = true
+			changed = true
+		}
+	}
+	offline, fn := c.offline, c.onChange
+	c.mu.Unlock()
+
+	if changed && fn != nil {
+		fn(offline)
+	}
+}
+
+// Offline reports whether the client currently considers HN unreachable.
+func (c *Client) Offline() bool {
+	c.conn.mu.Lock()
+	defer c.conn.mu.Unlock()
+	return c.conn.offline
+}
+
+// OnConnectivityChange registers fn to be called (from the goroutine that
+// made the request) whenever the client goes offline or comes back.
+func (c *Client) OnConnectivityChange(fn func(offline bool)) {
+	c.conn.mu.Lock()
+	c.conn.onChange = fn
+	c.conn.mu.Unlock()
+}
+
+// do sends req, short-circuiting with ErrOffline while offline and
+// recording whether the network was reachable.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Offline() {
+		return nil, ErrOffline
+	}
+	return c.send(req)
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil && req.Context().Err() != nil {
+		// Cancelled by the caller; says nothing about the network.
+		return nil, err
+	}
+	if errors.Is(err, transport.ErrCircuitOpen) {
+		// Refused without being sent: one host has been failing, which
+		// the failures that opened the breaker already told us.
+		return nil, err
+	}
+	c.conn.record(err)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = countingBody{ReadCloser: resp.Body, n: &c.bytesRead}
+	return resp, nil
+}
+
+// Probe checks whether HN is reachable, bypassing the offline short-circuit
+// and the transport's retries.
+func (c *Client) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(transport.WithoutRetries(ctx), http.MethodGet, c.endpoints.Firebase+"/maxitem.json", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "nitpick/1.0")
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// WatchConnectivity probes every interval while offline, so the client
+// notices when the network returns, and after interval without a
+// successful request while online, so a dead link is noticed even when
+// idle. It returns when ctx is done.
+func (c *Client) WatchConnectivity(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.conn.mu.Lock()
+		due := c.conn.offline || time.Since(c.conn.lastSuccess) >= interval
+		c.conn.mu.Unlock()
+		if due {
+			probeCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+			c.Probe(probeCtx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/transport"
+)
+
+func TestConnectivity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	opts := transport.DefaultOptions()
+	opts.MaxAttempts = 1
+	opts.BreakerThreshold = 2
+	opts.BreakerCooldown = time.Minute
+	rt := transport.New(opts)
+	ctx := context.Background()
+
+	// A failing server opens its breaker, but it's answering: online.
+	c := NewClient(Options{Endpoints: Endpoints{Firebase: srv.URL}, Transport: rt})
+	var sawOpen bool
+	for i := 0; i < 2*offlineAfter; i++ {
+		_, err := c.GetItem(ctx, 1)
+		sawOpen = sawOpen || errors.Is(err, transport.ErrCircuitOpen)
+	}
+	if !sawOpen {
+		t.Fatal("breaker never opened")
+	}
+	if c.Offline() {
+		t.Error("an open breaker took the client offline")
+	}
+
+	// Nothing listening: offline after offlineAfter failures.
+	srv.Close()
+	c = NewClient(Options{Endpoints: Endpoints{Firebase: srv.URL}, Transport: transport.New(transport.Options{MaxAttempts: 1})})
+	for i := 0; i < offlineAfter; i++ {
+		if c.Offline() {
+			t.Fatalf("offline after %d failures, want %d", i, offlineAfter)
+		}
+		c.GetItem(ctx, 1)
+	}
+	if !c.Offline() {
+		t.Error("still online with the server gone")
+	}
+	if _, err := c.GetItem(ctx, 1); !errors.Is(err, ErrOffline) {
+		t.Errorf("offline GetItem: err = %v, want ErrOffline", err)
+	}
+}
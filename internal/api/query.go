@@ -0,0 +1,351 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is a parsed search query such as
+//
+//	author:pg points>100 type:ask after:2024-01-01 "exact phrase"
+//
+// Terms are kept in input order so String() round-trips through ParseQuery.
+type Query struct {
+	Terms []QueryTerm
+}
+
+// QueryTerm is one node of a parsed query. The concrete types are Word,
+// Phrase, AuthorFilter, TypeFilter, StoryFilter, NumericFilter and DateFilter.
+type QueryTerm interface {
+	String() string
+	apply(b *paramBuilder)
+}
+
+// Word is a bare full-text search word.
+type Word string
+
+// Phrase is a quoted exact-match phrase.
+type Phrase string
+
+// AuthorFilter restricts results to one author (author:pg).
+type AuthorFilter string
+
+// TypeFilter restricts results by item type (type:ask).
+type TypeFilter string
+
+// StoryFilter restricts results to comments on one story (story:123).
+type StoryFilter int
+
+// NumericFilter compares a numeric field (points>100, comments>=10).
+type NumericFilter struct {
+	Field string // "points" or "comments"
+	Op    string // one of >, >=, <, <=, =
+	Value int
+}
+
+// DateFilter bounds the creation time (after:2024-01-01, before:7d).
+// Value is either an ISO date or a relative age like 24h, 7d or 2w.
+type DateFilter struct {
+	After bool
+	Value string
+}
+
+// typeTags maps type: values to Algolia tags.
+var typeTags = map[string]string{
+	"story":   "story",
+	"comment": "comment",
+	"ask":     "ask_hn",
+	"show":    "show_hn",
+	"poll":    "poll",
+	"job":     "job",
+	"front":   "front_page",
+}
+
+// numericFields maps query field names to Algolia numeric attributes.
+var numericFields = map[string]string{
+	"points":   "points",
+	"comments": "num_comments",
+}
+
+// numericOps is ordered longest first so ">=" wins over ">".
+var numericOps = []string{">=", "<=", ">", "<", "=", ":"}
+
+// ParseQuery parses the structured query syntax. Unknown key:value pairs
+// are treated as plain words so URLs and the like still search normally.
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	for _, tok := range tokens {
+		term, err := parseTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q, nil
+}
+
+// queryToken is a whitespace-separated chunk of input; quoted marks a
+// "..." phrase whose quotes have been stripped.
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, queryToken{text: cur.String()})
+			cur.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"':
+			flush()
+			end := strings.IndexByte(s[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated quote at column %d", utf8.RuneCountInString(s[:i])+1)
+			}
+			phrase := strings.TrimSpace(s[i+1 : i+1+end])
+			if phrase != "" {
+				tokens = append(tokens, queryToken{text: phrase, quoted: true})
+			}
+			size = end + 2
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			cur.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	flush()
+	return tokens, nil
+}
+
+func parseTerm(tok queryToken) (QueryTerm, error) {
+	if tok.quoted {
+		return Phrase(tok.text), nil
+	}
+	text := tok.text
+
+	if key, val, ok := strings.Cut(text, ":"); ok {
+		switch strings.ToLower(key) {
+		case "author", "by":
+			if val == "" {
+				return nil, fmt.Errorf("author: needs a username")
+			}
+			return AuthorFilter(val), nil
+		case "type":
+			val = strings.ToLower(val)
+			if _, ok := typeTags[val]; !ok {
+				return nil, fmt.Errorf("unknown type %q (want story, comment, ask, show, poll, job or front)", val)
+			}
+			return TypeFilter(val), nil
+		case "story":
+			id, err := strconv.Atoi(val)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("story: needs an item ID, got %q", val)
+			}
+			return StoryFilter(id), nil
+		case "after", "before":
+			if _, err := resolveDate(val, time.Now()); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			return DateFilter{After: strings.EqualFold(key, "after"), Value: val}, nil
+		}
+	}
+
+	for field := range numericFields {
+		if !strings.HasPrefix(strings.ToLower(text), field) {
+			continue
+		}
+		rest := text[len(field):]
+		for _, op := range numericOps {
+			if !strings.HasPrefix(rest, op) {
+				continue
+			}
+			n, err := strconv.Atoi(rest[len(op):])
+			if err != nil {
+				return nil, fmt.Errorf("%s%s needs a number, got %q", field, op, rest[len(op):])
+			}
+			if op == ":" {
+				op = "="
+			}
+			return NumericFilter{Field: field, Op: op, Value: n}, nil
+		}
+	}
+
+	return Word(text), nil
+}
+
+// resolveDate turns an ISO date or relative age (24h, 7d, 2w) into a time.
+func resolveDate(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if len(s) >= 2 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err == nil && n >= 0 {
+			switch s[len(s)-1] {
+			case 'h':
+				return now.Add(-time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q (want YYYY-MM-DD or an age like 24h, 7d, 2w)", s)
+}
+
+// String renders the query in canonical syntax. ParseQuery(q.String())
+// yields an equivalent query.
+func (q *Query) String() string {
+	parts := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// HasType reports whether the query contains a type: filter.
+func (q *Query) HasType() bool {
+	for _, t := range q.Terms {
+		if _, ok := t.(TypeFilter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Word) String() string   { return string(w) }
+func (p Phrase) String() string { return `"` + string(p) + `"` }
+
+func (a AuthorFilter) String() string { return "author:" + string(a) }
+func (t TypeFilter) String() string   { return "type:" + string(t) }
+func (s StoryFilter) String() string  { return fmt.Sprintf("story:%d", int(s)) }
+
+func (n NumericFilter) String() string {
+	return fmt.Sprintf("%s%s%d", n.Field, n.Op, n.Value)
+}
+
+// Time resolves the filter's date or age relative to now.
+func (d DateFilter) Time(now time.Time) (time.Time, error) {
+	return resolveDate(d.Value, now)
+}
+
+func (d DateFilter) String() string {
+	if d.After {
+		return "after:" + d.Value
+	}
+	return "before:" + d.Value
+}
+
+// paramBuilder accumulates Algolia parameters while walking the terms.
+type paramBuilder struct {
+	now      time.Time
+	text     []string
+	phrase   bool
+	authors  []string
+	types    []string
+	tags     []string
+	numerics []string
+}
+
+func (w Word) apply(b *paramBuilder) { b.text = append(b.text, string(w)) }
+
+func (p Phrase) apply(b *paramBuilder) {
+	b.text = append(b.text, p.String())
+	b.phrase = true
+}
+
+func (a AuthorFilter) apply(b *paramBuilder) { b.authors = append(b.authors, "author_"+string(a)) }
+func (t TypeFilter) apply(b *paramBuilder)   { b.types = append(b.types, typeTags[string(t)]) }
+func (s StoryFilter) apply(b *paramBuilder)  { b.tags = append(b.tags, fmt.Sprintf("story_%d", int(s))) }
+
+func (n NumericFilter) apply(b *paramBuilder) {
+	b.numerics = append(b.numerics, fmt.Sprintf("%s%s%d", numericFields[n.Field], n.Op, n.Value))
+}
+
+func (d DateFilter) apply(b *paramBuilder) {
+	t, err := resolveDate(d.Value, b.now)
+	if err != nil {
+		return
+	}
+	if d.After {
+		b.numerics = append(b.numerics, fmt.Sprintf("created_at_i>=%d", t.Unix()))
+	} else {
+		b.numerics = append(b.numerics, fmt.Sprintf("created_at_i<%d", t.Unix()))
+	}
+}
+
+// Params compiles the query into Algolia search parameters, the same
+// tags/numericFilters form GetPastStories builds by hand. Repeated author:
+// or type: filters are OR'd together; everything else is AND'd.
+func (q *Query) Params() SearchParams {
+	return q.paramsAt(time.Now())
+}
+
+// paramsAt is Params with relative dates resolved against now.
+func (q *Query) paramsAt(now time.Time) SearchParams {
+	b := &paramBuilder{now: now}
+	for _, t := range q.Terms {
+		t.apply(b)
+	}
+
+	tags := b.tags
+	if g := orGroup(b.types); g != "" {
+		tags = append([]string{g}, tags...)
+	}
+	if g := orGroup(b.authors); g != "" {
+		tags = append(tags, g)
+	}
+
+	return SearchParams{
+		Query:          strings.Join(b.text, " "),
+		Tags:           strings.Join(tags, ","),
+		NumericFilters: strings.Join(b.numerics, ","),
+		AdvancedSyntax: b.phrase,
+	}
+}
+
+// ParamsWithTags is Params with defaultTags (e.g. "story") AND'd in, unless
+// the query carries its own type: filter.
+func (q *Query) ParamsWithTags(defaultTags string) SearchParams {
+	p := q.Params()
+	if defaultTags == "" || q.HasType() {
+		return p
+	}
+	if p.Tags == "" {
+		p.Tags = defaultTags
+	} else {
+		p.Tags = defaultTags + "," + p.Tags
+	}
+	return p
+}
+
+// orGroup renders Algolia's OR syntax: a single tag stays bare, several
+// become "(a,b)".
+func orGroup(tags []string) string {
+	switch len(tags) {
+	case 0:
+		return ""
+	case 1:
+		return tags[0]
+	default:
+		return "(" + strings.Join(tags, ",") + ")"
+	}
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []QueryTerm
+	}{
+		{"", nil},
+		{"rust", []QueryTerm{Word("rust")}},
+		{`  "exact  phrase" `, []QueryTerm{Phrase("exact  phrase")}},
+		{`""`, nil},
+		{"author:pg by:dang", []QueryTerm{AuthorFilter("pg"), AuthorFilter("dang")}},
+		{"type:ASK", []QueryTerm{TypeFilter("ask")}},
+		{"story:8863", []QueryTerm{StoryFilter(8863)}},
+		{"points>100 comments>=10 points<5 comments<=3 points=7 points:8", []QueryTerm{
+			NumericFilter{Field: "points", Op: ">", Value: 100},
+			NumericFilter{Field: "comments", Op: ">=", Value: 10},
+			NumericFilter{Field: "points", Op: "<", Value: 5},
+			NumericFilter{Field: "comments", Op: "<=", Value: 3},
+			NumericFilter{Field: "points", Op: "=", Value: 7},
+			NumericFilter{Field: "points", Op: "=", Value: 8},
+		}},
+		{"after:2024-01-01 before:7d Before:24h", []QueryTerm{
+			DateFilter{After: true, Value: "2024-01-01"},
+			DateFilter{Value: "7d"},
+			DateFilter{Value: "24h"},
+		}},
+		// Unknown keys stay words, so URLs and the like still search.
+		{"https://example.com site:example.com", []QueryTerm{Word("https://example.com"), Word("site:example.com")}},
+		{`author:pg points>100 type:ask after:2024-01-01 "exact phrase" lisp`, []QueryTerm{
+			AuthorFilter("pg"),
+			NumericFilter{Field: "points", Op: ">", Value: 100},
+			TypeFilter("ask"),
+			DateFilter{After: true, Value: "2024-01-01"},
+			Phrase("exact phrase"),
+			Word("lisp"),
+		}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.in)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Terms, tt.want) {
+			t.Errorf("ParseQuery(%q) = %#v, want %#v", tt.in, q.Terms, tt.want)
+		}
+	}
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []queryToken
+	}{
+		{"voilà", []queryToken{{text: "voilà"}}},
+		{"Åsa", []queryToken{{text: "Åsa"}}},
+		{"naïve  Ærø\u00a0smørrebrød", []queryToken{{text: "naïve"}, {text: "Ærø"}, {text: "smørrebrød"}}},
+		{`"café crème" über "à la"`, []queryToken{{text: "café crème", quoted: true}, {text: "über"}, {text: "à la", quoted: true}}},
+		{`author:Åsa "日本語 テキスト"`, []queryToken{{text: "author:Åsa"}, {text: "日本語 テキスト", quoted: true}}},
+	}
+	for _, tt := range tests {
+		got, err := tokenizeQuery(tt.in)
+		if err != nil {
+			t.Errorf("tokenizeQuery(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeQuery(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		for _, tok := range got {
+			if !utf8.ValidString(tok.text) {
+				t.Errorf("tokenizeQuery(%q) split a character: %q", tt.in, tok.text)
+			}
+		}
+	}
+
+	// Columns count characters, not bytes.
+	if _, err := tokenizeQuery(`àé "open`); err == nil || !strings.Contains(err.Error(), "column 4") {
+		t.Errorf("unterminated quote after accents: err = %v, want column 4", err)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // substring of the error
+	}{
+		{"type:blog", `unknown type "blog"`},
+		{"author:", "author: needs a username"},
+		{"story:abc", `story: needs an item ID, got "abc"`},
+		{"story:-1", "story: needs an item ID"},
+		{"points>lots", `points> needs a number, got "lots"`},
+		{"comments>=", "comments>= needs a number"},
+		{"after:yesterday", `after: bad date "yesterday"`},
+		{"before:2024-13-01", `before: bad date "2024-13-01"`},
+		{"after:-3d", `after: bad date "-3d"`},
+		{"after:3m", `after: bad date "3m"`},
+		{`rust "unterminated`, "unterminated quote at column 6"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.in)
+		if err == nil {
+			t.Errorf("ParseQuery(%q) = %v, want error", tt.in, q)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseQuery(%q) error = %q, want it to contain %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestQueryRoundTrip(t *testing.T) {
+	tests := []string{
+		"rust",
+		`"exact phrase"`,
+		`author:pg points>100 type:ask after:2024-01-01 "exact phrase"`,
+		"by:dang type:COMMENT story:1 comments:5 before:2w",
+		"points>=10 points<=20 comments<3 after:24h lisp macros",
+		"https://example.com site:example.com",
+	}
+	for _, in := range tests {
+		q, err := ParseQuery(in)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", in, err)
+		}
+		again, err := ParseQuery(q.String())
+		if err != nil {
+			t.Errorf("ParseQuery(%q) (from %q): %v", q.String(), in, err)
+			continue
+		}
+		if !reflect.DeepEqual(again, q) {
+			t.Errorf("round trip of %q via %q = %#v, want %#v", in, q.String(), again, q)
+		}
+		if again.String() != q.String() {
+			t.Errorf("String() not canonical: %q then %q", q.String(), again.String())
+		}
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want SearchParams
+	}{
+		{"rust", SearchParams{Query: "rust"}},
+		{`"exact phrase" rust`, SearchParams{Query: `"exact phrase" rust`, AdvancedSyntax: true}},
+		{
+			`author:pg points>100 type:ask after:2024-01-01 "exact phrase"`,
+			SearchParams{
+				Query:          `"exact phrase"`,
+				Tags:           "ask_hn,author_pg",
+				NumericFilters: "points>100,created_at_i>=1704067200",
+				AdvancedSyntax: true,
+			},
+		},
+		{
+			"author:pg author:dang type:show type:front story:42",
+			SearchParams{Tags: "(show_hn,front_page),story_42,(author_pg,author_dang)"},
+		},
+		{"comments>=10 points<5", SearchParams{NumericFilters: "num_comments>=10,points<5"}},
+		{"after:24h", SearchParams{NumericFilters: "created_at_i>=1718366400"}},
+		{"before:7d", SearchParams{NumericFilters: "created_at_i<1717848000"}},
+		{"after:2w before:1d", SearchParams{NumericFilters: "created_at_i>=1717243200,created_at_i<1718366400"}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.in)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.in, err)
+		}
+		if got := q.paramsAt(now); got != tt.want {
+			t.Errorf("ParseQuery(%q).Params() = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryParamsResolvesRelativeDatesWhenApplied(t *testing.T) {
+	q, err := ParseQuery("after:1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A saved query keeps its relative age and moves with the clock.
+	day1 := q.paramsAt(time.Unix(1_700_000_000, 0))
+	day2 := q.paramsAt(time.Unix(1_700_086_400, 0))
+	if day1.NumericFilters != "created_at_i>=1699913600" || day2.NumericFilters != "created_at_i>=1700000000" {
+		t.Errorf("after:1d resolved to %q then %q", day1.NumericFilters, day2.NumericFilters)
+	}
+	if q.String() != "after:1d" {
+		t.Errorf("String() = %q, want the relative form kept", q.String())
+	}
+
+	before := time.Now().Add(-24 * time.Hour).Unix()
+	p := q.Params()
+	after := time.Now().Add(-24 * time.Hour).Unix()
+	var ts int64
+	if _, err := fmt.Sscanf(p.NumericFilters, "created_at_i>=%d", &ts); err != nil || ts < before || ts > after {
+		t.Errorf("Params() = %q, want created_at_i>= between %d and %d", p.NumericFilters, before, after)
+	}
+}
+
+func TestParamsWithTags(t *testing.T) {
+	tests := []struct {
+		in, defaults, want string
+	}{
+		{"rust", "story", "story"},
+		{"rust author:pg", "story", "story,author_pg"},
+		{"rust type:comment", "story", "comment"},
+		{"rust author:pg", "", "author_pg"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.in)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.in, err)
+		}
+		if got := q.ParamsWithTags(tt.defaults).Tags; got != tt.want {
+			t.Errorf("ParseQuery(%q).ParamsWithTags(%q).Tags = %q, want %q", tt.in, tt.defaults, got, tt.want)
+		}
+	}
+}
@@ -5,23 +5,24 @@ import (
 	"fmt"
 )
 
-var storyEndpoints = map[StoryType]string{
-	StoryTypeTop:  baseURL + "/topstories.json",
-	StoryTypeNew:  baseURL + "/newstories.json",
-	StoryTypeBest: baseURL + "/beststories.json",
-	StoryTypeAsk:  baseURL + "/askstories.json",
-	StoryTypeShow: baseURL + "/showstories.json",
-	StoryTypeJobs: baseURL + "/jobstories.json",
+// storyPaths are the Firebase list endpoints, relative to Endpoints.Firebase.
+var storyPaths = map[StoryType]string{
+	StoryTypeTop:  "/topstories.json",
+	StoryTypeNew:  "/newstories.json",
+	StoryTypeBest: "/beststories.json",
+	StoryTypeAsk:  "/askstories.json",
+	StoryTypeShow: "/showstories.json",
+	StoryTypeJobs: "/jobstories.json",
 }
 
 // GetStoryIDs fetches the list of story IDs for a given story type.
 func (c *Client) GetStoryIDs(ctx context.Context, st StoryType) ([]int, error) {
-	url, ok := storyEndpoints[st]
+	path, ok := storyPaths[st]
 	if !ok {
 		return nil, fmt.Errorf("unknown story type: %s", st)
 	}
 	var ids []int
-	if err := c.get(ctx, url, &ids); err != nil {
+	if err := c.get(ctx, c.endpoints.Firebase+path, &ids); err != nil {
 		return nil, fmt.Errorf("fetching %s stories: %w", st, err)
 	}
 	return ids, nil
@@ -10,8 +10,6 @@ import (
 	"strings"
 )
 
-const hnBaseURL = "https://news.ycombinator.com"
-
 // ThreadComment represents a parsed comment from the HN threads page.
 type ThreadComment struct {
 	ID         int
@@ -37,7 +35,7 @@ var (
 // comments with their proper indent levels as shown on the site.
 // Pass next="" for the first page, or the cursor from a previous call for subsequent pages.
 func (c *Client) GetThreadsPage(ctx context.Context, username string, next string) ([]ThreadComment, string, error) {
-	url := fmt.Sprintf("%s/threads?id=%s", hnBaseURL, username)
+	url := fmt.Sprintf("%s/threads?id=%s", c.endpoints.HN, username)
 	if next != "" {
 		url += "&next=" + next
 	}
@@ -48,7 +46,7 @@ func (c *Client) GetThreadsPage(ctx context.Context, username string, next strin
 	}
 	req.Header.Set("User-Agent", "nitpick/1.0")
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("fetching threads page: %w", err)
 	}
@@ -0,0 +1,244 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/fakehn"
+	"github.com/fragmede/nitpick/internal/tree"
+)
+
+// Story 9 in the fake HN fixtures has no comments, so the thread is all
+// ours: threadWidth top-level comments, each with threadWidth/2 replies,
+// threadDepth levels deep.
+const (
+	threadStory = 9
+	threadWidth = 10
+	threadDepth = 3
+)
+
+var (
+	threadOnce sync.Once
+	threadSrv  *httptest.Server
+	threadErr  error
+)
+
+// fakeThread starts a fake HN with a large thread on threadStory, posted
+// through the site like a user would. It's shared by the benchmarks.
+func fakeThread(tb testing.TB) *httptest.Server {
+	tb.Helper()
+	threadOnce.Do(func() {
+		srv, err := fakehn.New()
+		if err != nil {
+			threadErr = err
+			return
+		}
+		threadSrv = httptest.NewServer(srv)
+		threadErr = growThread(threadSrv.URL)
+	})
+	if threadErr != nil {
+		tb.Fatal(threadErr)
+	}
+	return threadSrv
+}
+
+func growThread(base string) error {
+	// The session logs every reply form.
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	session := auth.NewSession(auth.Options{BaseURL: base, Transport: http.DefaultTransport})
+	if err := session.Login("demo", "demo"); err != nil {
+		return err
+	}
+	client := newClient(base, http.DefaultTransport)
+	ctx := context.Background()
+
+	parents := []int{threadStory}
+	for level := 0; level < threadDepth; level++ {
+		n := threadWidth
+		if level > 0 {
+			n = threadWidth / 2
+		}
+		for _, parent := range parents {
+			for i := 0; i < n; i++ {
+				if err := session.Reply(parent, fmt.Sprintf("Level %d reply %d to %d.", level, i, parent)); err != nil {
+					return err
+				}
+			}
+		}
+		items, err := client.BatchGetItems(ctx, parents)
+		if err != nil {
+			return err
+		}
+		parents = parents[:0]
+		for _, it := range items {
+			parents = append(parents, it.Kids()...)
+		}
+	}
+	return nil
+}
+
+// countingTransport counts requests, to report them per load.
+type countingTransport struct {
+	n atomic.Int64
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newClient talks straight to the fake server. The shared transport's
+// rate limiter would only widen the gap between the two paths.
+func newClient(base string, rt http.RoundTripper) *api.Client {
+	return api.NewClient(api.Options{Endpoints: fakehn.Endpoints(base), Transport: rt})
+}
+
+// loadFirebase is the level-by-level load the story view falls back to:
+// one Firebase request per comment, each level queueing the replies it
+// finds. With prefetched set, comments already prefetched as leaves are
+// skipped, as the story view does after loadAlgolia.
+func loadFirebase(ctx context.Context, client *api.Client, db *cache.DB, kids []int, prefetched bool) (int, error) {
+	l := tree.Loader{Client: client, DB: db, Reuse: func(cached *api.Item, fresh bool) bool {
+		return prefetched && len(cached.Kids()) == 0
+	}}
+	return l.Walk(ctx, kids)
+}
+
+// loadAlgolia prefetches the whole tree in one request.
+func loadAlgolia(ctx context.Context, client *api.Client, db *cache.DB, id int) (int, error) {
+	items, err := client.GetItemTree(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return len(items) - 1, db.PutItems(items[1:])
+}
+
+func benchmarkLoad(b *testing.B, load func(ctx context.Context, client *api.Client, db *cache.DB, story *api.Item) (int, error)) {
+	srv := fakeThread(b)
+	rt := &countingTransport{}
+	client := newClient(srv.URL, rt)
+	ctx := context.Background()
+	story, err := client.GetItem(ctx, threadStory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	want := story.Descendants
+
+	b.ResetTimer()
+	rt.n.Store(0)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		db, err := cache.Open(filepath.Join(b.TempDir(), "cache.db"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		n, err := load(ctx, client, db, story)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != want {
+			b.Fatalf("loaded %d comments, want %d", n, want)
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(rt.n.Load())/float64(b.N), "requests/op")
+	b.ReportMetric(float64(want), "comments")
+}
+
+func BenchmarkThreadFirebase(b *testing.B) {
+	benchmarkLoad(b, func(ctx context.Context, client *api.Client, db *cache.DB, story *api.Item) (int, error) {
+		return loadFirebase(ctx, client, db, story.Kids(), false)
+	})
+}
+
+func BenchmarkThreadAlgolia(b *testing.B) {
+	benchmarkLoad(b, func(ctx context.Context, client *api.Client, db *cache.DB, story *api.Item) (int, error) {
+		return loadAlgolia(ctx, client, db, story.ID)
+	})
+}
+
+// BenchmarkThreadAlgoliaThenFirebase is what the story view does: the
+// tree in one request, then Firebase for the comments with replies, to
+// get HN's ordering of them.
+func BenchmarkThreadAlgoliaThenFirebase(b *testing.B) {
+	benchmarkLoad(b, func(ctx context.Context, client *api.Client, db *cache.DB, story *api.Item) (int, error) {
+		if _, err := loadAlgolia(ctx, client, db, story.ID); err != nil {
+			return 0, err
+		}
+		return loadFirebase(ctx, client, db, story.Kids(), true)
+	})
+}
+
+// TestItemTreeMatchesFirebase checks the two paths agree on the fake
+// thread: the same comments, the same replies under each, and the same
+// comment count on the story, including the fixture threads with deleted
+// comments.
+func TestItemTreeMatchesFirebase(t *testing.T) {
+	srv := fakeThread(t)
+	client := newClient(srv.URL, http.DefaultTransport)
+	ctx := context.Background()
+
+	top, err := client.GetStoryIDs(ctx, api.StoryTypeTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range top {
+		tree, err := client.GetItemTree(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		story, err := client.GetItem(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if story.Type != "job" && tree[0].Descendants != story.Descendants {
+			t.Errorf("story %d: tree descendants = %d, Firebase says %d", id, tree[0].Descendants, story.Descendants)
+		}
+	}
+
+	tree, err := client.GetItemTree(ctx, threadStory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	story, err := client.GetItem(ctx, threadStory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree[0].Descendants != story.Descendants {
+		t.Errorf("tree descendants = %d, Firebase says %d", tree[0].Descendants, story.Descendants)
+	}
+	if want := threadWidth * (1 + threadWidth/2 + threadWidth/2*threadWidth/2); story.Descendants != want {
+		t.Errorf("thread has %d comments, want %d", story.Descendants, want)
+	}
+
+	for _, it := range tree[1:] {
+		fb, err := client.GetItem(ctx, it.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it.Parent != fb.Parent || it.By != fb.By || it.Text != fb.Text || it.Deleted != fb.Deleted {
+			t.Errorf("item %d: tree has %+v, Firebase %+v", it.ID, it, fb)
+		}
+		if len(it.Kids()) != len(fb.Kids()) {
+			t.Errorf("item %d: tree has kids %v, Firebase %v", it.ID, it.Kids(), fb.Kids())
+		}
+	}
+}
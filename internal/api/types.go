@@ -2,23 +2,38 @@ package api
 
 import (
 	"encoding/json"
+	"strings"
 )
 
 // StoryType represents the different HN story categories.
 type StoryType string
 
 const (
-	StoryTypeTop      StoryType = "top"
-	StoryTypeNew      StoryType = "new"
-	StoryTypeBest     StoryType = "best"
-	StoryTypeAsk      StoryType = "ask"
-	StoryTypeShow     StoryType = "show"
-	StoryTypeJobs     StoryType = "jobs"
-	StoryTypeThreads  StoryType = "threads"
-	StoryTypePast     StoryType = "past"
-	StoryTypeComments StoryType = "comments"
+	StoryTypeTop       StoryType = "top"
+	StoryTypeNew       StoryType = "new"
+	StoryTypeBest      StoryType = "best"
+	StoryTypeAsk       StoryType = "ask"
+	StoryTypeShow      StoryType = "show"
+	StoryTypeJobs      StoryType = "jobs"
+	StoryTypeThreads   StoryType = "threads"
+	StoryTypePast      StoryType = "past"
+	StoryTypeComments  StoryType = "comments"
+	StoryTypeBookmarks StoryType = "bookmarks"
 )
 
+// savedSearchPrefix marks a StoryType naming one of the user's saved searches.
+const savedSearchPrefix = "search:"
+
+// SavedSearchType returns the tab type for the saved search with the given name.
+func SavedSearchType(name string) StoryType {
+	return StoryType(savedSearchPrefix + name)
+}
+
+// SavedSearchName returns the saved search name if st is a saved search tab.
+func (st StoryType) SavedSearchName() (string, bool) {
+	return strings.CutPrefix(string(st), savedSearchPrefix)
+}
+
 // Item represents an HN item (story, comment, job, poll, pollopt).
 type Item struct {
 	ID          int    `json:"id"`
@@ -35,6 +50,7 @@ type Item struct {
 	Deleted     bool   `json:"deleted"`
 	Poll        int    `json:"poll"`
 	StoryTitle  string `json:"-"` // Parent story title (Algolia-only, not from Firebase)
+	StoryID     int    `json:"-"` // Root story ID (Algolia-only, not from Firebase)
 
 	// Kids is stored as a JSON array of ints.
 	// We use json.RawMessage to handle the raw JSON and parse lazily.
@@ -3,6 +3,7 @@ package auth
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
@@ -10,28 +11,47 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/transport"
 )
 
-const hnBaseURL = "https://news.ycombinator.com"
+// Options configures a Session. Zero fields take the defaults.
+type Options struct {
+	BaseURL   string            // the HN website; default https://news.ycombinator.com
+	Transport http.RoundTripper // default transport.Default
+}
 
 // Session manages HN authentication state.
 type Session struct {
 	client   *http.Client
 	jar      *cookiejar.Jar
+	base     string
 	Username string
 	LoggedIn bool
 }
 
 // NewSession creates a new auth session.
-func NewSession() *Session {
+func NewSession(opts Options) *Session {
+	base := strings.TrimRight(opts.BaseURL, "/")
+	if base == "" {
+		base = api.DefaultEndpoints.HN
+	}
+	rt := opts.Transport
+	if rt == nil {
+		rt = transport.Default
+	}
 	jar, _ := cookiejar.New(nil)
 	return &Session{
 		client: &http.Client{
-			Jar: jar,
+			Jar:       jar,
+			Transport: rt,
 		},
-		jar: jar,
+		jar:  jar,
+		base: base,
 	}
 }
 
@@ -43,7 +63,7 @@ func (s *Session) Login(username, password string) error {
 		"goto": {"news"},
 	}
 
-	resp, err := s.client.PostForm(hnBaseURL+"/login", data)
+	resp, err := s.client.PostForm(s.base+"/login", data)
 	if err != nil {
 		return fmt.Errorf("login request failed: %w", err)
 	}
@@ -83,7 +103,7 @@ func (s *Session) Save(path string) error {
 		return nil
 	}
 
-	u, _ := url.Parse(hnBaseURL)
+	u, _ := url.Parse(s.base)
 	cookies := s.jar.Cookies(u)
 
 	sc := make([]savedCookie, len(cookies))
@@ -129,7 +149,7 @@ func (s *Session) Load(path string) bool {
 	}
 
 	// Restore cookies into the jar.
-	u, _ := url.Parse(hnBaseURL)
+	u, _ := url.Parse(s.base)
 	cookies := make([]*http.Cookie, len(saved.Cookies))
 	for i, sc := range saved.Cookies {
 		cookies[i] = &http.Cookie{
@@ -157,7 +177,7 @@ func (s *Session) Load(path string) bool {
 }
 
 func (s *Session) validate() error {
-	resp, err := s.client.Get(hnBaseURL + "/news")
+	resp, err := s.client.Get(s.base + "/news")
 	if err != nil {
 		return err
 	}
@@ -181,7 +201,7 @@ func (s *Session) Reply(parentID int, text string) error {
 	}
 
 	// Fetch the reply page to get the form tokens.
-	replyURL := fmt.Sprintf("%s/reply?id=%d", hnBaseURL, parentID)
+	replyURL := fmt.Sprintf("%s/reply?id=%d", s.base, parentID)
 	resp, err := s.client.Get(replyURL)
 	if err != nil {
 		return fmt.Errorf("fetching reply page: %w", err)
@@ -203,7 +223,7 @@ func (s *Session) Reply(parentID int, text string) error {
 	log.Printf("reply form fields: parent=%s goto=%s hmac=%s text_len=%d",
 		data.Get("parent"), data.Get("goto"), data.Get("hmac"), len(text))
 
-	resp2, err := s.client.PostForm(hnBaseURL+"/comment", data)
+	resp2, err := s.client.PostForm(s.base+"/comment", data)
 	if err != nil {
 		return fmt.Errorf("submitting reply: %w", err)
 	}
@@ -221,7 +241,7 @@ func (s *Session) Edit(itemID int, text string) error {
 	}
 
 	// Fetch the edit page to get the form tokens.
-	editURL := fmt.Sprintf("%s/edit?id=%d", hnBaseURL, itemID)
+	editURL := fmt.Sprintf("%s/edit?id=%d", s.base, itemID)
 	resp, err := s.client.Get(editURL)
 	if err != nil {
 		return fmt.Errorf("fetching edit page: %w", err)
@@ -243,7 +263,7 @@ func (s *Session) Edit(itemID int, text string) error {
 	log.Printf("edit form fields: id=%s hmac=%s text_len=%d",
 		data.Get("id"), data.Get("hmac"), len(text))
 
-	resp2, err := s.client.PostForm(hnBaseURL+"/xedit", data)
+	resp2, err := s.client.PostForm(s.base+"/xedit", data)
 	if err != nil {
 		return fmt.Errorf("submitting edit: %w", err)
 	}
@@ -256,12 +276,23 @@ func (s *Session) Edit(itemID int, text string) error {
 
 // Vote upvotes an HN item.
 func (s *Session) Vote(itemID int) error {
+	return s.vote(itemID, itemID)
+}
+
+// VotePollOption votes for a poll option. HN only links option votes
+// from the poll's own page, so the token is read from there.
+func (s *Session) VotePollOption(pollID, optionID int) error {
+	return s.vote(pollID, optionID)
+}
+
+// vote reads itemID's vote link from pageID's item page and follows it.
+func (s *Session) vote(pageID, itemID int) error {
 	if !s.LoggedIn {
 		return fmt.Errorf("not logged in")
 	}
 
 	// Fetch the item page to get the vote auth token.
-	itemURL := fmt.Sprintf("%s/item?id=%d", hnBaseURL, itemID)
+	itemURL := fmt.Sprintf("%s/item?id=%d", s.base, pageID)
 	resp, err := s.client.Get(itemURL)
 	if err != nil {
 		return fmt.Errorf("fetching item page: %w", err)
@@ -279,7 +310,7 @@ func (s *Session) Vote(itemID int) error {
 	}
 
 	// Execute the vote.
-	resp2, err := s.client.Get(hnBaseURL + "/" + voteURL)
+	resp2, err := s.client.Get(s.base + "/" + html.UnescapeString(voteURL))
 	if err != nil {
 		return fmt.Errorf("voting: %w", err)
 	}
@@ -289,6 +320,100 @@ func (s *Session) Vote(itemID int) error {
 	return nil
 }
 
+// maxFavoritesPages bounds how many pages of favorites Favorites follows.
+const maxFavoritesPages = 20
+
+var (
+	athingRe   = regexp.MustCompile(`<tr[^>]*class=['"]athing[^'"]*['"][^>]*id=['"](\d+)['"]`)
+	moreLinkRe = regexp.MustCompile(`<a[^>]*href=['"](favorites\?[^'"]+)['"][^>]*class=['"]morelink['"]`)
+)
+
+// Favorites returns the IDs of a user's HN favorites: their favorite
+// stories, or their favorite comments if comments is set. HN shows
+// favorites publicly, so this works for any user.
+func (s *Session) Favorites(username string, comments bool) ([]int, error) {
+	next := "favorites?id=" + url.QueryEscape(username)
+	if comments {
+		next += "&comments=t"
+	}
+	var ids []int
+	for page := 0; next != "" && page < maxFavoritesPages; page++ {
+		resp, err := s.client.Get(s.base + "/" + next)
+		if err != nil {
+			return ids, fmt.Errorf("fetching favorites: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return ids, err
+		}
+		if resp.StatusCode >= 400 {
+			return ids, fmt.Errorf("fetching favorites: status %d", resp.StatusCode)
+		}
+		for _, m := range athingRe.FindAllStringSubmatch(string(body), -1) {
+			if id, err := strconv.Atoi(m[1]); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		next = ""
+		if m := moreLinkRe.FindStringSubmatch(string(body)); m != nil {
+			next = html.UnescapeString(m[1])
+		}
+	}
+	return ids, nil
+}
+
+// Fave adds an item to the user's HN favorites, or removes it if un is
+// set. Like Vote, it follows the authenticated link on the item's page.
+// It is a no-op if the item is already in the requested state.
+func (s *Session) Fave(itemID int, un bool) error {
+	return s.itemAction("fave", "favorite", itemID, un)
+}
+
+// Hide hides a story from the user's HN front page, or unhides it if un
+// is set. Like Fave, it is a no-op if the story is already in the
+// requested state.
+func (s *Session) Hide(itemID int, un bool) error {
+	return s.itemAction("hide", "hide", itemID, un)
+}
+
+// itemAction follows the toggle link named action (fave, hide) on an
+// item's page, unless it already points the requested way.
+func (s *Session) itemAction(action, what string, itemID int, un bool) error {
+	if !s.LoggedIn {
+		return fmt.Errorf("not logged in")
+	}
+
+	resp, err := s.client.Get(fmt.Sprintf("%s/item?id=%d", s.base, itemID))
+	if err != nil {
+		return fmt.Errorf("fetching item page: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	actionURL := extractActionURL(string(body), action, itemID)
+	if actionURL == "" {
+		return fmt.Errorf("could not find %s link for item %d", what, itemID)
+	}
+	if strings.Contains(actionURL, "un=t") != un {
+		return nil // already in the requested state
+	}
+
+	resp2, err := s.client.Get(s.base + "/" + actionURL)
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", what, err)
+	}
+	defer resp2.Body.Close()
+	io.ReadAll(resp2.Body)
+	if resp2.StatusCode >= 400 {
+		return fmt.Errorf("updating %s: status %d", what, resp2.StatusCode)
+	}
+	return nil
+}
+
 // Submit posts a new story to HN.
 func (s *Session) Submit(title, storyURL, text string) error {
 	if !s.LoggedIn {
@@ -296,7 +421,7 @@ func (s *Session) Submit(title, storyURL, text string) error {
 	}
 
 	// Fetch the submit page to get the fnid token.
-	resp, err := s.client.Get(hnBaseURL + "/submit")
+	resp, err := s.client.Get(s.base + "/submit")
 	if err != nil {
 		return fmt.Errorf("fetching submit page: %w", err)
 	}
@@ -319,7 +444,7 @@ func (s *Session) Submit(title, storyURL, text string) error {
 		"url":   {storyURL},
 		"text":  {text},
 	}
-	resp2, err := s.client.PostForm(hnBaseURL+"/r", data)
+	resp2, err := s.client.PostForm(s.base+"/r", data)
 	if err != nil {
 		return fmt.Errorf("submitting story: %w", err)
 	}
@@ -440,3 +565,14 @@ func extractVoteURL(html string, itemID int) string {
 	}
 	return endSub[:end]
 }
+
+// extractActionURL finds the link for action (fave, hide) on itemID,
+// or its un- variant, with entities decoded.
+func extractActionURL(page, action string, itemID int) string {
+	re := regexp.MustCompile(fmt.Sprintf(`href=['"](%s\?id=%d&[^'"]*)['"]`, action, itemID))
+	m := re.FindStringSubmatch(page)
+	if m == nil {
+		return ""
+	}
+	return html.UnescapeString(m[1])
+}
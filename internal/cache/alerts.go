@@ -0,0 +1,62 @@
+package cache
+
+import "time"
+
+// Alert kinds.
+const (
+	AlertKeyword = "keyword" // pattern is a search query
+	AlertDomain  = "domain"  // pattern is a hostname matched against story URLs
+)
+
+// Alert is a user-defined topic watched for new stories and comments.
+type Alert struct {
+	ID        int
+	Kind      string
+	Pattern   string
+	Watermark int64 // created_at_i of the newest hit already notified
+	CreatedAt time.Time
+}
+
+// ListAlerts returns all alert rules.
+func (d *DB) ListAlerts() ([]Alert, error) {
+	rows, err := d.db.Query(`SELECT id, kind, pattern, watermark, created_at FROM alerts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Alert
+	for rows.Next() {
+		var a Alert
+		var createdAt int64
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Pattern, &a.Watermark, &createdAt); err != nil {
+			continue
+		}
+		a.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// ToggleAlert adds the rule if it doesn't exist, or removes it if it does.
+// Returns true if the rule is now active. New rules only match items
+// created from now on.
+func (d *DB) ToggleAlert(kind, pattern string) (bool, error) {
+	res, err := d.db.Exec(`DELETE FROM alerts WHERE kind = ? AND pattern = ?`, kind, pattern)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return false, nil
+	}
+	now := time.Now().Unix()
+	_, err = d.db.Exec(`INSERT INTO alerts (kind, pattern, watermark, created_at) VALUES (?, ?, ?, ?)`,
+		kind, pattern, now, now)
+	return err == nil, err
+}
+
+// SetAlertWatermark records the newest hit seen for an alert.
+func (d *DB) SetAlertWatermark(id int, watermark int64) error {
+	_, err := d.db.Exec(`UPDATE alerts SET watermark = ? WHERE id = ?`, watermark, id)
+	return err
+}
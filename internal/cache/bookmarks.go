@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// Bookmark is a saved story or comment. Title and author are copied from
+// the item when it is bookmarked, so the list renders without the item.
+type Bookmark struct {
+	ItemID    int
+	Type      string // item type: story, comment, ...
+	Title     string // story title, or the title of the story a comment is on
+	By        string
+	Tags      []string
+	Note      string
+	CreatedAt time.Time
+}
+
+// bookmarkFor builds a bookmark for item, looking up the story title for
+// comments.
+func (d *DB) bookmarkFor(item *api.Item) Bookmark {
+	title := item.Title
+	if title == "" {
+		title = d.storyTitle(item.Parent)
+	}
+	return Bookmark{ItemID: item.ID, Type: item.Type, Title: title, By: item.By}
+}
+
+// ToggleBookmark bookmarks the item, or removes its bookmark if it has
+// one. Returns true if the item is now bookmarked.
+func (d *DB) ToggleBookmark(item *api.Item) (bool, error) {
+	res, err := d.db.Exec(`DELETE FROM bookmarks WHERE item_id = ?`, item.ID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return false, nil
+	}
+	err = d.AddBookmark(d.bookmarkFor(item))
+	return err == nil, err
+}
+
+// AddBookmark saves b unless the item is already bookmarked. A zero
+// CreatedAt means now.
+func (d *DB) AddBookmark(b Bookmark) error {
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+	_, err := d.db.Exec(`INSERT OR IGNORE INTO bookmarks (item_id, type, title, by_user, tags, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		b.ItemID, b.Type, nullStr(b.Title), nullStr(b.By), joinTags(b.Tags), b.Note, b.CreatedAt.Unix())
+	return err
+}
+
+// BookmarkItem bookmarks item unless it already is, reporting whether it
+// was added.
+func (d *DB) BookmarkItem(item *api.Item) (bool, error) {
+	if d.IsBookmarked(item.ID) {
+		return false, nil
+	}
+	return true, d.AddBookmark(d.bookmarkFor(item))
+}
+
+// IsBookmarked reports whether the item is bookmarked.
+func (d *DB) IsBookmarked(id int) bool {
+	var n int
+	d.db.QueryRow(`SELECT COUNT(*) FROM bookmarks WHERE item_id = ?`, id).Scan(&n)
+	return n > 0
+}
+
+// ListBookmarks returns bookmarks newest first, only those tagged tag
+// if it is non-empty.
+func (d *DB) ListBookmarks(tag string) ([]Bookmark, error) {
+	rows, err := d.db.Query(`SELECT item_id, type, title, by_user, tags, note, created_at
+		FROM bookmarks ORDER BY created_at DESC, item_id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Bookmark
+	for rows.Next() {
+		var b Bookmark
+		var title, by sql.NullString
+		var tags string
+		var createdAt int64
+		if err := rows.Scan(&b.ItemID, &b.Type, &title, &by, &tags, &b.Note, &createdAt); err != nil {
+			continue
+		}
+		b.Title = title.String
+		b.By = by.String
+		b.Tags = ParseTags(tags)
+		b.CreatedAt = time.Unix(createdAt, 0)
+		if tag == "" || containsTag(b.Tags, tag) {
+			result = append(result, b)
+		}
+	}
+	return result, nil
+}
+
+// BookmarkTags returns every tag in use, sorted.
+func (d *DB) BookmarkTags() ([]string, error) {
+	all, err := d.ListBookmarks("")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var tags []string
+	for _, b := range all {
+		for _, t := range b.Tags {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// SetBookmarkTags replaces a bookmark's tags.
+func (d *DB) SetBookmarkTags(id int, tags []string) error {
+	_, err := d.db.Exec(`UPDATE bookmarks SET tags = ? WHERE item_id = ?`, joinTags(tags), id)
+	return err
+}
+
+// SetBookmarkNote replaces a bookmark's note.
+func (d *DB) SetBookmarkNote(id int, note string) error {
+	_, err := d.db.Exec(`UPDATE bookmarks SET note = ? WHERE item_id = ?`, note, id)
+	return err
+}
+
+// DeleteBookmark removes a bookmark.
+func (d *DB) DeleteBookmark(id int) error {
+	_, err := d.db.Exec(`DELETE FROM bookmarks WHERE item_id = ?`, id)
+	return err
+}
+
+// ParseTags splits a comma- or space-separated tag list, lowercasing and
+// dropping duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !containsTag(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(ParseTags(strings.Join(tags, ",")), ",")
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := map[string][]string{
+		"":                 nil,
+		"rust":             {"rust"},
+		"Rust, go  rust":   {"rust", "go"},
+		",,to-read,,later": {"to-read", "later"},
+	}
+	for in, want := range tests {
+		if got := ParseTags(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	d := testDB(t)
+	story := &api.Item{ID: 1, Type: "story", By: "pg", Title: "A story"}
+	reply := &api.Item{ID: 3, Type: "comment", By: "dang", Parent: 2}
+	if err := d.PutItems([]*api.Item{story, {ID: 2, Type: "comment", Parent: 1}, reply}); err != nil {
+		t.Fatal(err)
+	}
+
+	if on, err := d.ToggleBookmark(story); err != nil || !on {
+		t.Fatalf("toggle on: %v, %v", on, err)
+	}
+	if added, err := d.BookmarkItem(reply); err != nil || !added {
+		t.Fatalf("bookmark reply: %v, %v", added, err)
+	}
+	if added, _ := d.BookmarkItem(reply); added {
+		t.Error("bookmarked the reply twice")
+	}
+	if err := d.AddBookmark(Bookmark{ItemID: 9, Type: "story", Title: "Old", Tags: []string{"Go", "later"}, CreatedAt: time.Unix(1, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetBookmarkTags(1, []string{"Rust", "to-read", "rust"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetBookmarkTags(3, ParseTags("rust, go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetBookmarkNote(3, "good point"); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := d.ListBookmarks("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].ItemID != 3 || all[1].ItemID != 1 || all[2].ItemID != 9 {
+		t.Fatalf("bookmarks = %+v, want 3, 1, 9", all)
+	}
+	// A comment's bookmark carries the title of the story it is on.
+	if all[0].Title != "A story" || all[0].By != "dang" || all[0].Note != "good point" {
+		t.Errorf("reply bookmark = %+v", all[0])
+	}
+	if !reflect.DeepEqual(all[1].Tags, []string{"rust", "to-read"}) {
+		t.Errorf("story tags = %q, want them lowercased without duplicates", all[1].Tags)
+	}
+
+	for tag, want := range map[string][]int{"rust": {3, 1}, "go": {3, 9}, "later": {9}, "Rust": nil, "none": nil} {
+		bs, err := d.ListBookmarks(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, b := range bs {
+			ids = append(ids, b.ItemID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("tag %s: %v, want %v", tag, ids, want)
+		}
+	}
+	if tags, _ := d.BookmarkTags(); !reflect.DeepEqual(tags, []string{"go", "later", "rust", "to-read"}) {
+		t.Errorf("tags = %q", tags)
+	}
+
+	// Clearing the last use of a tag drops it from the list.
+	if err := d.SetBookmarkTags(9, nil); err != nil {
+		t.Fatal(err)
+	}
+	if tags, _ := d.BookmarkTags(); !reflect.DeepEqual(tags, []string{"go", "rust", "to-read"}) {
+		t.Errorf("tags after clearing = %q", tags)
+	}
+
+	if on, err := d.ToggleBookmark(story); err != nil || on {
+		t.Errorf("toggle off: %v, %v", on, err)
+	}
+	if err := d.DeleteBookmark(3); err != nil {
+		t.Fatal(err)
+	}
+	if d.IsBookmarked(1) || d.IsBookmarked(3) || !d.IsBookmarked(9) {
+		t.Error("bookmarks not removed")
+	}
+}
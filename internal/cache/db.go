@@ -2,19 +2,123 @@ package cache
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-
-	_ "modernc.org/sqlite"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 // DB wraps the SQLite database for HN item caching.
 type DB struct {
-	db *sql.DB
+	db   *sql.DB
+	path string
+
+	// Item IDs read since the last flush; see touch.
+	accessMu sync.Mutex
+	accessed map[int]struct{}
 }
 
 // Open creates or opens the SQLite cache database and runs migrations.
+//
+// If the file is corrupt or isn't a database at all, it is moved aside
+// and a fresh cache is created in its place. If it's a readable database
+// whose schema can't be upgraded, the same happens only when it holds
+// nothing but refetchable HN data; the login session, bookmarks, killfile,
+// saved searches, alerts and read history are the user's own, so Open
+// fails rather than set them aside where nobody would look. Transient
+// errors, such as another nitpick holding a lock past busyTimeout, are
+// returned as they are.
 func Open(path string) (*DB, error) {
-	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(wal)&_pragma=foreign_keys(on)")
+	db, err := open(path)
+	var merr *migrationError
+	if err == nil || !errors.As(err, &merr) || transient(err) {
+		return db, err
+	}
+	if !corrupt(err) && hasUserData(path) {
+		return nil, fmt.Errorf("%w; %s holds your own data (bookmarks, killfile, saved searches...), so it was left as it is: back it up and move it aside to start a fresh cache", err, path)
+	}
+
+	backup := fmt.Sprintf("%s.broken-%s", path, time.Now().Format("20060102-150405"))
+	log.Printf("cache: %v; moving %s to %s and starting a fresh cache", err, path, backup)
+	if rerr := os.Rename(path, backup); rerr != nil {
+		return nil, fmt.Errorf("%w (and moving it aside failed: %v)", err, rerr)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Rename(path+suffix, backup+suffix)
+	}
+	return open(path)
+}
+
+// busyTimeout is how long a statement waits for another connection's
+// lock, e.g. nitpick sync writing while the TUI starts.
+var busyTimeout = 5 * time.Second
+
+// sqliteCode returns the primary SQLite result code of err, or 0.
+func sqliteCode(err error) int {
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return 0
+	}
+	return serr.Code() & 0xff
+}
+
+// transient reports whether err says nothing about the file itself: a
+// lock, a full disk, a permissions problem. Retrying later may work.
+func transient(err error) bool {
+	switch sqliteCode(err) {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CANTOPEN,
+		sqlite3.SQLITE_FULL, sqlite3.SQLITE_READONLY, sqlite3.SQLITE_NOMEM, sqlite3.SQLITE_PERM,
+		sqlite3.SQLITE_PROTOCOL:
+		return true
+	}
+	return false
+}
+
+// corrupt reports whether err means the file can't be read as a database.
+func corrupt(err error) bool {
+	switch sqliteCode(err) {
+	case sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_NOTADB:
+		return true
+	}
+	return false
+}
+
+// userTables hold what the user made rather than what HN sent.
+var userTables = []string{
+	"session", "bookmarks", "killfile", "hidden_stories", "saved_searches", "alerts",
+	"story_visits", "seen_comments", "monitored_comments",
+}
+
+// hasUserData reports whether any of userTables in the database at path
+// has rows. When in doubt it says yes.
+func hasUserData(path string) bool {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return true
+	}
+	defer db.Close()
+	for _, table := range userTables {
+		var exists bool
+		err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + table + `)`).Scan(&exists)
+		if err != nil && strings.Contains(err.Error(), "no such table") {
+			continue
+		}
+		if err != nil || exists {
+			return true
+		}
+	}
+	return false
+}
+
+func open(path string) (*DB, error) {
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(wal)&_pragma=foreign_keys(on)", path, busyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -22,13 +126,14 @@ func Open(path string) (*DB, error) {
 
 	if err := migrate(db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("migrating database: %w", err)
+		return nil, err
 	}
-	return &DB{db: db}, nil
+	return &DB{db: db, path: path, accessed: make(map[int]struct{})}, nil
 }
 
-// Close closes the database connection.
+// Close records pending access times and closes the database connection.
 func (d *DB) Close() error {
+	d.FlushAccess()
 	return d.db.Close()
 }
 
@@ -46,74 +151,3 @@ func (d *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
 func (d *DB) QueryRow(query string, args ...interface{}) *sql.Row {
 	return d.db.QueryRow(query, args...)
 }
-
-func migrate(db *sql.DB) error {
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS items (
-			id INTEGER PRIMARY KEY,
-			type TEXT NOT NULL,
-			by_user TEXT,
-			time_unix INTEGER,
-			text TEXT,
-			parent_id INTEGER,
-			url TEXT,
-			title TEXT,
-			score INTEGER DEFAULT 0,
-			descendants INTEGER DEFAULT 0,
-			kids TEXT,
-			dead INTEGER DEFAULT 0,
-			deleted INTEGER DEFAULT 0,
-			fetched_at INTEGER NOT NULL
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_items_parent ON items(parent_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_items_by_user ON items(by_user)`,
-
-		`CREATE TABLE IF NOT EXISTS story_lists (
-			list_type TEXT PRIMARY KEY,
-			item_ids TEXT NOT NULL,
-			fetched_at INTEGER NOT NULL
-		)`,
-
-		`CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			created INTEGER,
-			karma INTEGER,
-			about TEXT,
-			fetched_at INTEGER NOT NULL
-		)`,
-
-		`CREATE TABLE IF NOT EXISTS monitored_comments (
-			item_id INTEGER PRIMARY KEY,
-			parent_story_id INTEGER,
-			known_kids TEXT NOT NULL DEFAULT '[]',
-			last_checked INTEGER NOT NULL,
-			depth INTEGER DEFAULT 0,
-			created_at INTEGER NOT NULL
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_monitored_last_checked ON monitored_comments(last_checked)`,
-
-		`CREATE TABLE IF NOT EXISTS notifications (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			item_id INTEGER NOT NULL UNIQUE,
-			parent_id INTEGER NOT NULL,
-			story_id INTEGER,
-			by_user TEXT,
-			text_preview TEXT,
-			created_at INTEGER NOT NULL,
-			read INTEGER DEFAULT 0
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_notifications_read ON notifications(read)`,
-
-		`CREATE TABLE IF NOT EXISTS session (
-			key TEXT PRIMARY KEY,
-			value TEXT NOT NULL
-		)`,
-	}
-
-	for _, m := range migrations {
-		if _, err := db.Exec(m); err != nil {
-			return fmt.Errorf("executing migration: %w\nSQL: %s", err, m)
-		}
-	}
-	return nil
-}
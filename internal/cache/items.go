@@ -8,27 +8,27 @@ import (
 	"github.com/fragmede/nitpick/internal/api"
 )
 
-// GetItem retrieves a cached item. Returns (item, isFresh, error).
-// isFresh indicates whether the item is within its TTL.
-// Returns nil item on cache miss.
-func (d *DB) GetItem(id int, ttl time.Duration) (*api.Item, bool, error) {
-	row := d.db.QueryRow(`SELECT id, type, by_user, time_unix, text, parent_id, url,
-		title, score, descendants, kids, dead, deleted, fetched_at
-		FROM items WHERE id = ?`, id)
+// itemColumns is the column list scanItem expects.
+const itemColumns = `id, type, by_user, time_unix, text, parent_id, url,
+	title, score, descendants, kids, parts, poll_id, dead, deleted, fetched_at`
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanItem reads one row selected with itemColumns, returning the item and
+// when it was fetched.
+func scanItem(row rowScanner) (*api.Item, int64, error) {
 	var item api.Item
-	var byUser, text, url, title, kids sql.NullString
-	var parentID sql.NullInt64
+	var byUser, text, url, title, kids, parts sql.NullString
+	var parentID, pollID sql.NullInt64
 	var fetchedAt int64
 	var dead, deleted int
 
 	err := row.Scan(&item.ID, &item.Type, &byUser, &item.Time, &text, &parentID,
-		&url, &title, &item.Score, &item.Descendants, &kids, &dead, &deleted, &fetchedAt)
-	if err == sql.ErrNoRows {
-		return nil, false, nil
-	}
+		&url, &title, &item.Score, &item.Descendants, &kids, &parts, &pollID, &dead, &deleted, &fetchedAt)
 	if err != nil {
-		return nil, false, err
+		return nil, 0, err
 	}
 
 	item.By = byUser.String
@@ -43,14 +43,56 @@ func (d *DB) GetItem(id int, ttl time.Duration) (*api.Item, bool, error) {
 	if kids.Valid && kids.String != "" {
 		item.RawKids = json.RawMessage(kids.String)
 	}
+	if parts.Valid && parts.String != "" {
+		item.RawParts = json.RawMessage(parts.String)
+	}
+	if pollID.Valid {
+		item.Poll = int(pollID.Int64)
+	}
+	return &item, fetchedAt, nil
+}
+
+// GetItem retrieves a cached item. Returns (item, isFresh, error).
+// isFresh indicates whether the item is within its TTL.
+// Returns nil item on cache miss.
+func (d *DB) GetItem(id int, ttl time.Duration) (*api.Item, bool, error) {
+	item, fetchedAt, err := scanItem(d.db.QueryRow(`SELECT `+itemColumns+` FROM items WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
 
+	d.touch(item.ID)
 	isFresh := time.Since(time.Unix(fetchedAt, 0)) < ttl
-	return &item, isFresh, nil
+	return item, isFresh, nil
 }
 
 // PutItem stores an item in the cache.
 func (d *DB) PutItem(item *api.Item) error {
+	return d.PutItems([]*api.Item{item})
+}
+
+// PutItems stores many items in one transaction, such as a whole comment
+// tree.
+func (d *DB) PutItems(items []*api.Item) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 	now := time.Now().Unix()
+	for _, item := range items {
+		if err := putItem(tx, item, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// putItem writes one item and its search index entry.
+func putItem(tx *sql.Tx, item *api.Item, now int64) error {
 	var dead, deleted int
 	if item.Dead {
 		dead = 1
@@ -59,14 +101,21 @@ func (d *DB) PutItem(item *api.Item) error {
 		deleted = 1
 	}
 	kidsJSON := item.KidsJSON()
+	var partsJSON sql.NullString
+	if len(item.Parts()) > 0 {
+		partsJSON = sql.NullString{String: string(item.RawParts), Valid: true}
+	}
 
-	_, err := d.db.Exec(`INSERT OR REPLACE INTO items
-		(id, type, by_user, time_unix, text, parent_id, url, title, score, descendants, kids, dead, deleted, fetched_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err := tx.Exec(`INSERT OR REPLACE INTO items
+		(id, type, by_user, time_unix, text, parent_id, url, title, score, descendants, kids, parts, poll_id, dead, deleted, fetched_at, accessed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		item.ID, item.Type, nullStr(item.By), item.Time, nullStr(item.Text),
 		nullInt(item.Parent), nullStr(item.URL), nullStr(item.Title),
-		item.Score, item.Descendants, kidsJSON, dead, deleted, now)
-	return err
+		item.Score, item.Descendants, kidsJSON, partsJSON, nullInt(item.Poll), dead, deleted, now, now)
+	if err != nil {
+		return err
+	}
+	return indexItem(tx, item.ID, item.Title, item.Text, item.By)
 }
 
 // InvalidateItem removes a single item from the cache.
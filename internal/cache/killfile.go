@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// Kill rule kinds.
+const (
+	KillUser   = "user"   // pattern is a username; hides their stories and mutes their comments
+	KillDomain = "domain" // pattern is a hostname; also matches its subdomains
+	KillTitle  = "title"  // pattern is a case-insensitive regexp matched against story titles
+)
+
+// KillRule is one killfile entry.
+type KillRule struct {
+	ID        int
+	Kind      string
+	Pattern   string
+	CreatedAt time.Time
+}
+
+// AddKillRule adds a rule, doing nothing if it already exists. Title
+// patterns must be valid regular expressions.
+func (d *DB) AddKillRule(kind, pattern string) error {
+	pattern, err := normalizeKillPattern(kind, pattern)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`INSERT OR IGNORE INTO killfile (kind, pattern, created_at) VALUES (?, ?, ?)`,
+		kind, pattern, time.Now().Unix())
+	return err
+}
+
+// ToggleKillRule adds the rule if it doesn't exist, or removes it if it
+// does. Returns true if the rule is now active.
+func (d *DB) ToggleKillRule(kind, pattern string) (bool, error) {
+	pattern, err := normalizeKillPattern(kind, pattern)
+	if err != nil {
+		return false, err
+	}
+	res, err := d.db.Exec(`DELETE FROM killfile WHERE kind = ? AND pattern = ?`, kind, pattern)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return false, nil
+	}
+	err = d.AddKillRule(kind, pattern)
+	return err == nil, err
+}
+
+// DeleteKillRule removes a rule by ID.
+func (d *DB) DeleteKillRule(id int) error {
+	_, err := d.db.Exec(`DELETE FROM killfile WHERE id = ?`, id)
+	return err
+}
+
+// ListKillRules returns all killfile rules.
+func (d *DB) ListKillRules() ([]KillRule, error) {
+	rows, err := d.db.Query(`SELECT id, kind, pattern, created_at FROM killfile ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []KillRule
+	for rows.Next() {
+		var r KillRule
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Pattern, &createdAt); err != nil {
+			continue
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// HideStory hides a story from every story list.
+func (d *DB) HideStory(id int) error {
+	_, err := d.db.Exec(`INSERT OR IGNORE INTO hidden_stories (item_id, created_at) VALUES (?, ?)`,
+		id, time.Now().Unix())
+	return err
+}
+
+// UnhideStory undoes HideStory, reporting whether the story was hidden.
+func (d *DB) UnhideStory(id int) (bool, error) {
+	res, err := d.db.Exec(`DELETE FROM hidden_stories WHERE item_id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// HiddenStories returns the IDs of hidden stories, most recently hidden first.
+func (d *DB) HiddenStories() ([]int, error) {
+	rows, err := d.db.Query(`SELECT item_id FROM hidden_stories ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func normalizeKillPattern(kind, pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", fmt.Errorf("empty %s pattern", kind)
+	}
+	switch kind {
+	case KillUser:
+		return pattern, nil
+	case KillDomain:
+		return strings.TrimPrefix(strings.ToLower(pattern), "www."), nil
+	case KillTitle:
+		if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+			return "", fmt.Errorf("bad title pattern: %w", err)
+		}
+		return pattern, nil
+	}
+	return "", fmt.Errorf("unknown kill rule kind %q (want user, domain or title)", kind)
+}
+
+// Killfile is the compiled set of rules and hidden stories, for matching
+// while building lists.
+type Killfile struct {
+	users   map[string]bool
+	domains []string
+	titles  []*regexp.Regexp
+	hidden  map[int]bool
+}
+
+// LoadKillfile compiles the current rules and hidden stories.
+func (d *DB) LoadKillfile() (*Killfile, error) {
+	kf := &Killfile{users: make(map[string]bool), hidden: make(map[int]bool)}
+	rules, err := d.ListKillRules()
+	if err != nil {
+		return kf, err
+	}
+	for _, r := range rules {
+		switch r.Kind {
+		case KillUser:
+			kf.users[r.Pattern] = true
+		case KillDomain:
+			kf.domains = append(kf.domains, r.Pattern)
+		case KillTitle:
+			if re, err := regexp.Compile("(?i)" + r.Pattern); err == nil {
+				kf.titles = append(kf.titles, re)
+			}
+		}
+	}
+	ids, err := d.HiddenStories()
+	for _, id := range ids {
+		kf.hidden[id] = true
+	}
+	return kf, err
+}
+
+// HidesStory reports whether a story should be left out of story lists:
+// it was hidden, or its author, domain or title matches a rule.
+func (kf *Killfile) HidesStory(item *api.Item) bool {
+	if kf == nil {
+		return false
+	}
+	if kf.hidden[item.ID] || kf.users[item.By] {
+		return true
+	}
+	if item.URL != "" && len(kf.domains) > 0 {
+		if u, err := url.Parse(item.URL); err == nil {
+			host := strings.ToLower(u.Hostname())
+			for _, dom := range kf.domains {
+				if host == dom || strings.HasSuffix(host, "."+dom) {
+					return true
+				}
+			}
+		}
+	}
+	title := html.UnescapeString(item.Title)
+	for _, re := range kf.titles {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
+
+// MutesUser reports whether comments by username are muted.
+func (kf *Killfile) MutesUser(username string) bool {
+	return kf != nil && kf.users[username]
+}
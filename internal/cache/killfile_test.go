@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+func TestKillfile(t *testing.T) {
+	d := testDB(t)
+	for _, r := range []struct{ kind, pattern string }{
+		{KillUser, "spammer"},
+		{KillDomain, " WWW.Example.com "},
+		{KillTitle, `^show hn:.*\bcrypto\b`},
+		{KillTitle, "AI &"},
+	} {
+		if err := d.AddKillRule(r.kind, r.pattern); err != nil {
+			t.Fatalf("%s %q: %v", r.kind, r.pattern, err)
+		}
+	}
+	if err := d.AddKillRule(KillDomain, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.HideStory(42); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := d.ListKillRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 4 || rules[1].Pattern != "example.com" {
+		t.Errorf("rules = %+v, want 4 with the domain normalized once", rules)
+	}
+
+	kf, err := d.LoadKillfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		item api.Item
+		want bool
+	}{
+		{api.Item{ID: 1, By: "spammer", Title: "Anything"}, true},
+		{api.Item{ID: 1, By: "Spammer", Title: "Anything"}, false}, // usernames are case sensitive
+		{api.Item{ID: 1, By: "pg", URL: "https://example.com/a"}, true},
+		{api.Item{ID: 1, By: "pg", URL: "http://blog.EXAMPLE.com/"}, true},
+		{api.Item{ID: 1, By: "pg", URL: "https://notexample.com/"}, false},
+		{api.Item{ID: 1, By: "pg", URL: "https://example.com.evil/"}, false},
+		{api.Item{ID: 1, By: "pg", URL: "not a url\x7f"}, false},
+		{api.Item{ID: 1, By: "pg", Title: "Show HN: My crypto wallet"}, true},
+		{api.Item{ID: 1, By: "pg", Title: "SHOW HN: Crypto"}, true},
+		{api.Item{ID: 1, By: "pg", Title: "Show HN: cryptography notes"}, false},
+		{api.Item{ID: 1, By: "pg", Title: "Ask HN: crypto?"}, false},
+		{api.Item{ID: 1, By: "pg", Title: "AI &amp; jobs"}, true}, // titles are matched unescaped
+		{api.Item{ID: 42, By: "pg", Title: "Hidden"}, true},
+		{api.Item{ID: 2, By: "pg", Title: "Fine", URL: "https://ok.example/"}, false},
+	}
+	for _, tt := range tests {
+		if got := kf.HidesStory(&tt.item); got != tt.want {
+			t.Errorf("HidesStory(%+v) = %v, want %v", tt.item, got, tt.want)
+		}
+	}
+	if !kf.MutesUser("spammer") || kf.MutesUser("pg") {
+		t.Error("MutesUser doesn't follow the user rules")
+	}
+	var none *Killfile
+	if none.HidesStory(&api.Item{By: "spammer"}) || none.MutesUser("spammer") {
+		t.Error("a nil killfile hides things")
+	}
+
+	// Toggling removes a rule, and adds it back.
+	if on, err := d.ToggleKillRule(KillUser, "spammer"); err != nil || on {
+		t.Fatalf("toggle off: %v, %v", on, err)
+	}
+	if kf, _ := d.LoadKillfile(); kf.MutesUser("spammer") {
+		t.Error("toggled-off user still muted")
+	}
+	if on, err := d.ToggleKillRule(KillUser, "spammer"); err != nil || !on {
+		t.Fatalf("toggle on: %v, %v", on, err)
+	}
+	if ok, _ := d.UnhideStory(42); !ok {
+		t.Error("story 42 wasn't hidden")
+	}
+	if kf, _ := d.LoadKillfile(); kf.HidesStory(&api.Item{ID: 42}) {
+		t.Error("unhidden story still hidden")
+	}
+}
+
+func TestKillRuleErrors(t *testing.T) {
+	d := testDB(t)
+	for _, tt := range []struct{ kind, pattern, want string }{
+		{KillUser, "  ", "empty user pattern"},
+		{KillTitle, "(unclosed", "bad title pattern"},
+		{"phrase", "x", `unknown kill rule kind "phrase"`},
+	} {
+		err := d.AddKillRule(tt.kind, tt.pattern)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %q: err = %v, want %s", tt.kind, tt.pattern, err, tt.want)
+		}
+	}
+	if rules, _ := d.ListKillRules(); len(rules) != 0 {
+		t.Errorf("bad rules were saved: %+v", rules)
+	}
+}
@@ -0,0 +1,294 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is one schema version. Each runs in its own transaction
+// together with the schema_version bump, so a failed upgrade leaves the
+// database at the previous version.
+//
+// Steps must be idempotent: databases created before schema_version
+// existed start at version 0 and replay every step over tables that may
+// already be there. Never edit a released step; append a new one.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "initial schema", func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS items (
+				id INTEGER PRIMARY KEY,
+				type TEXT NOT NULL,
+				by_user TEXT,
+				time_unix INTEGER,
+				text TEXT,
+				parent_id INTEGER,
+				url TEXT,
+				title TEXT,
+				score INTEGER DEFAULT 0,
+				descendants INTEGER DEFAULT 0,
+				kids TEXT,
+				dead INTEGER DEFAULT 0,
+				deleted INTEGER DEFAULT 0,
+				fetched_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_items_parent ON items(parent_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_items_by_user ON items(by_user)`,
+
+			`CREATE TABLE IF NOT EXISTS story_lists (
+				list_type TEXT PRIMARY KEY,
+				item_ids TEXT NOT NULL,
+				fetched_at INTEGER NOT NULL
+			)`,
+
+			`CREATE TABLE IF NOT EXISTS users (
+				id TEXT PRIMARY KEY,
+				created INTEGER,
+				karma INTEGER,
+				about TEXT,
+				fetched_at INTEGER NOT NULL
+			)`,
+
+			`CREATE TABLE IF NOT EXISTS monitored_comments (
+				item_id INTEGER PRIMARY KEY,
+				parent_story_id INTEGER,
+				known_kids TEXT NOT NULL DEFAULT '[]',
+				last_checked INTEGER NOT NULL,
+				depth INTEGER DEFAULT 0,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_monitored_last_checked ON monitored_comments(last_checked)`,
+
+			`CREATE TABLE IF NOT EXISTS notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				item_id INTEGER NOT NULL UNIQUE,
+				parent_id INTEGER NOT NULL,
+				story_id INTEGER,
+				by_user TEXT,
+				text_preview TEXT,
+				created_at INTEGER NOT NULL,
+				read INTEGER DEFAULT 0
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_notifications_read ON notifications(read)`,
+
+			`CREATE TABLE IF NOT EXISTS session (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+		)
+	}},
+
+	{2, "saved searches", func(tx *sql.Tx) error {
+		return execAll(tx, `CREATE TABLE IF NOT EXISTS saved_searches (
+			name TEXT PRIMARY KEY,
+			query TEXT NOT NULL,
+			tags TEXT NOT NULL DEFAULT '',
+			by_date INTEGER DEFAULT 0,
+			created_at INTEGER NOT NULL
+		)`)
+	}},
+
+	{3, "keyword and domain alerts", func(tx *sql.Tx) error {
+		if err := execAll(tx, `CREATE TABLE IF NOT EXISTS alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			watermark INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			UNIQUE(kind, pattern)
+		)`); err != nil {
+			return err
+		}
+		if _, err := addColumn(tx, "notifications", "kind", "TEXT NOT NULL DEFAULT 'reply'"); err != nil {
+			return err
+		}
+		_, err := addColumn(tx, "notifications", "topic", "TEXT")
+		return err
+	}},
+
+	{4, "item access times", func(tx *sql.Tx) error {
+		added, err := addColumn(tx, "items", "accessed_at", "INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			return err
+		}
+		if added {
+			if err := execAll(tx, `UPDATE items SET accessed_at = fetched_at`); err != nil {
+				return err
+			}
+		}
+		return execAll(tx, `CREATE INDEX IF NOT EXISTS idx_items_accessed ON items(accessed_at)`)
+	}},
+
+	{5, "poll parts", func(tx *sql.Tx) error {
+		if _, err := addColumn(tx, "items", "parts", "TEXT"); err != nil {
+			return err
+		}
+		_, err := addColumn(tx, "items", "poll_id", "INTEGER")
+		return err
+	}},
+
+	{6, "full-text search", func(tx *sql.Tx) error {
+		if err := execAll(tx,
+			`CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+				title, body, author, tokenize = 'porter unicode61'
+			)`,
+			`CREATE TRIGGER IF NOT EXISTS items_fts_delete AFTER DELETE ON items BEGIN
+				DELETE FROM items_fts WHERE rowid = old.id;
+			END`,
+		); err != nil {
+			return err
+		}
+		return reindexAll(tx)
+	}},
+
+	{7, "reading history", func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS story_visits (
+				story_id INTEGER PRIMARY KEY,
+				first_visited_at INTEGER NOT NULL,
+				visited_at INTEGER NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS seen_comments (
+				story_id INTEGER NOT NULL,
+				item_id INTEGER NOT NULL,
+				PRIMARY KEY (story_id, item_id)
+			) WITHOUT ROWID`,
+		)
+	}},
+
+	{8, "bookmarks", func(tx *sql.Tx) error {
+		return execAll(tx, `CREATE TABLE IF NOT EXISTS bookmarks (
+			item_id INTEGER PRIMARY KEY,
+			type TEXT NOT NULL,
+			title TEXT,
+			by_user TEXT,
+			tags TEXT NOT NULL DEFAULT '',
+			note TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		)`)
+	}},
+
+	{9, "killfile and hidden stories", func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS killfile (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				kind TEXT NOT NULL,
+				pattern TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				UNIQUE(kind, pattern)
+			)`,
+			`CREATE TABLE IF NOT EXISTS hidden_stories (
+				item_id INTEGER PRIMARY KEY,
+				created_at INTEGER NOT NULL
+			)`,
+		)
+	}},
+}
+
+// migrationError marks a failure to bring the schema up to date, as
+// opposed to failing to open the file at all.
+type migrationError struct{ err error }
+
+func (e *migrationError) Error() string { return "migrating database: " + e.err.Error() }
+func (e *migrationError) Unwrap() error { return e.err }
+
+// migrate brings the schema up to the latest version.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return &migrationError{err}
+	}
+	current, err := schemaVersion(db)
+	if err != nil {
+		return &migrationError{err}
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		// Written by a newer nitpick. Steps only ever add, so carry on.
+		log.Printf("cache: schema version %d is newer than this build's %d", current, latest)
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := runMigration(db, m); err != nil {
+			return &migrationError{fmt.Errorf("step %d (%s): %w", m.version, m.name, err)}
+		}
+	}
+	return nil
+}
+
+func runMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if err := execAll(tx,
+		`DELETE FROM schema_version`,
+		fmt.Sprintf(`INSERT INTO schema_version (version) VALUES (%d)`, m.version),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schemaVersion returns the recorded version, 0 for a new or pre-versioning
+// database.
+func schemaVersion(db *sql.DB) (int, error) {
+	var v sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+func execAll(tx *sql.Tx, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%w\nSQL: %s", err, stmt)
+		}
+	}
+	return nil
+}
+
+// addColumn adds a column unless it already exists, reporting whether it
+// was added.
+func addColumn(tx *sql.Tx, table, column, decl string) (bool, error) {
+	ok, err := hasColumn(tx, table, column)
+	if err != nil || ok {
+		return false, err
+	}
+	return true, execAll(tx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+}
+
+// hasColumn reports whether table already has the named column.
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,426 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// seeds are rows written into a database built at an older schema, keyed
+// by the version that created their table. Every one must survive the
+// upgrade.
+var seeds = []struct {
+	version int
+	table   string
+	insert  string
+}{
+	{1, "items", `INSERT INTO items (id, type, by_user, time_unix, text, parent_id, url, title, score, descendants, kids, dead, deleted, fetched_at)
+		VALUES (1, 'story', 'pg', 900, '', NULL, 'https://example.com', 'Rust in production', 42, 1, '[2]', 0, 0, 1000)`},
+	{1, "story_lists", `INSERT INTO story_lists (list_type, item_ids, fetched_at) VALUES ('top', '[1]', 1000)`},
+	{1, "users", `INSERT INTO users (id, created, karma, about, fetched_at) VALUES ('pg', 1, 100, 'hi', 1000)`},
+	{1, "monitored_comments", `INSERT INTO monitored_comments (item_id, parent_story_id, known_kids, last_checked, depth, created_at)
+		VALUES (2, 1, '[]', 1000, 0, 1000)`},
+	{1, "notifications", `INSERT INTO notifications (item_id, parent_id, story_id, by_user, text_preview, created_at, read)
+		VALUES (3, 2, 1, 'dang', 'a reply', 1000, 0)`},
+	{1, "session", `INSERT INTO session (key, value) VALUES ('user', 'pg')`},
+	{2, "saved_searches", `INSERT INTO saved_searches (name, query, tags, by_date, created_at) VALUES ('rust', 'rust', 'story', 0, 1000)`},
+	{3, "alerts", `INSERT INTO alerts (kind, pattern, watermark, created_at) VALUES ('keyword', 'nitpick', 1000, 1000)`},
+	{7, "story_visits", `INSERT INTO story_visits (story_id, first_visited_at, visited_at) VALUES (1, 1000, 1000)`},
+	{7, "seen_comments", `INSERT INTO seen_comments (story_id, item_id) VALUES (1, 2)`},
+	{8, "bookmarks", `INSERT INTO bookmarks (item_id, type, title, by_user, tags, note, created_at) VALUES (1, 'story', 'Rust in production', 'pg', 'rust', '', 1000)`},
+	{9, "killfile", `INSERT INTO killfile (kind, pattern, created_at) VALUES ('domain', 'spam.example', 1000)`},
+	{9, "hidden_stories", `INSERT INTO hidden_stories (item_id, created_at) VALUES (4, 1000)`},
+}
+
+// buildSchema creates a database at path as an older nitpick left it: the
+// first version steps applied and seeded with rows. Without versioned the
+// steps run without recording schema_version, as nitpick did before
+// migrations were tracked.
+func buildSchema(t *testing.T, path string, version int, versioned bool) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if versioned {
+		if _, err := db.Exec(`CREATE TABLE schema_version (version INTEGER NOT NULL)`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range migrations[:version] {
+		if versioned {
+			err = runMigration(db, m)
+		} else {
+			err = runUnversioned(db, m)
+		}
+		if err != nil {
+			t.Fatalf("building step %d: %v", m.version, err)
+		}
+	}
+	for _, s := range seeds {
+		if s.version > version {
+			continue
+		}
+		if _, err := db.Exec(s.insert); err != nil {
+			t.Fatalf("seeding %s: %v", s.table, err)
+		}
+	}
+}
+
+func runUnversioned(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migrations[%d] is version %d, want %d", i, m.version, i+1)
+		}
+	}
+}
+
+func TestUpgradeFromEachSchema(t *testing.T) {
+	type start struct {
+		name      string
+		version   int
+		versioned bool
+	}
+	starts := []start{
+		// The schema before any of the migration steps existed.
+		{"v0", 1, false},
+		// Tables added after v0 but still before schema_version.
+		{"v0 with later tables", 4, false},
+	}
+	for v := 1; v <= latestVersion(); v++ {
+		starts = append(starts, start{fmt.Sprintf("v%d", v), v, true})
+	}
+
+	for _, s := range starts {
+		t.Run(s.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.db")
+			buildSchema(t, path, s.version, s.versioned)
+
+			d, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer d.Close()
+			assertNotMovedAside(t, path)
+			assertLatestSchema(t, d)
+
+			for _, seed := range seeds {
+				want := 0
+				if seed.version <= s.version {
+					want = 1
+				}
+				if got := count(t, d, "SELECT COUNT(*) FROM "+seed.table); got != want {
+					t.Errorf("%s has %d rows, want %d", seed.table, got, want)
+				}
+			}
+
+			item, _, err := d.GetItem(1, 24*time.Hour*365*100)
+			if err != nil || item == nil {
+				t.Fatalf("GetItem(1) = %v, %v", item, err)
+			}
+			if item.Title != "Rust in production" || item.By != "pg" || item.Score != 42 || len(item.Kids()) != 1 {
+				t.Errorf("item 1 came back as %+v", item)
+			}
+			if got := count(t, d, `SELECT COUNT(*) FROM notifications WHERE item_id = 3 AND kind = 'reply'`); got != 1 {
+				t.Errorf("old notification lost or not marked a reply")
+			}
+			if s.version < 4 {
+				// Rows from before access tracking count as read when fetched.
+				if got := count(t, d, `SELECT accessed_at FROM items WHERE id = 1`); got != 1000 {
+					t.Errorf("accessed_at = %d, want fetched_at 1000", got)
+				}
+			}
+			if s.version < 6 {
+				// Rows from before full-text search are indexed by the upgrade.
+				if got := count(t, d, `SELECT COUNT(*) FROM items_fts WHERE items_fts MATCH 'rust'`); got != 1 {
+					t.Errorf("full-text index has %d matches for the old item, want 1", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	buildSchema(t, path, 1, false)
+	d, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// Running migrate again is a no-op.
+	if err := migrate(d.db); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	assertLatestSchema(t, d)
+
+	// Every step can replay over a schema that already has its changes,
+	// as they do for pre-versioning databases.
+	if _, err := d.db.Exec(`UPDATE schema_version SET version = 0`); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(d.db); err != nil {
+		t.Fatalf("replaying every step: %v", err)
+	}
+	assertLatestSchema(t, d)
+	if got := count(t, d, "SELECT COUNT(*) FROM items"); got != 1 {
+		t.Errorf("items has %d rows after replay, want 1", got)
+	}
+	if got := count(t, d, "SELECT COUNT(*) FROM items_fts"); got != 1 {
+		t.Errorf("items_fts has %d rows after replay, want 1", got)
+	}
+}
+
+func TestOpenNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	buildSchema(t, path, latestVersion(), true)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer := latestVersion() + 5
+	if _, err := db.Exec(`UPDATE schema_version SET version = ?`, newer); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+	assertNotMovedAside(t, path)
+	if v, _ := schemaVersion(d.db); v != newer {
+		t.Errorf("schema version = %d, want the newer %d left alone", v, newer)
+	}
+	if got := count(t, d, "SELECT COUNT(*) FROM items"); got != 1 {
+		t.Errorf("items has %d rows, want 1", got)
+	}
+}
+
+func TestOpenRebuildsBrokenCache(t *testing.T) {
+	garbage := strings.Repeat("not sqlite\n", 1000)
+	tests := []struct {
+		name  string
+		build func(t *testing.T, path string)
+		kept  func(t *testing.T, backup string) // checks the moved-aside file
+	}{
+		{
+			"not a database",
+			func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte(garbage), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			func(t *testing.T, backup string) {
+				if data, _ := os.ReadFile(backup); string(data) != garbage {
+					t.Errorf("backup holds %d bytes, want the original %d", len(data), len(garbage))
+				}
+			},
+		},
+		{
+			"failing step",
+			func(t *testing.T, path string) {
+				// A view where step 1 expects a table: it can't be indexed.
+				db, err := sql.Open("sqlite", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer db.Close()
+				if _, err := db.Exec(`CREATE VIEW items AS SELECT 1 AS id, 0 AS parent_id, '' AS by_user`); err != nil {
+					t.Fatal(err)
+				}
+			},
+			func(t *testing.T, backup string) {
+				db, err := sql.Open("sqlite", backup)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer db.Close()
+				var typ string
+				if err := db.QueryRow(`SELECT type FROM sqlite_master WHERE name = 'items'`).Scan(&typ); err != nil || typ != "view" {
+					t.Errorf("backup's items is %q (%v), want the original view", typ, err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.db")
+			tt.build(t, path)
+
+			d, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer d.Close()
+			assertLatestSchema(t, d)
+			if err := d.PutItem(&api.Item{ID: 1, Type: "story", By: "pg", Title: "Fresh"}); err != nil {
+				t.Errorf("fresh cache isn't usable: %v", err)
+			}
+
+			backups, _ := filepath.Glob(path + ".broken-*")
+			var files []string
+			for _, b := range backups {
+				if !strings.HasSuffix(b, "-wal") && !strings.HasSuffix(b, "-shm") {
+					files = append(files, b)
+				}
+			}
+			if len(files) != 1 {
+				t.Fatalf("backups = %v, want one moved-aside cache", backups)
+			}
+			tt.kept(t, files[0])
+		})
+	}
+}
+
+func TestOpenKeepsUserData(t *testing.T) {
+	// The same failing step, but the user has bookmarked something.
+	path := filepath.Join(t.TempDir(), "cache.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := execStmts(db,
+		`CREATE VIEW items AS SELECT 1 AS id, 0 AS parent_id, '' AS by_user`,
+		`CREATE TABLE bookmarks (item_id INTEGER PRIMARY KEY)`,
+		`INSERT INTO bookmarks VALUES (8863)`,
+	); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	d, err := Open(path)
+	if err == nil {
+		d.Close()
+		t.Fatal("Open rebuilt a cache holding bookmarks")
+	}
+	if !strings.Contains(err.Error(), "your own data") {
+		t.Errorf("err = %v, want it to say why the cache was kept", err)
+	}
+	assertNotMovedAside(t, path)
+}
+
+func TestOpenWaitsForLock(t *testing.T) {
+	defer func(d time.Duration) { busyTimeout = d }(busyTimeout)
+	busyTimeout = 200 * time.Millisecond
+
+	// An older cache another nitpick is writing to while this one starts
+	// and wants to upgrade it.
+	path := filepath.Join(t.TempDir(), "cache.db")
+	buildSchema(t, path, 1, true)
+	other, err := sql.Open("sqlite", path+"?_pragma=journal_mode(wal)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	other.SetMaxOpenConns(1)
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`INSERT INTO story_lists (list_type, item_ids, fetched_at) VALUES ('held', '[]', 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(path)
+	if err == nil {
+		d.Close()
+		t.Fatal("Open upgraded a locked cache")
+	}
+	if !transient(err) {
+		t.Errorf("err = %v, want SQLITE_BUSY", err)
+	}
+	assertNotMovedAside(t, path)
+
+	// Released within the timeout, the lock is waited out.
+	time.AfterFunc(busyTimeout/4, func() { tx.Commit() })
+	d, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open after the lock was released: %v", err)
+	}
+	defer d.Close()
+	assertLatestSchema(t, d)
+	var ms int
+	if err := d.db.QueryRow(`PRAGMA busy_timeout`).Scan(&ms); err != nil || ms != int(busyTimeout.Milliseconds()) {
+		t.Errorf("busy_timeout = %d (%v), want %d", ms, err, busyTimeout.Milliseconds())
+	}
+}
+
+func execStmts(db *sql.DB, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+func assertLatestSchema(t *testing.T, d *DB) {
+	t.Helper()
+	if v, err := schemaVersion(d.db); err != nil || v != latestVersion() {
+		t.Errorf("schema version = %d, %v; want %d", v, err, latestVersion())
+	}
+	for _, seed := range seeds {
+		count(t, d, "SELECT COUNT(*) FROM "+seed.table)
+	}
+	count(t, d, "SELECT COUNT(*) FROM items_fts")
+	tx, err := d.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for _, c := range []struct{ table, column string }{
+		{"items", "accessed_at"},
+		{"items", "parts"},
+		{"items", "poll_id"},
+		{"notifications", "kind"},
+		{"notifications", "topic"},
+	} {
+		if ok, err := hasColumn(tx, c.table, c.column); err != nil || !ok {
+			t.Errorf("%s.%s missing (%v)", c.table, c.column, err)
+		}
+	}
+}
+
+func assertNotMovedAside(t *testing.T, path string) {
+	t.Helper()
+	if backups, _ := filepath.Glob(path + ".broken-*"); len(backups) > 0 {
+		t.Errorf("cache was moved aside: %v", backups)
+	}
+}
+
+// count runs a query returning one integer, failing the test if the query
+// does (e.g. because a table is missing).
+func count(t *testing.T, d *DB, query string) int {
+	t.Helper()
+	var n int
+	if err := d.db.QueryRow(query).Scan(&n); err != nil {
+		t.Errorf("%s: %v", query, err)
+	}
+	return n
+}
@@ -51,7 +51,13 @@ func (d *DB) UpsertMonitoredComment(mc MonitoredComment) error {
 	return err
 }
 
-// AddNotification inserts a new notification.
+// Notification kinds.
+const (
+	NotificationReply   = "reply"   // someone replied to a monitored comment
+	NotificationMention = "mention" // an item matched a keyword/domain alert
+)
+
+// AddNotification inserts a new reply notification.
 func (d *DB) AddNotification(itemID, parentID, storyID int, byUser, textPreview string, createdAt int64) error {
 	_, err := d.db.Exec(`INSERT OR IGNORE INTO notifications
 		(item_id, parent_id, story_id, by_user, text_preview, created_at, read)
@@ -60,6 +66,75 @@ func (d *DB) AddNotification(itemID, parentID, storyID int, byUser, textPreview
 	return err
 }
 
+// AddMention inserts a notification for an item matching an alert. topic
+// is the alert pattern that matched. It reports whether the item was new;
+// an item already notified about is left alone.
+func (d *DB) AddMention(itemID, parentID, storyID int, byUser, textPreview, topic string, createdAt int64) (bool, error) {
+	res, err := d.db.Exec(`INSERT OR IGNORE INTO notifications
+		(item_id, parent_id, story_id, by_user, text_preview, created_at, read, kind, topic)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)`,
+		itemID, parentID, storyID, byUser, textPreview, createdAt, NotificationMention, topic)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Notification is a reply or alert mention shown in the notifications view.
+type Notification struct {
+	ID          int    `json:"id"`
+	ItemID      int    `json:"item_id"`
+	ParentID    int    `json:"parent_id"`
+	StoryID     int    `json:"story_id,omitempty"`
+	ByUser      string `json:"by"`
+	TextPreview string `json:"text_preview"`
+	CreatedAt   int64  `json:"created_at"`
+	Read        bool   `json:"read"`
+	Kind        string `json:"kind"`            // NotificationReply or NotificationMention
+	Topic       string `json:"topic,omitempty"` // matching alert pattern for mentions
+}
+
+// ListNotifications returns the newest notifications first.
+func (d *DB) ListNotifications(limit int, unreadOnly bool) ([]Notification, error) {
+	where := ""
+	if unreadOnly {
+		where = "WHERE read = 0"
+	}
+	rows, err := d.db.Query(`SELECT id, item_id, parent_id, COALESCE(story_id, 0), COALESCE(by_user, ''),
+		COALESCE(text_preview, ''), created_at, read, kind, COALESCE(topic, '')
+		FROM notifications `+where+` ORDER BY created_at DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Notification
+	for rows.Next() {
+		var n Notification
+		var readInt int
+		if err := rows.Scan(&n.ID, &n.ItemID, &n.ParentID, &n.StoryID, &n.ByUser,
+			&n.TextPreview, &n.CreatedAt, &readInt, &n.Kind, &n.Topic); err != nil {
+			continue
+		}
+		n.Read = readInt != 0
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// MarkNotificationRead marks one notification as read.
+func (d *DB) MarkNotificationRead(id int) error {
+	_, err := d.db.Exec(`UPDATE notifications SET read = 1 WHERE id = ?`, id)
+	return err
+}
+
+// MarkAllNotificationsRead marks every notification as read.
+func (d *DB) MarkAllNotificationsRead() error {
+	_, err := d.db.Exec(`UPDATE notifications SET read = 1 WHERE read = 0`)
+	return err
+}
+
 // UnreadNotificationCount returns the count of unread notifications.
 func (d *DB) UnreadNotificationCount() int {
 	var count int
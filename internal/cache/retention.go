@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// accessFlushSize is how many reads are buffered before their access
+// times are written back. Batching keeps GetItem from writing on every
+// read while FlattenTree walks a large thread.
+const accessFlushSize = 500
+
+// pruneBatch is how many items are evicted per step while shrinking the
+// database below RetentionPolicy.MaxSize.
+const pruneBatch = 2000
+
+// touch records that an item was read, for least-recently-accessed eviction.
+func (d *DB) touch(id int) {
+	d.accessMu.Lock()
+	d.accessed[id] = struct{}{}
+	full := len(d.accessed) >= accessFlushSize
+	d.accessMu.Unlock()
+	if full {
+		d.FlushAccess()
+	}
+}
+
+// FlushAccess writes buffered access times to the items table.
+func (d *DB) FlushAccess() error {
+	d.accessMu.Lock()
+	ids := d.accessed
+	d.accessed = make(map[int]struct{})
+	d.accessMu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`UPDATE items SET accessed_at = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().Unix()
+	for id := range ids {
+		if _, err := stmt.Exec(now, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RetentionPolicy bounds how much the cache keeps.
+type RetentionPolicy struct {
+	MaxAge  time.Duration // evict items not read or fetched for this long; 0 = keep
+	MaxSize int64         // evict least recently accessed items above this many bytes; 0 = no limit
+	PinUser string        // never evict items posted by this user
+}
+
+// pinnedClause excludes items that are never evicted: the user's own
+// posts, comments being watched for replies and the stories they're on,
+// items referenced by notifications, and bookmarks. It takes PinUser as
+// its one parameter.
+const pinnedClause = `NOT (COALESCE(by_user, '') = ?1 AND ?1 != '')
+	AND id NOT IN (SELECT item_id FROM monitored_comments)
+	AND id NOT IN (SELECT parent_story_id FROM monitored_comments WHERE parent_story_id IS NOT NULL)
+	AND id NOT IN (SELECT item_id FROM notifications)
+	AND id NOT IN (SELECT item_id FROM bookmarks)`
+
+// PruneResult reports what Prune removed.
+type PruneResult struct {
+	Items         int64
+	Users         int64
+	StoryLists    int64
+	Notifications int64
+	Visits        int64 // stories whose reading history was dropped
+	UsedBefore    int64 // bytes in use before pruning
+	UsedAfter     int64
+}
+
+// Prune applies the retention policy. Freed pages are reused by later
+// writes; call Vacuum to shrink the file itself.
+func (d *DB) Prune(p RetentionPolicy) (PruneResult, error) {
+	var res PruneResult
+	if err := d.FlushAccess(); err != nil {
+		return res, err
+	}
+	var err error
+	if res.UsedBefore, err = d.usedBytes(); err != nil {
+		return res, err
+	}
+
+	if p.MaxAge > 0 {
+		cutoff := time.Now().Add(-p.MaxAge).Unix()
+		steps := []struct {
+			count *int64
+			query string
+			args  []interface{}
+		}{
+			{&res.Items, `DELETE FROM items WHERE accessed_at < ?2 AND ` + pinnedClause, []interface{}{p.PinUser, cutoff}},
+			{&res.Users, `DELETE FROM users WHERE fetched_at < ? AND id != ?`, []interface{}{cutoff, p.PinUser}},
+			{&res.StoryLists, `DELETE FROM story_lists WHERE fetched_at < ?`, []interface{}{cutoff}},
+			{&res.Notifications, `DELETE FROM notifications WHERE read = 1 AND created_at < ?`, []interface{}{cutoff}},
+			{&res.Visits, `DELETE FROM story_visits WHERE visited_at < ?`, []interface{}{cutoff}},
+			{new(int64), `DELETE FROM seen_comments WHERE story_id NOT IN (SELECT story_id FROM story_visits)`, nil},
+		}
+		for _, s := range steps {
+			r, err := d.db.Exec(s.query, s.args...)
+			if err != nil {
+				return res, fmt.Errorf("pruning: %w", err)
+			}
+			n, _ := r.RowsAffected()
+			*s.count += n
+		}
+	}
+
+	if p.MaxSize > 0 {
+		for {
+			used, err := d.usedBytes()
+			if err != nil {
+				return res, err
+			}
+			if used <= p.MaxSize {
+				break
+			}
+			r, err := d.db.Exec(`DELETE FROM items WHERE id IN (
+				SELECT id FROM items WHERE `+pinnedClause+`
+				ORDER BY accessed_at ASC LIMIT ?2)`, p.PinUser, pruneBatch)
+			if err != nil {
+				return res, fmt.Errorf("pruning: %w", err)
+			}
+			n, _ := r.RowsAffected()
+			if n == 0 {
+				break // only pinned items left
+			}
+			res.Items += n
+		}
+	}
+
+	res.UsedAfter, err = d.usedBytes()
+	return res, err
+}
+
+// usedBytes is the size of the pages holding data, excluding free pages
+// left behind by deletes.
+func (d *DB) usedBytes() (int64, error) {
+	var pageSize, pageCount, freePages int64
+	if err := d.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	if err := d.db.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := d.db.QueryRow(`PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return 0, err
+	}
+	return (pageCount - freePages) * pageSize, nil
+}
+
+// Vacuum rebuilds the database file, returning free pages to the OS.
+func (d *DB) Vacuum() error {
+	if err := d.FlushAccess(); err != nil {
+		return err
+	}
+	if _, err := d.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	_, err := d.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	return err
+}
+
+// Stats summarizes what the cache holds.
+type Stats struct {
+	Path           string
+	FileBytes      int64 // database file plus write-ahead log
+	UsedBytes      int64
+	Stories        int64
+	Comments       int64
+	OtherItems     int64
+	PinnedItems    int64
+	Users          int64
+	StoryLists     int64
+	Notifications  int64
+	OldestAccessed time.Time // zero if the cache is empty
+}
+
+// Stats reports the cache's size and contents. pinUser is counted as in
+// RetentionPolicy.PinUser.
+func (d *DB) Stats(pinUser string) (Stats, error) {
+	if err := d.FlushAccess(); err != nil {
+		return Stats{}, err
+	}
+	st := Stats{Path: d.path}
+	for _, f := range []string{d.path, d.path + "-wal"} {
+		if fi, err := os.Stat(f); err == nil {
+			st.FileBytes += fi.Size()
+		}
+	}
+	var err error
+	if st.UsedBytes, err = d.usedBytes(); err != nil {
+		return st, err
+	}
+
+	var oldest int64
+	counts := []struct {
+		dst   *int64
+		query string
+		args  []interface{}
+	}{
+		{&st.Stories, `SELECT COUNT(*) FROM items WHERE type = 'story'`, nil},
+		{&st.Comments, `SELECT COUNT(*) FROM items WHERE type = 'comment'`, nil},
+		{&st.OtherItems, `SELECT COUNT(*) FROM items WHERE type NOT IN ('story', 'comment')`, nil},
+		{&st.PinnedItems, `SELECT COUNT(*) FROM items WHERE NOT (` + pinnedClause + `)`, []interface{}{pinUser}},
+		{&st.Users, `SELECT COUNT(*) FROM users`, nil},
+		{&st.StoryLists, `SELECT COUNT(*) FROM story_lists`, nil},
+		{&st.Notifications, `SELECT COUNT(*) FROM notifications`, nil},
+		{&oldest, `SELECT COALESCE(MIN(accessed_at), 0) FROM items`, nil},
+	}
+	for _, c := range counts {
+		if err := d.db.QueryRow(c.query, c.args...).Scan(c.dst); err != nil {
+			return st, err
+		}
+	}
+	if oldest > 0 {
+		st.OldestAccessed = time.Unix(oldest, 0)
+	}
+	return st, nil
+}
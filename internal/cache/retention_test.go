@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// testDB opens an empty cache that is closed when the test ends.
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	d, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// putItems caches n comments numbered from first, each with a body of
+// size bytes, and backdates their access times so item id was last read
+// id seconds after the epoch: lower ids are evicted first.
+func putItems(t *testing.T, d *DB, first, n, size int) {
+	t.Helper()
+	items := make([]*api.Item, n)
+	for i := range items {
+		items[i] = &api.Item{ID: first + i, Type: "comment", By: "someone", Parent: 1, Text: strings.Repeat("x", size)}
+	}
+	if err := d.PutItems(items); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.db.Exec(`UPDATE items SET accessed_at = id, fetched_at = id WHERE id BETWEEN ? AND ?`, first, first+n-1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(t *testing.T, d *DB, id int) bool {
+	t.Helper()
+	return count(t, d, fmt.Sprintf(`SELECT COUNT(*) FROM items WHERE id = %d`, id)) == 1
+}
+
+// pin caches items 1 to 5 and references each of them from something
+// Prune must keep: item 1 is pg's own post, 2 a watched comment on story
+// 3, 4 the reply a notification points at and 5 a bookmark.
+func pin(t *testing.T, d *DB) {
+	t.Helper()
+	putItems(t, d, 1, 5, 10)
+	if _, err := d.db.Exec(`UPDATE items SET by_user = 'pg' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UpsertMonitoredComment(MonitoredComment{ItemID: 2, ParentStoryID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddNotification(4, 2, 3, "dang", "a reply", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.BookmarkItem(&api.Item{ID: 5, Type: "comment"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	d := testDB(t)
+	pin(t, d)
+	putItems(t, d, 10, 3, 10)
+	recent := time.Now().Add(-time.Hour).Unix()
+	if _, err := d.db.Exec(`UPDATE items SET accessed_at = ? WHERE id = 12`, recent); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutUser(&api.User{ID: "pg"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutUser(&api.User{ID: "dang"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutStoryList("top", []int{3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddNotification(20, 10, 3, "tptacek", "old and read", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordVisit(3, []int{2, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := execStmts(d.db,
+		`UPDATE users SET fetched_at = 1`,
+		`UPDATE story_lists SET fetched_at = 1`,
+		`UPDATE notifications SET read = 1 WHERE item_id = 20`,
+		`UPDATE story_visits SET visited_at = 1`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := d.Prune(RetentionPolicy{MaxAge: 24 * time.Hour, PinUser: "pg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Items 10 and 11 are old and unpinned; 12 was read an hour ago.
+	want := PruneResult{Items: 2, Users: 1, StoryLists: 1, Notifications: 1, Visits: 1}
+	res.UsedBefore, res.UsedAfter = 0, 0
+	if res != want {
+		t.Errorf("pruned %+v, want %+v", res, want)
+	}
+	for id := 1; id <= 5; id++ {
+		if !exists(t, d, id) {
+			t.Errorf("pinned item %d was pruned", id)
+		}
+	}
+	if exists(t, d, 10) || exists(t, d, 11) || !exists(t, d, 12) {
+		t.Error("wrong unpinned items pruned")
+	}
+	if n := count(t, d, `SELECT COUNT(*) FROM users WHERE id = 'pg'`); n != 1 {
+		t.Error("the pinned user's profile was pruned")
+	}
+	if n := count(t, d, `SELECT COUNT(*) FROM notifications`); n != 1 {
+		t.Errorf("%d notifications left, want the unread one", n)
+	}
+	if n := count(t, d, `SELECT COUNT(*) FROM seen_comments`); n != 0 {
+		t.Errorf("%d seen comments left for a pruned visit", n)
+	}
+}
+
+func TestPruneMaxSize(t *testing.T) {
+	d := testDB(t)
+	pin(t, d)
+	const n = 3 * pruneBatch
+	putItems(t, d, 100, n, 400)
+	before, err := d.usedBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := before * 2 / 3
+	res, err := d.Prune(RetentionPolicy{MaxSize: limit, PinUser: "pg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UsedBefore != before || res.UsedAfter > limit {
+		t.Errorf("used %d -> %d bytes, want at most %d", res.UsedBefore, res.UsedAfter, limit)
+	}
+	// Whole batches are evicted, oldest access first, and it stops as
+	// soon as the cache fits.
+	if res.Items == 0 || res.Items%pruneBatch != 0 || res.Items >= n {
+		t.Errorf("pruned %d items, want whole batches of %d short of all %d", res.Items, pruneBatch, n)
+	}
+	if exists(t, d, 100+int(res.Items)-1) || !exists(t, d, 100+int(res.Items)) {
+		t.Errorf("pruned %d items, but not the least recently accessed", res.Items)
+	}
+
+	// A limit only pinned items fit under leaves those and stops.
+	if _, err := d.Prune(RetentionPolicy{MaxSize: 1, PinUser: "pg"}); err != nil {
+		t.Fatal(err)
+	}
+	if left := count(t, d, `SELECT COUNT(*) FROM items`); left != 5 {
+		t.Errorf("%d items left, want the 5 pinned", left)
+	}
+}
+
+func TestAccessBatching(t *testing.T) {
+	d := testDB(t)
+	putItems(t, d, 1, accessFlushSize+1, 10)
+	accessed := func(id int) int {
+		t.Helper()
+		return count(t, d, fmt.Sprintf(`SELECT accessed_at FROM items WHERE id = %d`, id))
+	}
+
+	// Reads are buffered, not written one by one.
+	start := time.Now().Unix()
+	if item, _, _ := d.GetItem(1, time.Hour); item == nil {
+		t.Fatal("item 1 not cached")
+	}
+	if got := accessed(1); got != 1 {
+		t.Errorf("accessed_at = %d after one read, want it still 1 until a flush", got)
+	}
+	if err := d.FlushAccess(); err != nil {
+		t.Fatal(err)
+	}
+	if got := accessed(1); int64(got) < start {
+		t.Errorf("accessed_at = %d after a flush, want now", got)
+	}
+
+	// A full buffer flushes itself.
+	for id := 2; id < accessFlushSize+1; id++ {
+		d.GetItem(id, time.Hour)
+	}
+	if got := accessed(2); got != 2 {
+		t.Errorf("accessed_at = %d after %d reads, want them still buffered", got, accessFlushSize-1)
+	}
+	d.GetItem(accessFlushSize+1, time.Hour)
+	if got := accessed(2); int64(got) < start {
+		t.Errorf("accessed_at = %d after %d reads, want them flushed", got, accessFlushSize)
+	}
+
+	// Prune flushes first, so an item read since the last flush is kept.
+	putItems(t, d, 1000, 2, 10)
+	d.GetItem(1000, time.Hour)
+	if _, err := d.Prune(RetentionPolicy{MaxAge: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(t, d, 1000) || exists(t, d, 1001) {
+		t.Error("Prune went by access times that hadn't been flushed")
+	}
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/render"
+)
+
+// indexItem replaces an item's row in the items_fts full-text index. The
+// body is indexed as plain text so tags and entities don't match queries.
+// Deleting items drops their index rows through the items_fts_delete
+// trigger.
+func indexItem(tx *sql.Tx, id int, title, text, by string) error {
+	if _, err := tx.Exec(`DELETE FROM items_fts WHERE rowid = ?`, id); err != nil {
+		return err
+	}
+	if title == "" && text == "" {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO items_fts (rowid, title, body, author) VALUES (?, ?, ?, ?)`,
+		id, html.UnescapeString(title), render.HNToPlainText(text), by)
+	return err
+}
+
+// reindexAll rebuilds items_fts from the items table.
+func reindexAll(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, COALESCE(title, ''), COALESCE(text, ''), COALESCE(by_user, '') FROM items`)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id              int
+		title, text, by string
+	}
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.title, &r.text, &r.by); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, r := range all {
+		if err := indexItem(tx, r.id, r.title, r.text, r.by); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchOptions controls a local search.
+type SearchOptions struct {
+	Types       []string // item types searched when the query has no type: filter; empty = all
+	ByDate      bool     // newest first instead of best match first
+	Page        int      // 0-indexed
+	HitsPerPage int
+}
+
+// localTypes maps type: values to conditions on the items table. There is
+// no record of what reached the front page, so front means any story.
+var localTypes = map[string]string{
+	"story":   `i.type = 'story'`,
+	"comment": `i.type = 'comment'`,
+	"ask":     `(i.type = 'story' AND i.title LIKE 'Ask HN:%')`,
+	"show":    `(i.type = 'story' AND i.title LIKE 'Show HN:%')`,
+	"poll":    `i.type = 'poll'`,
+	"job":     `i.type = 'job'`,
+	"front":   `i.type = 'story'`,
+}
+
+// localNumeric maps numeric query fields to items columns.
+var localNumeric = map[string]string{
+	"points":   "i.score",
+	"comments": "i.descendants",
+}
+
+// Search runs a parsed query against every cached item, with the same
+// filters Algolia supports. Words match as prefixes anywhere in the
+// title, text or author; quoted phrases match exactly.
+func (d *DB) Search(q *api.Query, opts SearchOptions) (*api.SearchResult, error) {
+	var match []string
+	var where, authors, types []string
+	var args, authorArgs, typeArgs []interface{}
+	now := time.Now()
+
+	for _, t := range q.Terms {
+		switch t := t.(type) {
+		case api.Word:
+			match = append(match, ftsString(string(t))+"*")
+		case api.Phrase:
+			match = append(match, ftsString(string(t)))
+		case api.AuthorFilter:
+			authors = append(authors, `i.by_user = ?`)
+			authorArgs = append(authorArgs, string(t))
+		case api.TypeFilter:
+			types = append(types, localTypes[string(t)])
+		case api.StoryFilter:
+			where = append(where, `i.id IN (WITH RECURSIVE thread(id) AS (
+				SELECT ? UNION ALL SELECT c.id FROM items c JOIN thread ON c.parent_id = thread.id
+			) SELECT id FROM thread)`)
+			args = append(args, int(t))
+		case api.NumericFilter:
+			where = append(where, fmt.Sprintf("%s %s ?", localNumeric[t.Field], t.Op))
+			args = append(args, t.Value)
+		case api.DateFilter:
+			at, err := t.Time(now)
+			if err != nil {
+				return nil, err
+			}
+			if t.After {
+				where = append(where, `i.time_unix >= ?`)
+			} else {
+				where = append(where, `i.time_unix < ?`)
+			}
+			args = append(args, at.Unix())
+		}
+	}
+	if len(types) == 0 {
+		for _, typ := range opts.Types {
+			types = append(types, `i.type = ?`)
+			typeArgs = append(typeArgs, typ)
+		}
+	}
+	// Repeated author: and type: filters are OR'd, as with Algolia.
+	if len(authors) > 0 {
+		where = append(where, "("+strings.Join(authors, " OR ")+")")
+		args = append(args, authorArgs...)
+	}
+	if len(types) > 0 {
+		where = append(where, "("+strings.Join(types, " OR ")+")")
+		args = append(args, typeArgs...)
+	}
+	where = append(where, `i.deleted = 0`, `i.dead = 0`)
+
+	from := `items i`
+	order := `i.time_unix DESC`
+	if len(match) > 0 {
+		from = `items_fts JOIN items i ON i.id = items_fts.rowid`
+		where = append([]string{`items_fts MATCH ?`}, where...)
+		args = append([]interface{}{strings.Join(match, " ")}, args...)
+		if !opts.ByDate {
+			// Title and author hits outrank body hits.
+			order = `bm25(items_fts, 10.0, 1.0, 5.0)`
+		}
+	}
+	cond := strings.Join(where, " AND ")
+
+	res := &api.SearchResult{Page: opts.Page}
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM `+from+` WHERE `+cond, args...).Scan(&res.NbHits); err != nil {
+		return nil, fmt.Errorf("local search: %w", err)
+	}
+	perPage := max(opts.HitsPerPage, 1)
+	res.NbPages = (res.NbHits + perPage - 1) / perPage
+
+	cols := strings.Fields(strings.ReplaceAll(itemColumns, ",", " "))
+	for i, c := range cols {
+		cols[i] = "i." + c
+	}
+	rows, err := d.db.Query(`SELECT `+strings.Join(cols, ", ")+` FROM `+from+` WHERE `+cond+
+		` ORDER BY `+order+` LIMIT ? OFFSET ?`, append(args, perPage, opts.Page*perPage)...)
+	if err != nil {
+		return nil, fmt.Errorf("local search: %w", err)
+	}
+	for rows.Next() {
+		item, _, err := scanItem(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		res.Items = append(res.Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, item := range res.Items {
+		if item.Type == "comment" {
+			item.StoryTitle = d.storyTitle(item.Parent)
+		}
+	}
+	return res, nil
+}
+
+// storyTitle walks up the cached ancestors of id to the story's title, or
+// returns "" if the chain isn't cached.
+func (d *DB) storyTitle(id int) string {
+	var title sql.NullString
+	d.db.QueryRow(`WITH RECURSIVE up(id, parent_id, title) AS (
+			SELECT id, parent_id, title FROM items WHERE id = ?
+			UNION ALL SELECT i.id, i.parent_id, i.title FROM items i JOIN up ON i.id = up.parent_id
+		) SELECT title FROM up WHERE title IS NOT NULL LIMIT 1`, id).Scan(&title)
+	return title.String
+}
+
+// ftsString quotes s as an FTS5 string so operators and punctuation in
+// user input are matched literally.
+func ftsString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// searchItems is a small thread to search: a story, two comments on it,
+// a comment on another story and an Ask HN.
+var searchItems = []*api.Item{
+	{ID: 1, Type: "story", By: "pg", Time: 100, Title: "Rust &amp; Go in production", Score: 200, Descendants: 2},
+	{ID: 2, Type: "comment", By: "dang", Time: 200, Parent: 1, Text: "We moved from <i>Python</i> to Rust.<p>It&#x27;s fast."},
+	{ID: 3, Type: "comment", By: "tptacek", Time: 300, Parent: 2, Text: `Use <code>C++</code> or "AND" OR NOT, see <a href="https://rustacean.example">here</a>`},
+	{ID: 4, Type: "comment", By: "pg", Time: 400, Parent: 9, Text: "Rust elsewhere"},
+	{ID: 5, Type: "story", By: "someone", Time: 500, Title: "Ask HN: Is Rust worth it?", Score: 5},
+}
+
+func search(t *testing.T, d *DB, query string, opts SearchOptions) []int {
+	t.Helper()
+	q, err := api.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.HitsPerPage == 0 {
+		opts.HitsPerPage = 20
+	}
+	res, err := d.Search(q, opts)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	var ids []int
+	for _, item := range res.Items {
+		ids = append(ids, item.ID)
+	}
+	if res.NbHits < len(ids) {
+		t.Errorf("%s: %d hits counted for %d returned", query, res.NbHits, len(ids))
+	}
+	return ids
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearch(t *testing.T) {
+	d := testDB(t)
+	if err := d.PutItems(searchItems); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  []int // newest first
+	}{
+		{"rust", []int{5, 4, 3, 2, 1}},
+		{"rus", []int{5, 4, 3, 2, 1}}, // words match as prefixes
+		{"python", []int{2}},          // text inside tags is indexed
+		{"href", nil},                 // the tags themselves aren't
+		{"it's", []int{2}},            // entities are decoded
+		{`"rust & go"`, []int{1}},     // so are titles'
+		{"rustacean", []int{3}},       // link targets are
+		{"c++", []int{3}},             // punctuation is matched literally
+		{`AND OR NOT`, []int{3}},      // FTS operators are plain words
+		{`"and" or`, []int{3}},        // as are quotes
+		{`"in production"`, []int{1}}, // phrases are exact
+		{`"production in"`, nil},
+		{"tptacek", []int{3}}, // authors are indexed
+		{"rust author:pg", []int{4, 1}},
+		{"rust type:comment", []int{4, 3, 2}},
+		{"rust type:ask", []int{5}},
+		{"rust story:1", []int{3, 2, 1}},
+		{"rust points>100", []int{1}},
+		{"story:1", []int{3, 2, 1}}, // filters alone need no match
+	}
+	for _, tt := range tests {
+		if got := search(t, d, tt.query, SearchOptions{ByDate: true}); !sameIDs(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// By relevance, a title hit outranks body hits.
+	if got := search(t, d, "production", SearchOptions{}); !sameIDs(got, []int{1}) {
+		t.Errorf("production: got %v", got)
+	}
+	if got := search(t, d, "rust type:story", SearchOptions{}); len(got) != 2 {
+		t.Errorf("rust type:story: got %v", got)
+	}
+
+	// Comments carry their story's title.
+	q, _ := api.ParseQuery("python")
+	res, err := d.Search(q, SearchOptions{HitsPerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items[0].StoryTitle != "Rust &amp; Go in production" {
+		t.Errorf("story title = %q", res.Items[0].StoryTitle)
+	}
+}
+
+func TestSearchIndexFollowsItems(t *testing.T) {
+	d := testDB(t)
+	if err := d.PutItems(searchItems); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refetched items are reindexed, not indexed twice.
+	edited := *searchItems[1]
+	edited.Text = "We moved from Python to Zig."
+	if err := d.PutItem(&edited); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, d, "zig", SearchOptions{}); !sameIDs(got, []int{2}) {
+		t.Errorf("zig: got %v", got)
+	}
+	if got := search(t, d, "python", SearchOptions{}); !sameIDs(got, []int{2}) {
+		t.Errorf("python: got %v", got)
+	}
+	if got := search(t, d, "fast", SearchOptions{}); len(got) != 0 {
+		t.Errorf("fast: got %v after the edit removed it", got)
+	}
+	if n := count(t, d, `SELECT COUNT(*) FROM items_fts`); n != len(searchItems) {
+		t.Errorf("%d index rows for %d items", n, len(searchItems))
+	}
+
+	// Deleted and dead items aren't found; evicted ones leave the index.
+	gone := *searchItems[3]
+	gone.Deleted, gone.Text = true, ""
+	if err := d.PutItem(&gone); err != nil {
+		t.Fatal(err)
+	}
+	dead := *searchItems[4]
+	dead.Dead = true
+	if err := d.PutItem(&dead); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, d, "rust", SearchOptions{ByDate: true}); !sameIDs(got, []int{3, 1}) {
+		t.Errorf("rust: got %v", got)
+	}
+	if _, err := d.db.Exec(`DELETE FROM items WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, d, `SELECT COUNT(*) FROM items_fts WHERE rowid = 1`); n != 0 {
+		t.Error("evicted item left in the index")
+	}
+
+	// Paging.
+	if err := d.PutItems(searchItems); err != nil {
+		t.Fatal(err)
+	}
+	q, _ := api.ParseQuery("rust")
+	res, err := d.Search(q, SearchOptions{ByDate: true, HitsPerPage: 3, Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NbHits != 5 || res.NbPages != 2 || len(res.Items) != 2 || res.Items[0].ID != 2 {
+		t.Errorf("page 2: %d hits, %d pages, %d items", res.NbHits, res.NbPages, len(res.Items))
+	}
+}
+
+func TestFTSString(t *testing.T) {
+	for in, want := range map[string]string{
+		"rust":       `"rust"`,
+		`say "hi"`:   `"say ""hi"""`,
+		"NEAR(a b)":  `"NEAR(a b)"`,
+		"title:rust": `"title:rust"`,
+	} {
+		if got := ftsString(in); got != want {
+			t.Errorf("ftsString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+// SavedSearch is a named Algolia query shown as a custom tab.
+type SavedSearch struct {
+	Name      string
+	Query     string
+	Tags      string // hit type tags applied unless the query has a type: filter
+	ByDate    bool
+	CreatedAt time.Time
+}
+
+// ListSavedSearches returns all saved searches in creation order.
+func (d *DB) ListSavedSearches() ([]SavedSearch, error) {
+	rows, err := d.db.Query(`SELECT name, query, tags, by_date, created_at
+		FROM saved_searches ORDER BY created_at ASC, name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []SavedSearch
+	for rows.Next() {
+		var s SavedSearch
+		var byDate int
+		var createdAt int64
+		if err := rows.Scan(&s.Name, &s.Query, &s.Tags, &byDate, &createdAt); err != nil {
+			continue
+		}
+		s.ByDate = byDate != 0
+		s.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// GetSavedSearch returns the saved search with the given name, or nil.
+func (d *DB) GetSavedSearch(name string) *SavedSearch {
+	var s SavedSearch
+	var byDate int
+	var createdAt int64
+	err := d.db.QueryRow(`SELECT name, query, tags, by_date, created_at
+		FROM saved_searches WHERE name = ?`, name).Scan(&s.Name, &s.Query, &s.Tags, &byDate, &createdAt)
+	if err != nil {
+		return nil
+	}
+	s.ByDate = byDate != 0
+	s.CreatedAt = time.Unix(createdAt, 0)
+	return &s
+}
+
+// PutSavedSearch creates or replaces a saved search. Replacing keeps the
+// original tab position and drops the cached results.
+func (d *DB) PutSavedSearch(s SavedSearch) error {
+	var byDate int
+	if s.ByDate {
+		byDate = 1
+	}
+	_, err := d.db.Exec(`INSERT INTO saved_searches (name, query, tags, by_date, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET query = excluded.query, tags = excluded.tags, by_date = excluded.by_date`,
+		s.Name, s.Query, s.Tags, byDate, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	d.InvalidateStoryList(SavedSearchListType(s.Name))
+	return nil
+}
+
+// DeleteSavedSearch removes a saved search and its cached results.
+func (d *DB) DeleteSavedSearch(name string) error {
+	_, err := d.db.Exec(`DELETE FROM saved_searches WHERE name = ?`, name)
+	d.InvalidateStoryList(SavedSearchListType(name))
+	return err
+}
+
+// SavedSearchListType is the story_lists key holding a saved search's result IDs.
+func SavedSearchListType(name string) string {
+	return string(api.SavedSearchType(name))
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Visit is the reading history for one story: when it was last opened
+// and which of its comments existed then.
+type Visit struct {
+	StoryID   int
+	VisitedAt time.Time
+	Seen      map[int]bool
+}
+
+// GetVisit returns the last recorded visit to a story, or nil if it has
+// never been opened.
+func (d *DB) GetVisit(storyID int) (*Visit, error) {
+	var visitedAt int64
+	err := d.db.QueryRow(`SELECT visited_at FROM story_visits WHERE story_id = ?`, storyID).Scan(&visitedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Visit{StoryID: storyID, VisitedAt: time.Unix(visitedAt, 0), Seen: make(map[int]bool)}
+	rows, err := d.db.Query(`SELECT item_id FROM seen_comments WHERE story_id = ?`, storyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		v.Seen[id] = true
+	}
+	return v, rows.Err()
+}
+
+// RecordVisit marks a story as opened now, with ids as the comments it
+// had. IDs already recorded are kept, so comments that have since been
+// deleted don't come back as new.
+func (d *DB) RecordVisit(storyID int, ids []int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec(`INSERT INTO story_visits (story_id, first_visited_at, visited_at) VALUES (?, ?, ?)
+		ON CONFLICT(story_id) DO UPDATE SET visited_at = excluded.visited_at`, storyID, now, now); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO seen_comments (story_id, item_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range ids {
+		if _, err := stmt.Exec(storyID, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// newSince counts the comments in ids a visit hadn't seen, as the story
+// view does for its NEW badge.
+func newSince(v *Visit, ids []int) int {
+	n := 0
+	for _, id := range ids {
+		if v != nil && !v.Seen[id] {
+			n++
+		}
+	}
+	return n
+}
+
+func TestVisits(t *testing.T) {
+	d := testDB(t)
+	v, err := d.GetVisit(1)
+	if err != nil || v != nil {
+		t.Fatalf("unvisited story: visit %+v, err %v", v, err)
+	}
+	if n := newSince(v, []int{2, 3}); n != 0 {
+		t.Errorf("%d new on a first visit, want none", n)
+	}
+
+	start := time.Now().Truncate(time.Second)
+	if err := d.RecordVisit(1, []int{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordVisit(7, []int{8}); err != nil {
+		t.Fatal(err)
+	}
+	v, err = d.GetVisit(1)
+	if err != nil || v == nil {
+		t.Fatalf("visit not recorded: %v", err)
+	}
+	if len(v.Seen) != 2 || !v.Seen[2] || !v.Seen[3] || v.VisitedAt.Before(start) {
+		t.Errorf("visit = %+v", v)
+	}
+
+	// Two replies arrive and one comment is deleted before the next visit.
+	thread := []int{2, 4, 5}
+	if n := newSince(v, thread); n != 2 {
+		t.Errorf("%d new, want 2", n)
+	}
+	if _, err := d.db.Exec(`UPDATE story_visits SET first_visited_at = 1, visited_at = 1 WHERE story_id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RecordVisit(1, thread); err != nil {
+		t.Fatal(err)
+	}
+	v, err = d.GetVisit(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The watermark moves up; the deleted comment stays seen, so it can't
+	// come back as new.
+	if v.VisitedAt.Before(start) {
+		t.Errorf("visited_at = %s, want it moved to now", v.VisitedAt)
+	}
+	if first := count(t, d, `SELECT first_visited_at FROM story_visits WHERE story_id = 1`); first != 1 {
+		t.Errorf("first_visited_at = %d, want the first visit kept", first)
+	}
+	if len(v.Seen) != 4 {
+		t.Errorf("seen = %v, want 2 to 5", v.Seen)
+	}
+	if n := newSince(v, []int{2, 3, 4, 5}); n != 0 {
+		t.Errorf("%d new right after a visit", n)
+	}
+	if n := newSince(v, []int{2, 3, 4, 5, 6}); n != 1 {
+		t.Errorf("%d new, want 1", n)
+	}
+
+	// Visits are per story.
+	if v, _ := d.GetVisit(7); v == nil || len(v.Seen) != 1 || !v.Seen[8] {
+		t.Errorf("story 7 visit = %+v", v)
+	}
+}
@@ -0,0 +1,140 @@
+// Package cli implements nitpick's non-interactive subcommands. They share
+// the TUI's api.Client, cache.DB and saved login session, and print plain
+// text or JSON to stdout for use in scripts and pipelines.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+)
+
+// Env is the shared state handed to every subcommand.
+type Env struct {
+	Cfg    config.Config
+	Client *api.Client
+	DB     *cache.DB
+	Out    io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *Env, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"top":           {"top [--limit N] [--json]", storiesCmd(api.StoryTypeTop)},
+		"new":           {"new [--limit N] [--json]", storiesCmd(api.StoryTypeNew)},
+		"best":          {"best [--limit N] [--json]", storiesCmd(api.StoryTypeBest)},
+		"ask":           {"ask [--limit N] [--json]", storiesCmd(api.StoryTypeAsk)},
+		"show":          {"show [--limit N] [--json]", storiesCmd(api.StoryTypeShow)},
+		"jobs":          {"jobs [--limit N] [--json]", storiesCmd(api.StoryTypeJobs)},
+		"past":          {"past [--limit N] [--json]", runPast},
+		"item":          {"item [--json] ID", runItem},
+		"user":          {"user [--json] NAME", runUser},
+		"threads":       {"threads [--json] [NAME]", runThreads},
+		"search":        {"search [--limit N] [--page N] [--by-date] [--type all|stories|comments] [--local] [--json] QUERY...", runSearch},
+		"bookmarks":     {"bookmarks [--tag TAG] [--json]", runBookmarks},
+		"keys":          {"keys [--json]", runKeys},
+		"killfile":      {"killfile list [--json] | add user|domain|title PATTERN | rm ID | unhide ID", runKillfile},
+		"cache":         {"cache stats | prune [--max-age DUR] [--max-size SIZE] | vacuum", runCache},
+		"export":        {"export [--format json|ndjson|md|html] [-o FILE] ID", runExport},
+		"sync":          {"sync [--tabs top,ask,...] [--stories N] [--concurrency N] [--max-bytes SIZE] [--quiet]", runSync},
+		"notifications": {"notifications [--all] [--limit N] [--mark-read] [--json]", runNotifications},
+		"fakehn":        {"fakehn [--addr HOST:PORT] [--quiet]", runFakeHN},
+	}
+}
+
+// IsCommand reports whether name is a known subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok || name == "help"
+}
+
+// Run executes the subcommand named by args[0].
+func Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 || args[0] == "help" {
+		Usage(env.Out)
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q (see nitpick help)", args[0])
+	}
+	err := cmd.run(ctx, env, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(env.Out, "Usage: nitpick %s\n", cmd.usage)
+		return nil
+	}
+	return err
+}
+
+// Usage prints the list of subcommands.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: nitpick [flags] [command [command flags]]")
+	fmt.Fprintln(w, "\nWith no command, nitpick starts the interactive TUI. Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  nitpick %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nRun nitpick --help for the global flags.")
+}
+
+// newFlags returns a flag set that reports errors instead of exiting.
+func newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses args, allowing flags after positional arguments
+// (e.g. "nitpick search rust --limit 5").
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// itemURL links to an item on the configured HN website.
+func (env *Env) itemURL(id int) string {
+	return env.Client.Endpoints().ItemURL(id)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/fakehn"
+)
+
+// testEnv runs commands against a fake HN, with an empty cache.
+func testEnv(t *testing.T) (*Env, *bytes.Buffer) {
+	t.Helper()
+	srv, err := fakehn.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.CacheDir = dir
+	cfg.DBPath = filepath.Join(dir, "cache.db")
+	cfg.SessionPath = filepath.Join(dir, "session.json")
+	db, err := cache.Open(cfg.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	out := &bytes.Buffer{}
+	client := api.NewClient(api.Options{Endpoints: fakehn.Endpoints(ts.URL)})
+	return &Env{Cfg: cfg, Client: client, DB: db, Out: out}, out
+}
+
+func TestItem(t *testing.T) {
+	env, out := testEnv(t)
+	if err := Run(context.Background(), env, []string{"item", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "/item?id=1") {
+		t.Errorf("item 1 printed:\n%s", out)
+	}
+
+	out.Reset()
+	err := Run(context.Background(), env, []string{"item", "99999999"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("item 99999999: err = %v, want not found", err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("item 99999999 printed:\n%s", out)
+	}
+	if item, _, _ := env.DB.GetItem(0, 0); item != nil {
+		t.Errorf("cache has an empty item: %+v", item)
+	}
+}
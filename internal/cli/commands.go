@@ -0,0 +1,734 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/export"
+	"github.com/fragmede/nitpick/internal/fakehn"
+	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/syncer"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+)
+
+const textWidth = 80
+
+func storiesCmd(st api.StoryType) func(context.Context, *Env, []string) error {
+	return func(ctx context.Context, env *Env, args []string) error {
+		fs := newFlags(string(st))
+		limit := fs.Int("limit", env.Cfg.FetchPageSize, "number of stories")
+		asJSON := fs.Bool("json", false, "print JSON")
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+
+		ids, fresh, _ := env.DB.GetStoryList(string(st), env.Cfg.StoryListTTL)
+		if !fresh || len(ids) == 0 {
+			fetched, err := env.Client.GetStoryIDs(ctx, st)
+			if err != nil {
+				if len(ids) == 0 {
+					return err
+				}
+				// Serve the stale list rather than failing.
+			} else {
+				ids = fetched
+				env.DB.PutStoryList(string(st), ids)
+			}
+		}
+		if *limit > 0 && *limit < len(ids) {
+			ids = ids[:*limit]
+		}
+		items, err := getItems(ctx, env, ids)
+		if err != nil {
+			return err
+		}
+		return printStories(env, items, *asJSON)
+	}
+}
+
+func runPast(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("past")
+	limit := fs.Int("limit", env.Cfg.FetchPageSize, "number of stories")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	items, err := env.Client.GetPastStories(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	return printStories(env, items, *asJSON)
+}
+
+func runItem(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("item")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("item: expected one item ID")
+	}
+	id, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return fmt.Errorf("item: bad ID %q", rest[0])
+	}
+
+	items, err := getItems(ctx, env, []int{id})
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("item %d not found", id)
+	}
+	item := items[0]
+	if *asJSON {
+		return writeJSON(env.Out, item)
+	}
+
+	w := env.Out
+	if item.Title != "" {
+		fmt.Fprintln(w, html.UnescapeString(item.Title))
+	}
+	fmt.Fprintf(w, "%s by %s %s", item.Type, item.By, render.TimeAgo(item.Time))
+	if item.Type != "comment" {
+		fmt.Fprintf(w, " | %d points | %d comments", item.Score, item.Descendants)
+	}
+	fmt.Fprintln(w)
+	if item.URL != "" {
+		fmt.Fprintln(w, item.URL)
+	}
+	fmt.Fprintln(w, env.itemURL(item.ID))
+	if item.Text != "" {
+		fmt.Fprintf(w, "\n%s\n", render.HNToText(item.Text, textWidth))
+	}
+	return nil
+}
+
+func runUser(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("user")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("user: expected one username")
+	}
+
+	user, fresh, _ := env.DB.GetUser(rest[0], env.Cfg.UserTTL)
+	if !fresh || user == nil {
+		fetched, err := env.Client.GetUser(ctx, rest[0])
+		switch {
+		case err == nil:
+			env.DB.PutUser(fetched)
+			user = fetched
+		case user == nil:
+			return err
+		}
+	}
+	if user == nil || user.ID == "" {
+		return fmt.Errorf("user %q not found", rest[0])
+	}
+	if *asJSON {
+		return writeJSON(env.Out, user)
+	}
+
+	fmt.Fprintf(env.Out, "%s\nkarma:   %d\ncreated: %s\n", user.ID, user.Karma, render.TimeAgo(user.Created))
+	if user.About != "" {
+		fmt.Fprintf(env.Out, "\n%s\n", render.HNToText(user.About, textWidth))
+	}
+	return nil
+}
+
+func runThreads(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("threads")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var username string
+	switch len(rest) {
+	case 0:
+		session := newSession(env)
+		if !session.Load(env.Cfg.SessionPath) {
+			return fmt.Errorf("threads: not logged in; log in from the TUI or pass a username")
+		}
+		username = session.Username
+	case 1:
+		username = rest[0]
+	default:
+		return fmt.Errorf("threads: expected at most one username")
+	}
+
+	comments, _, err := env.Client.GetThreadsPage(ctx, username, "")
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(env.Out, comments)
+	}
+	for _, c := range comments {
+		indent := strings.Repeat("  ", c.Indent)
+		fmt.Fprintf(env.Out, "%s%s %s", indent, c.Author, render.TimeAgo(c.Time))
+		if c.StoryTitle != "" {
+			fmt.Fprintf(env.Out, " | on: %s", html.UnescapeString(c.StoryTitle))
+		}
+		fmt.Fprintf(env.Out, " | %s\n", env.itemURL(c.ID))
+		for _, line := range strings.Split(render.HNToText(c.Text, textWidth-len(indent)), "\n") {
+			fmt.Fprintf(env.Out, "%s  %s\n", indent, line)
+		}
+		fmt.Fprintln(env.Out)
+	}
+	return nil
+}
+
+func runSearch(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("search")
+	limit := fs.Int("limit", env.Cfg.FetchPageSize, "hits per page")
+	page := fs.Int("page", 0, "result page (0-indexed)")
+	byDate := fs.Bool("by-date", false, "newest first instead of by relevance")
+	kind := fs.String("type", "all", "all, stories or comments")
+	local := fs.Bool("local", false, "search the local cache instead of Algolia")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("search: missing query")
+	}
+
+	var tags string
+	var types []string
+	switch *kind {
+	case "all":
+		tags, types = "(story,comment)", []string{"story", "comment"}
+	case "stories", "story":
+		tags, types = "story", []string{"story"}
+	case "comments", "comment":
+		tags, types = "comment", []string{"comment"}
+	default:
+		return fmt.Errorf("search: --type must be all, stories or comments, got %q", *kind)
+	}
+
+	q, err := api.ParseQuery(strings.Join(rest, " "))
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	var result *api.SearchResult
+	if *local {
+		result, err = env.DB.Search(q, cache.SearchOptions{
+			Types: types, ByDate: *byDate, Page: *page, HitsPerPage: *limit,
+		})
+	} else {
+		params := q.ParamsWithTags(tags)
+		params.ByDate = *byDate
+		params.Page = *page
+		params.HitsPerPage = *limit
+		result, err = env.Client.Search(ctx, params)
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(env.Out, result)
+	}
+
+	if result.NbHits == 0 {
+		fmt.Fprintln(env.Out, "no results")
+		return nil
+	}
+	for _, item := range result.Items {
+		if item.Type == "story" {
+			printStory(env, item)
+			continue
+		}
+		fmt.Fprintf(env.Out, "%s %s", item.By, render.TimeAgo(item.Time))
+		if item.StoryTitle != "" {
+			fmt.Fprintf(env.Out, " | on: %s", html.UnescapeString(item.StoryTitle))
+		}
+		fmt.Fprintf(env.Out, " | %s\n", env.itemURL(item.ID))
+		for _, line := range strings.Split(render.HNToText(item.Text, textWidth), "\n") {
+			fmt.Fprintf(env.Out, "  %s\n", line)
+		}
+		fmt.Fprintln(env.Out)
+	}
+	fmt.Fprintf(env.Out, "page %d of %d, %d hits\n", result.Page+1, result.NbPages, result.NbHits)
+	return nil
+}
+
+func runNotifications(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("notifications")
+	all := fs.Bool("all", false, "include read notifications")
+	limit := fs.Int("limit", 50, "maximum number to show")
+	markRead := fs.Bool("mark-read", false, "mark the listed notifications as read")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	notes, err := env.DB.ListNotifications(*limit, !*all)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if notes == nil {
+			notes = []cache.Notification{}
+		}
+		if err := writeJSON(env.Out, notes); err != nil {
+			return err
+		}
+	} else {
+		for _, n := range notes {
+			mark := " "
+			if !n.Read {
+				mark = "*"
+			}
+			what := "replied"
+			if n.Topic != "" {
+				what = fmt.Sprintf("mentioned %q", n.Topic)
+			}
+			fmt.Fprintf(env.Out, "%s %s %s %s | %s\n", mark, n.ByUser, what, render.TimeAgo(n.CreatedAt), env.itemURL(n.ItemID))
+			if n.TextPreview != "" {
+				fmt.Fprintf(env.Out, "  %s\n", oneLine(n.TextPreview))
+			}
+		}
+	}
+	if *markRead {
+		for _, n := range notes {
+			env.DB.MarkNotificationRead(n.ID)
+		}
+	}
+	return nil
+}
+
+func runBookmarks(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("bookmarks")
+	tag := fs.String("tag", "", "only bookmarks with this tag")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	marks, err := env.DB.ListBookmarks(strings.ToLower(*tag))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if marks == nil {
+			marks = []cache.Bookmark{}
+		}
+		return writeJSON(env.Out, marks)
+	}
+	for _, b := range marks {
+		title := html.UnescapeString(b.Title)
+		if b.Type == "comment" {
+			title = fmt.Sprintf("%s's comment on: %s", b.By, title)
+		}
+		fmt.Fprintf(env.Out, "%s | %s", title, env.itemURL(b.ItemID))
+		for _, t := range b.Tags {
+			fmt.Fprintf(env.Out, " #%s", t)
+		}
+		fmt.Fprintln(env.Out)
+		if b.Note != "" {
+			fmt.Fprintf(env.Out, "  %s\n", oneLine(b.Note))
+		}
+	}
+	return nil
+}
+
+// runKeys lists the key bindings in effect after the config's preset and
+// [keys] overrides, with the action names [keys] accepts.
+func runKeys(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("keys")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	type binding struct {
+		Action string   `json:"action"`
+		Keys   []string `json:"keys"`
+		Help   string   `json:"help"`
+	}
+	var result []binding
+	for _, a := range keys.Keys.Actions() {
+		result = append(result, binding{a.Name, a.Binding.Keys(), a.Binding.Help().Desc})
+	}
+	if *asJSON {
+		return writeJSON(env.Out, result)
+	}
+	for _, b := range result {
+		ks := make([]string, len(b.Keys))
+		for i, k := range b.Keys {
+			ks[i] = strconv.Quote(k)
+		}
+		fmt.Fprintf(env.Out, "%-28s %-32s %s\n", b.Action, strings.Join(ks, ", "), b.Help)
+	}
+	return nil
+}
+
+func runKillfile(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("killfile: expected list, add, rm or unhide")
+	}
+
+	switch args[0] {
+	case "list":
+		fs := newFlags("killfile list")
+		asJSON := fs.Bool("json", false, "print JSON")
+		if _, err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		rules, err := env.DB.ListKillRules()
+		if err != nil {
+			return err
+		}
+		hidden, err := env.DB.HiddenStories()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if rules == nil {
+				rules = []cache.KillRule{}
+			}
+			if hidden == nil {
+				hidden = []int{}
+			}
+			return writeJSON(env.Out, struct {
+				Rules  []cache.KillRule
+				Hidden []int
+			}{rules, hidden})
+		}
+		for _, r := range rules {
+			fmt.Fprintf(env.Out, "%4d  %-6s  %s\n", r.ID, r.Kind, r.Pattern)
+		}
+		if len(hidden) > 0 {
+			fmt.Fprintf(env.Out, "%d hidden stories\n", len(hidden))
+		}
+		return nil
+
+	case "add":
+		if len(args) != 3 {
+			return fmt.Errorf("killfile add: expected a kind (user, domain, title) and a pattern")
+		}
+		return env.DB.AddKillRule(args[1], args[2])
+
+	case "rm", "unhide":
+		if len(args) != 2 {
+			return fmt.Errorf("killfile %s: expected one ID", args[0])
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("killfile %s: bad ID %q", args[0], args[1])
+		}
+		if args[0] == "rm" {
+			return env.DB.DeleteKillRule(id)
+		}
+		ok, err := env.DB.UnhideStory(id)
+		if err == nil && !ok {
+			err = fmt.Errorf("killfile unhide: story %d isn't hidden", id)
+		}
+		return err
+	}
+	return fmt.Errorf("killfile: unknown subcommand %q (want list, add, rm or unhide)", args[0])
+}
+
+func runExport(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("export")
+	formatName := fs.String("format", env.Cfg.ExportFormat, "json, ndjson, md or html")
+	outPath := fs.String("o", "", "write to FILE instead of stdout")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("export: expected one item ID")
+	}
+	id, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return fmt.Errorf("export: bad ID %q", rest[0])
+	}
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	if *outPath == "" {
+		return export.Export(ctx, env.Client, env.DB, env.Cfg, id, env.Out, format)
+	}
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	err = export.Export(ctx, env.Client, env.DB, env.Cfg, id, f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*outPath)
+	}
+	return err
+}
+
+func runSync(ctx context.Context, env *Env, args []string) error {
+	opts := syncer.OptionsFromConfig(env.Cfg)
+	fs := newFlags("sync")
+	tabs := fs.String("tabs", strings.Join(env.Cfg.SyncTabs, ","), "comma-separated tabs to sync")
+	fs.IntVar(&opts.Stories, "stories", opts.Stories, "stories per tab")
+	fs.IntVar(&opts.Concurrency, "concurrency", opts.Concurrency, "parallel requests")
+	maxBytes := fs.String("max-bytes", "", "stop after downloading this much (e.g. 50MB, 0 = no limit)")
+	quiet := fs.Bool("quiet", false, "only print the summary")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("sync: unexpected argument %q", rest[0])
+	}
+
+	opts.Tabs = nil
+	for _, name := range strings.Split(*tabs, ",") {
+		st := api.StoryType(strings.TrimSpace(name))
+		if !syncer.CanSync(st) {
+			return fmt.Errorf("sync: can't sync tab %q", name)
+		}
+		opts.Tabs = append(opts.Tabs, st)
+	}
+	if *maxBytes != "" {
+		if opts.MaxBytes, err = config.ParseSize(*maxBytes); err != nil {
+			return fmt.Errorf("sync: %w", err)
+		}
+	}
+
+	var report func(syncer.Progress)
+	if !*quiet {
+		report = func(p syncer.Progress) { fmt.Fprintln(env.Out, p) }
+	}
+	p, err := syncer.New(env.Client, env.DB, env.Cfg, opts).Run(ctx, report)
+	switch {
+	case errors.Is(err, syncer.ErrBudget):
+		fmt.Fprintf(env.Out, "Stopped at the %s byte budget.\n", syncer.FormatBytes(opts.MaxBytes))
+	case err != nil:
+		return err
+	}
+	fmt.Fprintf(env.Out, "Synced %d items (%s).\n", p.Items, syncer.FormatBytes(p.Bytes))
+	return nil
+}
+
+func runCache(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("cache: expected stats, prune or vacuum")
+	}
+	pinUser := sessionUser(env)
+
+	switch args[0] {
+	case "stats":
+		st, err := env.DB.Stats(pinUser)
+		if err != nil {
+			return err
+		}
+		w := env.Out
+		fmt.Fprintf(w, "path:          %s\n", st.Path)
+		fmt.Fprintf(w, "file size:     %s (%s in use)\n", syncer.FormatBytes(st.FileBytes), syncer.FormatBytes(st.UsedBytes))
+		fmt.Fprintf(w, "stories:       %d\n", st.Stories)
+		fmt.Fprintf(w, "comments:      %d\n", st.Comments)
+		fmt.Fprintf(w, "other items:   %d\n", st.OtherItems)
+		fmt.Fprintf(w, "pinned items:  %d\n", st.PinnedItems)
+		fmt.Fprintf(w, "users:         %d\n", st.Users)
+		fmt.Fprintf(w, "story lists:   %d\n", st.StoryLists)
+		fmt.Fprintf(w, "notifications: %d\n", st.Notifications)
+		if !st.OldestAccessed.IsZero() {
+			fmt.Fprintf(w, "oldest read:   %s\n", render.TimeAgo(st.OldestAccessed.Unix()))
+		}
+		return nil
+
+	case "prune":
+		fs := newFlags("cache prune")
+		maxAge := fs.Duration("max-age", env.Cfg.CacheMaxAge, "evict items not read for this long (0 = never)")
+		maxSize := fs.String("max-size", "", "evict least recently read items above this size (0 = no limit)")
+		if _, err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		policy := cache.RetentionPolicy{MaxAge: *maxAge, MaxSize: env.Cfg.CacheMaxSize, PinUser: pinUser}
+		if *maxSize != "" {
+			n, err := config.ParseSize(*maxSize)
+			if err != nil {
+				return fmt.Errorf("cache prune: %w", err)
+			}
+			policy.MaxSize = n
+		}
+		res, err := env.DB.Prune(policy)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Out, "Removed %d items, %d users, %d story lists, %d notifications, %d story visits.\n",
+			res.Items, res.Users, res.StoryLists, res.Notifications, res.Visits)
+		fmt.Fprintf(env.Out, "In use: %s -> %s. Run nitpick cache vacuum to shrink the file.\n",
+			syncer.FormatBytes(res.UsedBefore), syncer.FormatBytes(res.UsedAfter))
+		return nil
+
+	case "vacuum":
+		st, err := env.DB.Stats(pinUser)
+		if err != nil {
+			return err
+		}
+		if err := env.DB.Vacuum(); err != nil {
+			return err
+		}
+		after, err := env.DB.Stats(pinUser)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Out, "File size: %s -> %s.\n", syncer.FormatBytes(st.FileBytes), syncer.FormatBytes(after.FileBytes))
+		return nil
+	}
+	return fmt.Errorf("cache: unknown subcommand %q (want stats, prune or vacuum)", args[0])
+}
+
+func runFakeHN(ctx context.Context, env *Env, args []string) error {
+	fs := newFlags("fakehn")
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	quiet := fs.Bool("quiet", false, "don't log requests")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("fakehn: unexpected argument %q", rest[0])
+	}
+
+	srv, err := fakehn.New()
+	if err != nil {
+		return fmt.Errorf("fakehn: %w", err)
+	}
+	if !*quiet {
+		srv.Log = log.New(env.Out, "", log.Ltime)
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("fakehn: %w", err)
+	}
+	ep := fakehn.Endpoints("http://" + ln.Addr().String())
+	// A separate cache dir keeps fake items and the fake login out of
+	// the real cache.
+	fmt.Fprintf(env.Out, "Fake HN listening on %s. To use it, run:\n\n", ep.HN)
+	fmt.Fprintf(env.Out, "  nitpick --cache-dir %s --firebase-url %s --algolia-url %s --hn-url %s\n\n",
+		filepath.Join(os.TempDir(), "nitpick-fakehn"), ep.Firebase, ep.Algolia, ep.HN)
+	fmt.Fprintf(env.Out, "Log in as demo, password demo. Press Ctrl-C to stop.\n")
+
+	hs := &http.Server{Handler: srv}
+	go func() {
+		<-ctx.Done()
+		hs.Close()
+	}()
+	if err := hs.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("fakehn: %w", err)
+	}
+	return nil
+}
+
+// newSession returns a login session that talks to the same HN as
+// env.Client.
+func newSession(env *Env) *auth.Session {
+	return auth.NewSession(auth.Options{BaseURL: env.Client.Endpoints().HN, Transport: env.Client.Transport()})
+}
+
+// sessionUser returns the logged-in username from the saved session, or "".
+func sessionUser(env *Env) string {
+	session := newSession(env)
+	if !session.Load(env.Cfg.SessionPath) {
+		return ""
+	}
+	return session.Username
+}
+
+// getItems returns the items for ids in order, using fresh cache entries
+// and fetching the rest. Items that can't be loaded are skipped.
+func getItems(ctx context.Context, env *Env, ids []int) ([]*api.Item, error) {
+	byID := make(map[int]*api.Item, len(ids))
+	stale := make(map[int]*api.Item)
+	var missing []int
+	for _, id := range ids {
+		item, fresh, _ := env.DB.GetItem(id, env.Cfg.ItemTTL)
+		switch {
+		case item != nil && fresh:
+			byID[id] = item
+		default:
+			if item != nil {
+				stale[id] = item
+			}
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		fetched, err := env.Client.BatchGetItems(ctx, missing)
+		if err != nil && !api.IsPartial(err) && len(stale) == 0 && len(byID) == 0 {
+			return nil, err
+		}
+		for i, item := range fetched {
+			if item != nil {
+				env.DB.PutItem(item)
+				byID[missing[i]] = item
+			}
+		}
+		for id, item := range stale {
+			if byID[id] == nil {
+				byID[id] = item
+			}
+		}
+	}
+
+	items := make([]*api.Item, 0, len(ids))
+	for _, id := range ids {
+		if item := byID[id]; item != nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func printStories(env *Env, items []*api.Item, asJSON bool) error {
+	w := env.Out
+	if asJSON {
+		if items == nil {
+			items = []*api.Item{}
+		}
+		return writeJSON(w, items)
+	}
+	for i, item := range items {
+		fmt.Fprintf(w, "%3d. ", i+1)
+		printStory(env, item)
+	}
+	return nil
+}
+
+func printStory(env *Env, item *api.Item) {
+	w := env.Out
+	title := html.UnescapeString(item.Title)
+	if u, err := url.Parse(item.URL); err == nil && u.Hostname() != "" {
+		title += " (" + u.Hostname() + ")"
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintf(w, "     %d points by %s %s | %d comments | %s\n",
+		item.Score, item.By, render.TimeAgo(item.Time), item.Descendants, env.itemURL(item.ID))
+}
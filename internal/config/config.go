@@ -18,7 +18,31 @@ type Config struct {
 	MonitorInterval  time.Duration
 	MonitorMaxDepth  int
 	MonitorSeedCount int
+	AlertInterval    time.Duration
+	ProbeInterval    time.Duration
 	FetchPageSize    int
+	ExportDir        string
+	ExportFormat     string
+	SyncTabs         []string
+	SyncStories      int
+	SyncConcurrency  int
+	SyncMaxBytes     int64
+	SyncInterval     time.Duration // 0 disables the in-app background sync
+	CacheMaxAge      time.Duration // 0 keeps items forever
+	CacheMaxSize     int64         // 0 means no size limit
+	SyncFavorites    bool          // mirror bookmarks to HN favorites
+	SyncHidden       bool          // mirror hidden stories to HN's hide list
+	KeyPreset        string        // vim, emacs or less
+	Theme            string        // auto, a built-in theme, or a custom theme name or file
+	FirebaseURL      string        // "" means HN's own; see api.Endpoints
+	AlgoliaURL       string
+	HNURL            string
+	Record           string // directory to record HTTP traffic to, or ""
+	Replay           string // directory of a recording to replay instead of the network, or ""
+
+	// KeyBindings holds the config file's [keys] section: action name to
+	// keys, applied over KeyPreset.
+	KeyBindings map[string][]string
 }
 
 func Default() Config {
@@ -35,7 +59,19 @@ func Default() Config {
 		MonitorInterval:  30 * time.Second,
 		MonitorMaxDepth:  2,
 		MonitorSeedCount: 50,
+		AlertInterval:    2 * time.Minute,
+		ProbeInterval:    15 * time.Second,
 		FetchPageSize:    30,
+		ExportDir:        filepath.Join(cacheDir, "exports"),
+		ExportFormat:     "json",
+		SyncTabs:         []string{"top"},
+		SyncStories:      30,
+		SyncConcurrency:  8,
+		SyncMaxBytes:     100 << 20,
+		CacheMaxAge:      30 * 24 * time.Hour,
+		CacheMaxSize:     500 << 20,
+		KeyPreset:        "vim",
+		Theme:            "auto",
 	}
 }
 
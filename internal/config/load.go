@@ -0,0 +1,398 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one user-overridable Config field. The same entry drives the
+// config file key (name), the environment variable (NITPICK_NAME) and the
+// command-line flag (--name with dashes).
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func (s setting) envVar() string   { return "NITPICK_" + strings.ToUpper(s.name) }
+func (s setting) flagName() string { return strings.ReplaceAll(s.name, "_", "-") }
+
+func pathSetting(name, usage string, field func(*Config) *string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return errors.New("path must not be empty")
+		}
+		*field(c) = expandHome(v)
+		return nil
+	}}
+}
+
+func durationSetting(name, usage string, field func(*Config) *time.Duration) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (use e.g. 90s, 5m, 1h)", v)
+		}
+		if d <= 0 {
+			return fmt.Errorf("duration must be positive, got %s", v)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
+
+func intSetting(name, usage string, min, max int, field func(*Config) *int) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		if n < min || n > max {
+			return fmt.Errorf("must be between %d and %d, got %d", min, max, n)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func choiceSetting(name, usage string, choices []string, field func(*Config) *string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		for _, choice := range choices {
+			if v == choice {
+				*field(c) = v
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(choices, ", "), v)
+	}}
+}
+
+func stringSetting(name, usage string, field func(*Config) *string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		if strings.TrimSpace(v) == "" {
+			return errors.New("must not be empty")
+		}
+		*field(c) = strings.TrimSpace(v)
+		return nil
+	}}
+}
+
+// urlSetting takes an http or https base URL.
+func urlSetting(name, usage string, field func(*Config) *string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		u, err := url.Parse(strings.TrimSpace(v))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URL %q (use e.g. http://localhost:8080)", v)
+		}
+		*field(c) = strings.TrimRight(u.String(), "/")
+		return nil
+	}}
+}
+
+func boolSetting(name, usage string, field func(*Config) *bool) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q (use true or false)", v)
+		}
+		*field(c) = b
+		return nil
+	}}
+}
+
+// intervalSetting is a durationSetting that also accepts 0 or "off".
+func intervalSetting(name, usage string, field func(*Config) *time.Duration) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		if v == "0" || v == "off" {
+			*field(c) = 0
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid duration %q (use e.g. 30m, 1h, or off)", v)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
+
+// listSetting takes a comma-separated list (or an array in the config
+// file) whose elements must all be in choices.
+func listSetting(name, usage string, choices []string, field func(*Config) *[]string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		var list []string
+		for _, elem := range strings.Split(v, ",") {
+			elem = strings.TrimSpace(elem)
+			if elem == "" {
+				continue
+			}
+			if !slices.Contains(choices, elem) {
+				return fmt.Errorf("unknown value %q (want %s)", elem, strings.Join(choices, ", "))
+			}
+			list = append(list, elem)
+		}
+		*field(c) = list
+		return nil
+	}}
+}
+
+// sizeSetting takes a byte count with an optional KB/MB/GB suffix
+// (powers of 1024). 0 means unlimited.
+func sizeSetting(name, usage string, field func(*Config) *int64) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, v string) error {
+		n, err := ParseSize(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+// ParseSize parses a byte count such as 500000, 512KB, 100MB or 2GB.
+func ParseSize(v string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(v))
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500KB, 100MB, 2GB)", v)
+	}
+	return n * mult, nil
+}
+
+var settings = []setting{
+	pathSetting("cache_dir", "directory for the cache, session and log files", func(c *Config) *string { return &c.CacheDir }),
+	pathSetting("db_path", "SQLite cache database (default <cache_dir>/cache.db)", func(c *Config) *string { return &c.DBPath }),
+	pathSetting("session_path", "saved login session (default <cache_dir>/session.json)", func(c *Config) *string { return &c.SessionPath }),
+	pathSetting("log_path", "debug log file (default <cache_dir>/debug.log)", func(c *Config) *string { return &c.LogPath }),
+	durationSetting("story_list_ttl", "how long story lists stay fresh", func(c *Config) *time.Duration { return &c.StoryListTTL }),
+	durationSetting("item_ttl", "how long stories stay fresh", func(c *Config) *time.Duration { return &c.ItemTTL }),
+	durationSetting("comment_ttl", "how long comments stay fresh", func(c *Config) *time.Duration { return &c.CommentTTL }),
+	durationSetting("user_ttl", "how long user profiles stay fresh", func(c *Config) *time.Duration { return &c.UserTTL }),
+	durationSetting("monitor_interval", "how often to check for replies", func(c *Config) *time.Duration { return &c.MonitorInterval }),
+	durationSetting("alert_interval", "how often to poll keyword/domain alerts", func(c *Config) *time.Duration { return &c.AlertInterval }),
+	durationSetting("probe_interval", "how often to check connectivity while offline or idle", func(c *Config) *time.Duration { return &c.ProbeInterval }),
+	intSetting("monitor_max_depth", "how many reply levels below your comments to watch", 0, 10, func(c *Config) *int { return &c.MonitorMaxDepth }),
+	intSetting("monitor_seed_count", "how many of your recent items to watch for replies", 1, 1000, func(c *Config) *int { return &c.MonitorSeedCount }),
+	intSetting("fetch_page_size", "stories and search hits fetched per page", 1, 500, func(c *Config) *int { return &c.FetchPageSize }),
+	listSetting("sync_tabs", "tabs whose stories nitpick sync downloads", []string{"top", "new", "best", "ask", "show", "jobs", "past"}, func(c *Config) *[]string { return &c.SyncTabs }),
+	intSetting("sync_stories", "how many stories per tab to sync", 1, 500, func(c *Config) *int { return &c.SyncStories }),
+	intSetting("sync_concurrency", "parallel requests while syncing", 1, 64, func(c *Config) *int { return &c.SyncConcurrency }),
+	sizeSetting("sync_max_bytes", "stop a sync after downloading this much (0 = no limit)", func(c *Config) *int64 { return &c.SyncMaxBytes }),
+	intervalSetting("sync_interval", "run a background sync this often inside the TUI (default off)", func(c *Config) *time.Duration { return &c.SyncInterval }),
+	intervalSetting("cache_max_age", "evict cached items not read for this long (0 = never)", func(c *Config) *time.Duration { return &c.CacheMaxAge }),
+	sizeSetting("cache_max_size", "evict least recently read items above this size (0 = no limit)", func(c *Config) *int64 { return &c.CacheMaxSize }),
+	pathSetting("export_dir", "where the story view's export key writes files (default <cache_dir>/exports)", func(c *Config) *string { return &c.ExportDir }),
+	choiceSetting("export_format", "format for the story view's export key", []string{"json", "ndjson", "md", "html"}, func(c *Config) *string { return &c.ExportFormat }),
+	boolSetting("sync_favorites", "mirror bookmarks to your HN favorites when logged in", func(c *Config) *bool { return &c.SyncFavorites }),
+	choiceSetting("key_preset", "key binding preset (also preset in [keys])", []string{"vim", "emacs", "less"}, func(c *Config) *string { return &c.KeyPreset }),
+	boolSetting("sync_hidden", "also hide stories on HN when you hide them here while logged in", func(c *Config) *bool { return &c.SyncHidden }),
+	urlSetting("firebase_url", "HN API base URL, e.g. from nitpick fakehn (default https://hacker-news.firebaseio.com/v0)", func(c *Config) *string { return &c.FirebaseURL }),
+	urlSetting("algolia_url", "HN Search API base URL (default https://hn.algolia.com/api/v1)", func(c *Config) *string { return &c.AlgoliaURL }),
+	urlSetting("hn_url", "HN website base URL, for login, posting and item links (default https://news.ycombinator.com)", func(c *Config) *string { return &c.HNURL }),
+	pathSetting("record", "record every HTTP request and response to a cassette in this directory", func(c *Config) *string { return &c.Record }),
+	pathSetting("replay", "answer HTTP requests from a cassette recorded with --record, without the network, your cache or your saved login", func(c *Config) *string { return &c.Replay }),
+	stringSetting("theme", "color theme: auto, dark, light, high-contrast, 16color, or a custom theme name or file", func(c *Config) *string { return &c.Theme }),
+}
+
+// derivedPaths default to a file or directory under CacheDir unless set
+// explicitly.
+var derivedPaths = map[string]func(c *Config) (*string, string){
+	"db_path":      func(c *Config) (*string, string) { return &c.DBPath, "cache.db" },
+	"session_path": func(c *Config) (*string, string) { return &c.SessionPath, "session.json" },
+	"log_path":     func(c *Config) (*string, string) { return &c.LogPath, "debug.log" },
+	"export_dir":   func(c *Config) (*string, string) { return &c.ExportDir, "exports" },
+}
+
+// Load builds the effective configuration from the defaults, then the
+// config file, then NITPICK_* environment variables, then command-line
+// flags, each overriding the last. It returns the arguments left after
+// the flags.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+	explicit := make(map[string]bool)
+
+	// Parse flags first so --config is known, but apply them last.
+	type pending struct {
+		s setting
+		v string
+	}
+	var fromFlags []pending
+	fs := flag.NewFlagSet("nitpick", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", "", "config file (default "+DefaultConfigPath()+")")
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flagName(), s.usage, func(v string) error {
+			fromFlags = append(fromFlags, pending{s, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cfg, nil, err
+		}
+		return cfg, nil, fmt.Errorf("%w (see nitpick --help)", err)
+	}
+
+	// Config file.
+	path, required := *configPath, true
+	if path == "" {
+		path, required = os.Getenv("NITPICK_CONFIG"), true
+	}
+	if path == "" {
+		path, required = DefaultConfigPath(), false
+	}
+	if err := applyFile(&cfg, expandHome(path), required, explicit); err != nil {
+		return cfg, nil, err
+	}
+
+	// Environment.
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.envVar()); ok {
+			if err := s.set(&cfg, v); err != nil {
+				return cfg, nil, fmt.Errorf("%s: %w", s.envVar(), err)
+			}
+			explicit[s.name] = true
+		}
+	}
+
+	// Flags.
+	for _, p := range fromFlags {
+		if err := p.s.set(&cfg, p.v); err != nil {
+			return cfg, nil, fmt.Errorf("--%s: %w", p.s.flagName(), err)
+		}
+		explicit[p.s.name] = true
+	}
+
+	for name, field := range derivedPaths {
+		if !explicit[name] {
+			ptr, file := field(&cfg)
+			*ptr = filepath.Join(cfg.CacheDir, file)
+		}
+	}
+	return cfg, fs.Args(), nil
+}
+
+func applyFile(cfg *Config, path string, required bool, explicit map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return fmt.Errorf("reading config: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := parseTOML(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.name] = s
+	}
+	for _, e := range entries {
+		if e.section == "keys" && e.key != "preset" {
+			if err := applyKeyBinding(cfg, e); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, e.line, err)
+			}
+			continue
+		}
+		if e.section == "keys" {
+			e.key = "key_preset"
+		} else if e.section != "" {
+			return fmt.Errorf("%s:%d: unknown section [%s]", path, e.line, e.section)
+		}
+		s, ok := byName[e.key]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, e.line, e.key)
+		}
+		var v string
+		switch val := e.value.(type) {
+		case string:
+			v = val
+		case int64:
+			v = strconv.FormatInt(val, 10)
+		case bool:
+			v = strconv.FormatBool(val)
+		case []string:
+			v = strings.Join(val, ",")
+		default:
+			return fmt.Errorf("%s:%d: %s: expected a string, number, boolean or list", path, e.line, e.key)
+		}
+		if err := s.set(cfg, v); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, e.line, e.key, err)
+		}
+		explicit[e.key] = true
+	}
+	return nil
+}
+
+// applyKeyBinding records one action = keys entry from the [keys]
+// section. Actions are checked against the key map when the UI loads it.
+func applyKeyBinding(cfg *Config, e fileEntry) error {
+	var ks []string
+	switch val := e.value.(type) {
+	case string:
+		ks = []string{val}
+	case []string:
+		ks = val
+	default:
+		return fmt.Errorf("[keys] %s: expected a key or a list of keys", e.key)
+	}
+	if cfg.KeyBindings == nil {
+		cfg.KeyBindings = make(map[string][]string)
+	}
+	cfg.KeyBindings[e.key] = ks
+	return nil
+}
+
+// DefaultConfigPath is where Load looks for the config file when neither
+// --config nor NITPICK_CONFIG is given.
+func DefaultConfigPath() string {
+	return filepath.Join(userConfigDir(), "nitpick", "config.toml")
+}
+
+// ThemeDir is where custom themes named by the theme setting live, as
+// NAME.toml.
+func ThemeDir() string {
+	return filepath.Join(userConfigDir(), "nitpick", "themes")
+}
+
+// PrintUsage writes the flag and environment variable reference to w.
+func PrintUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: nitpick [flags]\n\nFlags (also settable in %s or as NITPICK_* variables):\n", DefaultConfigPath())
+	fmt.Fprintf(w, "  --config PATH\n\tconfig file (env NITPICK_CONFIG)\n")
+	for _, s := range settings {
+		fmt.Fprintf(w, "  --%s\n\t%s (env %s, file key %s)\n", s.flagName(), s.usage, s.envVar(), s.name)
+	}
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
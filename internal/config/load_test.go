@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate points the default config and cache locations at a temporary
+// directory and clears NITPICK_* variables, so the tests see only what
+// they set.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "NITPICK_") {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+	return dir
+}
+
+func writeConfig(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := isolate(t)
+	path := writeConfig(t, dir, `
+item_ttl = "90s"
+fetch_page_size = 50
+sync_favorites = true
+sync_max_bytes = "2MB"
+sync_interval = "off"
+sync_tabs = ["top", "ask"]
+export_format = "md"
+
+[keys]
+preset = "emacs"
+quit = "x"
+"story.reply" = ["r", "R"]
+`)
+	cfg, args, err := Load([]string{"--config", path, "top", "--limit", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"top", "--limit", "3"}) {
+		t.Errorf("args = %q", args)
+	}
+	if cfg.ItemTTL != 90*time.Second || cfg.FetchPageSize != 50 || !cfg.SyncFavorites ||
+		cfg.SyncMaxBytes != 2<<20 || cfg.SyncInterval != 0 || cfg.ExportFormat != "md" {
+		t.Errorf("settings not applied: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.SyncTabs, []string{"top", "ask"}) {
+		t.Errorf("sync_tabs = %q", cfg.SyncTabs)
+	}
+	if cfg.KeyPreset != "emacs" {
+		t.Errorf("key preset = %q, want emacs", cfg.KeyPreset)
+	}
+	want := map[string][]string{"quit": {"x"}, "story.reply": {"r", "R"}}
+	if !reflect.DeepEqual(cfg.KeyBindings, want) {
+		t.Errorf("key bindings = %v, want %v", cfg.KeyBindings, want)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"fetch_page_size = 50\n\nnope = 1", `config.toml:3: unknown setting "nope"`},
+		{"item_ttl = \"soon\"", `config.toml:1: item_ttl: invalid duration "soon"`},
+		{"item_ttl = 90", `config.toml:1: item_ttl: invalid duration "90"`},
+		{"fetch_page_size = 0", "config.toml:1: fetch_page_size: must be between 1 and 500, got 0"},
+		{"sync_favorites = \"maybe\"", `config.toml:1: sync_favorites: invalid boolean "maybe"`},
+		{"sync_tabs = [\"top\", \"later\"]", `config.toml:1: sync_tabs: unknown value "later"`},
+		{"[colors]\nfg = \"red\"", "config.toml:2: unknown section [colors]"},
+		{"[keys]\nquit = 1", "config.toml:2: [keys] quit: expected a key or a list of keys"},
+		{"theme = dark", "config.toml: line 1: theme: cannot parse value dark"},
+	}
+	for _, tt := range tests {
+		dir := isolate(t)
+		path := writeConfig(t, dir, tt.body)
+		_, _, err := Load([]string{"--config", path})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.body, err, tt.want)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := isolate(t)
+	// The default path is only read if it exists.
+	cfg, _, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FetchPageSize != Default().FetchPageSize {
+		t.Errorf("default fetch_page_size = %d", cfg.FetchPageSize)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "nitpick"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(DefaultConfigPath(), []byte("fetch_page_size = 50\nitem_ttl = \"1m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	load := func(args ...string) Config {
+		t.Helper()
+		cfg, _, err := Load(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	if cfg := load(); cfg.FetchPageSize != 50 || cfg.ItemTTL != time.Minute {
+		t.Errorf("file: fetch_page_size = %d, item_ttl = %s", cfg.FetchPageSize, cfg.ItemTTL)
+	}
+	t.Setenv("NITPICK_FETCH_PAGE_SIZE", "60")
+	if cfg := load(); cfg.FetchPageSize != 60 || cfg.ItemTTL != time.Minute {
+		t.Errorf("env over file: fetch_page_size = %d, item_ttl = %s", cfg.FetchPageSize, cfg.ItemTTL)
+	}
+	if cfg := load("--fetch-page-size", "70"); cfg.FetchPageSize != 70 {
+		t.Errorf("flag over env: fetch_page_size = %d", cfg.FetchPageSize)
+	}
+
+	t.Setenv("NITPICK_ITEM_TTL", "-1s")
+	if _, _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "NITPICK_ITEM_TTL") {
+		t.Errorf("bad env var: err = %v, want it named", err)
+	}
+	os.Unsetenv("NITPICK_ITEM_TTL")
+	if _, _, err := Load([]string{"--item-ttl", "soon"}); err == nil || !strings.Contains(err.Error(), "--item-ttl") {
+		t.Errorf("bad flag: err = %v, want it named", err)
+	}
+
+	// An explicit config file must exist.
+	t.Setenv("NITPICK_CONFIG", filepath.Join(dir, "missing.toml"))
+	if _, _, err := Load(nil); err == nil {
+		t.Error("missing NITPICK_CONFIG file wasn't reported")
+	}
+}
+
+func TestLoadDerivedPaths(t *testing.T) {
+	dir := isolate(t)
+	cache := filepath.Join(dir, "elsewhere")
+	cfg, _, err := Load([]string{"--cache-dir", cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]string{
+		"cache.db":     cfg.DBPath,
+		"session.json": cfg.SessionPath,
+		"debug.log":    cfg.LogPath,
+		"exports":      cfg.ExportDir,
+	} {
+		if want := filepath.Join(cache, name); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+
+	// Set explicitly, in any layer, a path stays put.
+	path := writeConfig(t, dir, `db_path = "/var/tmp/nitpick.db"`)
+	t.Setenv("NITPICK_EXPORT_DIR", "~/exports")
+	cfg, _, err = Load([]string{"--config", path, "--cache-dir", cache, "--log-path", "/tmp/n.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBPath != "/var/tmp/nitpick.db" || cfg.LogPath != "/tmp/n.log" || cfg.ExportDir != filepath.Join(dir, "exports") {
+		t.Errorf("explicit paths moved: db %s, log %s, exports %s", cfg.DBPath, cfg.LogPath, cfg.ExportDir)
+	}
+	if cfg.SessionPath != filepath.Join(cache, "session.json") {
+		t.Errorf("session_path = %s, want it under cache_dir", cfg.SessionPath)
+	}
+}
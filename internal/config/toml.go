@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fileEntry is one key = value assignment from the config file.
+type fileEntry struct {
+	section string // "" for top-level keys
+	key     string
+	value   interface{} // string, int64, bool or []string
+	line    int
+}
+
+// parseTOML reads the small TOML subset nitpick's config file uses:
+// [sections], key = value pairs, # comments, and values that are quoted
+// strings, integers, booleans or single-line arrays of strings.
+func parseTOML(r io.Reader) ([]fileEntry, error) {
+	var entries []fileEntry
+	var section string
+
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(sc.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty section name", lineNo)
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if unq, err := strconv.Unquote(key); err == nil {
+			key = unq
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNo)
+		}
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+		entries = append(entries, fileEntry{section: section, key: key, value: value, line: lineNo})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ReadFile reads a config-style file with no sections, such as a theme,
+// returning its top-level keys. Values are as in parseTOML.
+func ReadFile(path string) (map[string]interface{}, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseTOML(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string]interface{}, len(entries))
+	for _, e := range entries {
+		if e.section != "" {
+			return nil, fmt.Errorf("%s:%d: unexpected section [%s]", path, e.line, e.section)
+		}
+		values[e.key] = e.value
+	}
+	return values, nil
+}
+
+func parseValue(raw string) (interface{}, error) {
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("missing value")
+	case raw == "true":
+		return true, nil
+	case raw == "false":
+		return false, nil
+	case raw[0] == '"' || raw[0] == '\'':
+		return parseString(raw)
+	case raw[0] == '[':
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		var list []string
+		for _, part := range splitArray(raw[1 : len(raw)-1]) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			s, err := parseString(part)
+			if err != nil {
+				return nil, fmt.Errorf("array elements must be strings: %w", err)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse value %s (strings must be quoted)", raw)
+	}
+	return n, nil
+}
+
+func parseString(raw string) (string, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		// Literal string: no escapes.
+		return raw[1 : len(raw)-1], nil
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", fmt.Errorf("bad string %s", raw)
+	}
+	return s, nil
+}
+
+// splitArray splits array contents on commas outside quotes.
+func splitArray(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// stripComment removes a trailing # comment that isn't inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
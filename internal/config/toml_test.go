@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	in := `# nitpick config
+cache_dir = "~/nitpick"   # trailing comment
+theme = 'C:\themes\dark'
+fetch_page_size = 1_000
+sync_favorites = true
+sync_hidden = false
+item_ttl = "90s"
+note = "a # inside a string"
+sync_tabs = ["top", 'ask', "a,b"]
+"quoted key" = 1
+
+[keys]
+preset = "emacs"
+quit = ["q", "ctrl+c"]
+`
+	entries, err := parseTOML(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fileEntry{
+		{"", "cache_dir", "~/nitpick", 2},
+		{"", "theme", `C:\themes\dark`, 3},
+		{"", "fetch_page_size", int64(1000), 4},
+		{"", "sync_favorites", true, 5},
+		{"", "sync_hidden", false, 6},
+		{"", "item_ttl", "90s", 7},
+		{"", "note", "a # inside a string", 8},
+		{"", "sync_tabs", []string{"top", "ask", "a,b"}, 9},
+		{"", "quoted key", int64(1), 10},
+		{"keys", "preset", "emacs", 13},
+		{"keys", "quit", []string{"q", "ctrl+c"}, 14},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseTOML =\n%v\nwant\n%v", entries, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a = 1\nname \"x\"", "line 2: expected key = value"},
+		{"[keys", "line 1: unterminated section header"},
+		{"a = 1\n\n[ ]", "line 3: empty section name"},
+		{"= 1", "line 1: missing key"},
+		{"a =", "line 1: a: missing value"},
+		{"theme = dark", "line 1: theme: cannot parse value dark (strings must be quoted)"},
+		{"a = \"open", "line 1: a: bad string"},
+		{"a = [1, 2]", "line 1: a: array elements must be strings"},
+		{"a = [\"x\"", "line 1: a: unterminated array"},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML(strings.NewReader(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseTOML(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
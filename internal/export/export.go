@@ -0,0 +1,169 @@
+// Package export writes a story or comment and its full reply tree as nested
+// JSON, newline-delimited JSON (one object per item), Markdown or a
+// standalone HTML page, for archiving and offline analysis.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/tree"
+)
+
+// Format selects the output encoding.
+type Format string
+
+const (
+	FormatJSON     Format = "json"   // one nested document
+	FormatNDJSON   Format = "ndjson" // one object per line, depth-first
+	FormatMarkdown Format = "md"     // replies as nested blockquotes
+	FormatHTML     Format = "html"   // standalone page, replies indented
+)
+
+// Formats lists the supported formats. Each name doubles as the file
+// extension.
+var Formats = []Format{FormatJSON, FormatNDJSON, FormatMarkdown, FormatHTML}
+
+// ParseFormat validates a format name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q (want json, ndjson, md or html)", s)
+}
+
+// Node is one exported item. Children is only populated in nested JSON.
+type Node struct {
+	ID          int     `json:"id"`
+	Type        string  `json:"type"`
+	Parent      int     `json:"parent,omitempty"`
+	Depth       int     `json:"depth"`
+	Author      string  `json:"author"`
+	Time        int64   `json:"time"`
+	Title       string  `json:"title,omitempty"`
+	URL         string  `json:"url,omitempty"`
+	Score       int     `json:"score,omitempty"`
+	Descendants int     `json:"descendants,omitempty"`
+	Dead        bool    `json:"dead,omitempty"`
+	Deleted     bool    `json:"deleted,omitempty"`
+	HTML        string  `json:"html"`
+	Text        string  `json:"text"`
+	Children    []*Node `json:"children,omitempty"`
+
+	link string // the item's page on the HN website
+}
+
+// Fetch makes sure rootID and every item below it are in the cache,
+// fetching missing or stale items one tree level at a time. Items that
+// fail to load are left out of the export rather than failing it.
+func Fetch(ctx context.Context, client *api.Client, db *cache.DB, cfg config.Config, rootID int) (*api.Item, error) {
+	root, fresh, _ := db.GetItem(rootID, cfg.ItemTTL)
+	if root == nil || !fresh {
+		item, err := client.GetItem(ctx, rootID)
+		switch {
+		case err == nil && item.ID != 0:
+			db.PutItem(item)
+			root = item
+		case root == nil:
+			if err == nil || errors.Is(err, api.ErrNotFound) {
+				err = fmt.Errorf("item %d not found", rootID)
+			}
+			return nil, err
+		}
+	}
+
+	l := tree.Loader{Client: client, DB: db, TTL: cfg.CommentTTL}
+	if _, err := l.Walk(ctx, root.Kids()); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Build converts root and its cached descendants into a Node tree, in the
+// same order storyview.FlattenTree displays them. Markdown and HTML link
+// each item to its page on ep.HN.
+func Build(root *api.Item, db *cache.DB, cfg config.Config, ep api.Endpoints) *Node {
+	var walk func(item *api.Item, depth int) *Node
+	walk = func(item *api.Item, depth int) *Node {
+		n := newNode(item, depth)
+		n.link = ep.ItemURL(item.ID)
+		for _, kidID := range item.Kids() {
+			kid, _, _ := db.GetItem(kidID, cfg.CommentTTL)
+			if kid == nil || kid.ID == 0 {
+				continue
+			}
+			n.Children = append(n.Children, walk(kid, depth+1))
+		}
+		return n
+	}
+	return walk(root, 0)
+}
+
+func newNode(item *api.Item, depth int) *Node {
+	return &Node{
+		ID:          item.ID,
+		Type:        item.Type,
+		Parent:      item.Parent,
+		Depth:       depth,
+		Author:      item.By,
+		Time:        item.Time,
+		Title:       item.Title,
+		URL:         item.URL,
+		Score:       item.Score,
+		Descendants: item.Descendants,
+		Dead:        item.Dead,
+		Deleted:     item.Deleted,
+		HTML:        item.Text,
+		Text:        render.HNToPlainText(item.Text),
+	}
+}
+
+// Write encodes the tree rooted at root to w.
+func Write(w io.Writer, root *Node, f Format) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(root)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		var walk func(n *Node) error
+		walk = func(n *Node) error {
+			flat := *n
+			flat.Children = nil
+			if err := enc.Encode(&flat); err != nil {
+				return err
+			}
+			for _, kid := range n.Children {
+				if err := walk(kid); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return walk(root)
+	case FormatMarkdown:
+		return writeMarkdown(w, root)
+	case FormatHTML:
+		return writeHTML(w, root)
+	}
+	return fmt.Errorf("unknown export format %q", f)
+}
+
+// Export fetches the tree under rootID and writes it to w.
+func Export(ctx context.Context, client *api.Client, db *cache.DB, cfg config.Config, rootID int, w io.Writer, f Format) error {
+	root, err := Fetch(ctx, client, db, cfg, rootID)
+	if err != nil {
+		return err
+	}
+	return Write(w, Build(root, db, cfg, client.Endpoints()), f)
+}
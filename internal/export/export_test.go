@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+)
+
+// items is a story whose second reply no longer exists: Firebase lists
+// it in kids but answers null for it.
+var items = map[string]string{
+	"/item/1.json": `{"id":1,"type":"story","by":"pg","time":100,"title":"A story","kids":[2,3],"descendants":2}`,
+	"/item/2.json": `{"id":2,"type":"comment","by":"dang","time":101,"parent":1,"text":"A reply","kids":[4]}`,
+	"/item/4.json": `{"id":4,"type":"comment","by":"pg","time":102,"parent":2,"text":"Nested"}`,
+}
+
+func testExport(t *testing.T, id int, f Format) (string, error) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := items[r.URL.Path]
+		if !ok {
+			body = "null"
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	db, err := cache.Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	client := api.NewClient(api.Options{Endpoints: api.Endpoints{Firebase: srv.URL, HN: "https://hn.example"}})
+
+	var out bytes.Buffer
+	err = Export(context.Background(), client, db, config.Default(), id, &out, f)
+	if item, _, _ := db.GetItem(0, 0); item != nil {
+		t.Errorf("cache has an empty item: %+v", item)
+	}
+	return out.String(), err
+}
+
+func TestExportMissingRoot(t *testing.T) {
+	for _, f := range Formats {
+		out, err := testExport(t, 99999, f)
+		if err == nil || !strings.Contains(err.Error(), "item 99999 not found") {
+			t.Errorf("%s: err = %v, want item 99999 not found", f, err)
+		}
+		if out != "" {
+			t.Errorf("%s: wrote %q", f, out)
+		}
+	}
+}
+
+func TestExportSkipsMissingKids(t *testing.T) {
+	out, err := testExport(t, 1, FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var n Node
+		if err := json.Unmarshal([]byte(line), &n); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, n.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 {
+		t.Errorf("ndjson has items %v, want [1 2 4]", ids)
+	}
+
+	out, err = testExport(t, 1, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root Node
+	if err := json.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 1 || root.Children[0].ID != 2 || len(root.Children[0].Children) != 1 {
+		t.Errorf("json tree = %s", out)
+	}
+
+	for _, f := range []Format{FormatMarkdown, FormatHTML} {
+		out, err := testExport(t, 1, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "item?id=0") || strings.Contains(out, "item?id=3") {
+			t.Errorf("%s export has a node for the missing reply:\n%s", f, out)
+		}
+		if !strings.Contains(out, "https://hn.example/item?id=4") {
+			t.Errorf("%s export lacks the nested reply:\n%s", f, out)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// thread is a story with a reply two levels deep and a deleted sibling.
+func thread() *Node {
+	return &Node{
+		ID: 1, Type: "story", Author: "pg", Time: 0, Title: "Tom &amp; Jerry", URL: "https://e.com/",
+		Score: 10, Descendants: 3, link: "https://hn.example/item?id=1",
+		Children: []*Node{
+			{ID: 2, Type: "comment", Depth: 1, Author: "dang", Time: 60, HTML: "First<p>Second <i>para</i>", link: "https://hn.example/item?id=2",
+				Children: []*Node{
+					{ID: 3, Type: "comment", Depth: 2, Time: 120, HTML: `<script>alert(1)</script><a href="javascript:x()" onclick="y()">hi</a> &lt;b&gt;`, Author: "tptacek", link: "https://hn.example/item?id=3"},
+				}},
+			{ID: 4, Type: "comment", Depth: 1, Time: 180, Deleted: true, link: "https://hn.example/item?id=4"},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	if err := Write(&out, thread(), FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Tom & Jerry
+
+<https://e.com/>
+
+**pg** · 10 points · 1970-01-01 00:00 UTC · 3 comments · [link](https://hn.example/item?id=1)
+
+---
+
+**dang** · 1970-01-01 00:01 UTC · [link](https://hn.example/item?id=2)
+
+First
+
+Second *para*
+
+> **tptacek** · 1970-01-01 00:02 UTC · [link](https://hn.example/item?id=3)
+>
+> hi \<b>
+
+**[deleted]** · 1970-01-01 00:03 UTC · [link](https://hn.example/item?id=4)
+
+*[deleted]*
+
+`
+	if out.String() != want {
+		t.Errorf("markdown:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestMarkdownQuoteDepth(t *testing.T) {
+	root := &Node{ID: 1, Type: "story", link: "l"}
+	n := root
+	for depth := 1; depth <= 4; depth++ {
+		kid := &Node{ID: depth + 1, Type: "comment", Depth: depth, HTML: "a<p>b", link: "l"}
+		n.Children = []*Node{kid}
+		n = kid
+	}
+	var out bytes.Buffer
+	if err := writeMarkdown(&out, root); err != nil {
+		t.Fatal(err)
+	}
+	// Each paragraph of a reply at depth d sits in d-1 levels of quote,
+	// with the blank line between them quoted too.
+	for d := 1; d <= 4; d++ {
+		prefix := strings.Repeat("> ", d-1)
+		para := prefix + "a\n" + strings.TrimRight(prefix, " ") + "\n" + prefix + "b\n"
+		if !strings.Contains(out.String(), "\n"+para) {
+			t.Errorf("depth %d: no %q in\n%s", d, para, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "> > > > ") {
+		t.Errorf("quoted deeper than the tree:\n%s", out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := Write(&out, thread(), FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	for _, want := range []string{
+		"<title>Tom &amp; Jerry</title>",
+		`<h1><a href="https://e.com/">Tom &amp; Jerry</a></h1>`,
+		"· 10 points · 1970-01-01 00:00 UTC · 3 comments</p>",
+		`<div class="text">First<p>Second <i>para</i></div>`,
+		`<div class="text"><a>hi</a> &lt;b&gt;</div>`,
+		`<div class="text"><i>[deleted]</i></div>`,
+		`<footer>Exported from <a href="https://hn.example/item?id=1">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %s:\n%s", want, page)
+		}
+	}
+	for _, bad := range []string{"<script", "alert(1)", "javascript:", "onclick"} {
+		if strings.Contains(page, bad) {
+			t.Errorf("page contains %s:\n%s", bad, page)
+		}
+	}
+
+	// Replies nest inside their parent's element.
+	c2 := strings.Index(page, `id="c2"`)
+	c3 := strings.Index(page, `id="c3"`)
+	c4 := strings.Index(page, `id="c4"`)
+	if c2 < 0 || c3 < c2 || c4 < c3 {
+		t.Fatalf("comments out of order:\n%s", page)
+	}
+	if between := page[c3:c4]; strings.Count(between, "</div>") != 3 {
+		t.Errorf("reply 3 isn't nested under 2: %q", between)
+	}
+}
+
+func TestWriteHTMLComment(t *testing.T) {
+	root := &Node{ID: 5, Type: "comment", Author: "<b>x</b>", Time: 0, HTML: "text", link: "https://hn.example/item?id=5"}
+	var out bytes.Buffer
+	if err := Write(&out, root, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "<title>Comment by &lt;b&gt;x&lt;/b&gt;</title>") {
+		t.Errorf("a comment's page isn't titled after its escaped author:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "<h1>") || strings.Contains(out.String(), "comments</p>") {
+		t.Errorf("a comment's page has story parts:\n%s", out.String())
+	}
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"html"
+	"html/template"
+	"io"
+
+	"github.com/fragmede/nitpick/internal/render"
+)
+
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"title":     html.UnescapeString,
+	"author":    authorName,
+	"timestamp": timestamp,
+	"link":      func(n *Node) string { return n.link },
+	"body": func(n *Node) template.HTML {
+		switch {
+		case n.Deleted:
+			return "<i>[deleted]</i>"
+		case n.Dead:
+			return "<i>[flagged]</i>"
+		}
+		return template.HTML(render.SanitizeHN(n.HTML))
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Title}}{{title .Title}}{{else}}Comment by {{author .}}{{end}}</title>
+<style>
+body { font: 15px/1.5 Verdana, Geneva, sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+a { color: #000; }
+h1 { font-size: 1.4em; margin-bottom: 0.2em; }
+.meta { color: #828282; font-size: 0.85em; margin: 0 0 0.3em; }
+.meta b { color: #ff6600; }
+.text p { margin: 0.5em 0; }
+pre { background: #f6f6ef; padding: 0.5em; overflow-x: auto; }
+.comment { margin: 1em 0 0 1.2em; padding-left: 0.8em; border-left: 2px solid #e0e0e0; }
+.comments > .comment { margin-left: 0; }
+footer { margin-top: 3em; color: #828282; font-size: 0.8em; }
+</style>
+</head>
+<body>
+<header>
+{{if .Title}}<h1>{{if .URL}}<a href="{{.URL}}">{{title .Title}}</a>{{else}}{{title .Title}}{{end}}</h1>{{end}}
+<p class="meta"><b>{{author .}}</b>{{if .Score}} · {{.Score}} points{{end}} · {{timestamp .Time}}{{if ne .Type "comment"}} · {{.Descendants}} comments{{end}}</p>
+{{if or .HTML .Deleted .Dead}}<div class="text">{{body .}}</div>{{end}}
+</header>
+<div class="comments">
+{{template "comments" .Children}}
+</div>
+<footer>Exported from <a href="{{link .}}">{{link .}}</a></footer>
+</body>
+</html>
+{{define "comments"}}{{range .}}<div class="comment" id="c{{.ID}}">
+<p class="meta"><b>{{author .}}</b> · <a href="{{link .}}">{{timestamp .Time}}</a></p>
+<div class="text">{{body .}}</div>
+{{template "comments" .Children}}</div>
+{{end}}{{end}}
+`))
+
+// writeHTML renders a standalone page with replies nested and indented
+// under their parents.
+func writeHTML(w io.Writer, root *Node) error {
+	return pageTemplate.Execute(w, root)
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/render"
+)
+
+// writeMarkdown renders the root as a heading and each comment as a block,
+// nesting replies in one more level of blockquote per depth.
+func writeMarkdown(w io.Writer, root *Node) error {
+	bw := bufio.NewWriter(w)
+
+	if root.Title != "" {
+		fmt.Fprintf(bw, "# %s\n\n", html.UnescapeString(root.Title))
+	}
+	if root.URL != "" {
+		fmt.Fprintf(bw, "<%s>\n\n", root.URL)
+	}
+	fmt.Fprintf(bw, "%s\n\n", markdownMeta(root))
+	if body := markdownBody(root); body != "" {
+		fmt.Fprintf(bw, "%s\n\n", body)
+	}
+	if len(root.Children) > 0 {
+		fmt.Fprint(bw, "---\n\n")
+	}
+
+	var walk func(n *Node, level int)
+	walk = func(n *Node, level int) {
+		prefix := strings.Repeat("> ", level)
+		fmt.Fprintf(bw, "%s\n", quote(prefix, markdownMeta(n)))
+		fmt.Fprintf(bw, "%s\n", strings.TrimRight(prefix, " "))
+		fmt.Fprintf(bw, "%s\n\n", quote(prefix, markdownBody(n)))
+		for _, kid := range n.Children {
+			walk(kid, level+1)
+		}
+	}
+	for _, kid := range root.Children {
+		walk(kid, 0)
+	}
+	return bw.Flush()
+}
+
+func markdownMeta(n *Node) string {
+	parts := []string{"**" + authorName(n) + "**"}
+	if n.Score > 0 && n.Type != "comment" {
+		parts = append(parts, fmt.Sprintf("%d points", n.Score))
+	}
+	parts = append(parts, timestamp(n.Time))
+	if n.Type != "comment" {
+		parts = append(parts, fmt.Sprintf("%d comments", n.Descendants))
+	}
+	parts = append(parts, fmt.Sprintf("[link](%s)", n.link))
+	return strings.Join(parts, " · ")
+}
+
+func markdownBody(n *Node) string {
+	switch {
+	case n.Deleted:
+		return "*[deleted]*"
+	case n.Dead:
+		return "*[flagged]*"
+	}
+	return render.HNToMarkdown(n.HTML)
+}
+
+// quote prefixes every line of s, trimming the trailing space on blank
+// lines so the quote continues across paragraphs.
+func quote(prefix, s string) string {
+	if prefix == "" {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func authorName(n *Node) string {
+	if n.Author == "" {
+		return "[deleted]"
+	}
+	return n.Author
+}
+
+func timestamp(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04 UTC")
+}
@@ -0,0 +1,315 @@
+package fakehn
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// routeAlgolia serves the subset of HN Search under /api/v1 that nitpick
+// uses: search, search_by_date and items.
+func (s *Server) routeAlgolia() {
+	s.mux.HandleFunc("GET /api/v1/search", func(w http.ResponseWriter, r *http.Request) { s.search(w, r, false) })
+	s.mux.HandleFunc("GET /api/v1/search_by_date", func(w http.ResponseWriter, r *http.Request) { s.search(w, r, true) })
+	s.mux.HandleFunc("GET /api/v1/items/{id}", s.algoliaItem)
+}
+
+// hit is one search result.
+type hit struct {
+	ObjectID    string   `json:"objectID"`
+	Title       string   `json:"title,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Author      string   `json:"author"`
+	Points      int      `json:"points"`
+	NumComments int      `json:"num_comments"`
+	CreatedAtI  int64    `json:"created_at_i"`
+	StoryText   string   `json:"story_text,omitempty"`
+	CommentText string   `json:"comment_text,omitempty"`
+	ParentID    int      `json:"parent_id,omitempty"`
+	StoryID     int      `json:"story_id,omitempty"`
+	StoryTitle  string   `json:"story_title,omitempty"`
+	StoryURL    string   `json:"story_url,omitempty"`
+	Tags        []string `json:"_tags"`
+}
+
+func (s *Server) hit(it *item, front map[int]bool) hit {
+	h := hit{
+		ObjectID:   strconv.Itoa(it.ID),
+		Author:     it.By,
+		Points:     it.Score,
+		CreatedAtI: it.Time,
+		Tags:       []string{it.Type, "author_" + it.By},
+	}
+	switch it.Type {
+	case "comment", "pollopt":
+		story := s.story(it)
+		h.CommentText = it.Text
+		h.ParentID = it.Parent
+		h.StoryID = story.ID
+		h.StoryTitle = story.Title
+		h.StoryURL = story.URL
+		h.Tags = append(h.Tags, "story_"+strconv.Itoa(story.ID))
+	default:
+		h.Title = it.Title
+		h.URL = it.URL
+		h.StoryText = it.Text
+		h.NumComments = s.descendants(it)
+		h.StoryID = it.ID
+		h.Tags = append(h.Tags, "story_"+strconv.Itoa(it.ID))
+		if strings.HasPrefix(it.Title, "Ask HN") {
+			h.Tags = append(h.Tags, "ask_hn")
+		}
+		if strings.HasPrefix(it.Title, "Show HN") {
+			h.Tags = append(h.Tags, "show_hn")
+		}
+		if front[it.ID] {
+			h.Tags = append(h.Tags, "front_page")
+		}
+	}
+	return h
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, byDate bool) {
+	q := r.URL.Query()
+	numeric, err := parseNumericFilters(q.Get("numericFilters"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]any{"message": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	tags := parseTags(q.Get("tags"))
+	terms := parseQuery(q.Get("query"))
+	urlOnly := q.Get("restrictSearchableAttributes") == "url"
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, err := strconv.Atoi(q.Get("hitsPerPage"))
+	if err != nil || perPage <= 0 {
+		perPage = 20
+	}
+	perPage = min(perPage, 1000)
+
+	s.mu.Lock()
+	front := s.frontPage()
+	var hits []hit
+	for _, it := range s.items {
+		if it.Dead || it.Deleted {
+			continue
+		}
+		h := s.hit(it, front)
+		if matchTags(h, tags) && matchNumeric(h, numeric) && matchQuery(h, terms, urlOnly) {
+			hits = append(hits, h)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if !byDate && a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.CreatedAtI > b.CreatedAtI
+	})
+	total := len(hits)
+	start := min(page*perPage, total)
+	hits = hits[start:min(start+perPage, total)]
+	if hits == nil {
+		hits = []hit{}
+	}
+	writeJSON(w, map[string]any{
+		"hits":        hits,
+		"nbHits":      total,
+		"page":        page,
+		"nbPages":     (total + perPage - 1) / perPage,
+		"hitsPerPage": perPage,
+	})
+}
+
+// parseTags splits Algolia's tags parameter into AND'd groups of OR'd
+// tags: "story,(author_a,author_b)".
+func parseTags(v string) [][]string {
+	var groups [][]string
+	for v != "" {
+		var group string
+		if strings.HasPrefix(v, "(") {
+			end := strings.Index(v, ")")
+			if end < 0 {
+				end = len(v)
+			}
+			group, v = v[1:end], v[min(end+1, len(v)):]
+		} else if i := strings.Index(v, ","); i >= 0 {
+			group, v = v[:i], v[i:]
+		} else {
+			group, v = v, ""
+		}
+		v = strings.TrimPrefix(v, ",")
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, strings.Split(group, ","))
+		}
+	}
+	return groups
+}
+
+func matchTags(h hit, groups [][]string) bool {
+	for _, group := range groups {
+		ok := false
+		for _, tag := range group {
+			for _, t := range h.Tags {
+				if strings.TrimSpace(tag) == t {
+					ok = true
+				}
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type numericFilter struct {
+	field string
+	op    string
+	value int64
+}
+
+var numericRe = regexp.MustCompile(`^(\w+)\s*(<=|>=|!=|<|>|=)\s*(-?\d+)$`)
+
+func parseNumericFilters(v string) ([]numericFilter, error) {
+	var filters []numericFilter
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		m := numericRe.FindStringSubmatch(f)
+		if m == nil {
+			return nil, fmt.Errorf("invalid numeric filter %q", f)
+		}
+		switch m[1] {
+		case "created_at_i", "points", "num_comments":
+		default:
+			return nil, fmt.Errorf("unknown numeric attribute %q", m[1])
+		}
+		n, _ := strconv.ParseInt(m[3], 10, 64)
+		filters = append(filters, numericFilter{m[1], m[2], n})
+	}
+	return filters, nil
+}
+
+func matchNumeric(h hit, filters []numericFilter) bool {
+	for _, f := range filters {
+		var v int64
+		switch f.field {
+		case "created_at_i":
+			v = h.CreatedAtI
+		case "points":
+			v = int64(h.Points)
+		case "num_comments":
+			v = int64(h.NumComments)
+		}
+		var ok bool
+		switch f.op {
+		case "<":
+			ok = v < f.value
+		case "<=":
+			ok = v <= f.value
+		case ">":
+			ok = v > f.value
+		case ">=":
+			ok = v >= f.value
+		case "=":
+			ok = v == f.value
+		case "!=":
+			ok = v != f.value
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// term is one word or "quoted phrase" of a query; exclude is set for
+// -word.
+type term struct {
+	text    string
+	exclude bool
+}
+
+var termRe = regexp.MustCompile(`-?"[^"]*"|\S+`)
+
+func parseQuery(q string) []term {
+	var terms []term
+	for _, t := range termRe.FindAllString(strings.ToLower(q), -1) {
+		exclude := len(t) > 1 && strings.HasPrefix(t, "-")
+		if exclude {
+			t = t[1:]
+		}
+		if t = strings.Trim(t, `"`); t != "" {
+			terms = append(terms, term{t, exclude})
+		}
+	}
+	return terms
+}
+
+func matchQuery(h hit, terms []term, urlOnly bool) bool {
+	text := h.URL + " " + h.StoryURL
+	if !urlOnly {
+		text += " " + h.Title + " " + h.Author + " " + h.StoryText + " " + h.CommentText
+	}
+	text = strings.ToLower(text)
+	for _, t := range terms {
+		if strings.Contains(text, t.text) == t.exclude {
+			return false
+		}
+	}
+	return true
+}
+
+// treeItem is an item with its replies, as /items/{id} returns it.
+// Deleted comments keep their place with no author or text.
+type treeItem struct {
+	ID         int        `json:"id"`
+	Type       string     `json:"type"`
+	Author     *string    `json:"author"`
+	CreatedAtI int64      `json:"created_at_i"`
+	Title      *string    `json:"title"`
+	URL        *string    `json:"url"`
+	Text       *string    `json:"text"`
+	Points     *int       `json:"points"`
+	ParentID   *int       `json:"parent_id"`
+	StoryID    int        `json:"story_id"`
+	Children   []treeItem `json:"children"`
+}
+
+func (s *Server) algoliaItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[id]
+	if err != nil || !ok || it.Dead {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]any{"error": "Not Found", "status": http.StatusNotFound})
+		return
+	}
+	writeJSON(w, s.tree(it, s.story(it).ID))
+}
+
+func (s *Server) tree(it *item, storyID int) treeItem {
+	t := treeItem{ID: it.ID, Type: it.Type, CreatedAtI: it.Time, StoryID: storyID, Children: []treeItem{}}
+	if !it.Deleted {
+		t.Author, t.Text = &it.By, &it.Text
+	}
+	if it.Type == "comment" {
+		t.ParentID = &it.Parent
+	} else {
+		t.Title, t.URL, t.Points = &it.Title, &it.URL, &it.Score
+	}
+	for _, id := range it.Kids {
+		if kid := s.items[id]; kid != nil && !kid.Dead {
+			t.Children = append(t.Children, s.tree(kid, storyID))
+		}
+	}
+	return t
+}
@@ -0,0 +1,310 @@
+// Package fakehn is an in-process stand-in for Hacker News, for
+// developing and demoing nitpick without touching production. It serves
+// the Firebase API, Algolia search and the website pages nitpick scrapes
+// or posts to, from fixtures embedded in the binary. Logins, comments,
+// votes and submissions work, and live in memory until the server stops.
+package fakehn
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// item is an HN item as the Firebase API serves it.
+type item struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by,omitempty"`
+	Time        int64  `json:"time"`
+	Text        string `json:"text,omitempty"`
+	Parent      int    `json:"parent,omitempty"`
+	Poll        int    `json:"poll,omitempty"`
+	Kids        []int  `json:"kids,omitempty"`
+	Parts       []int  `json:"parts,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Score       int    `json:"score,omitempty"`
+	Descendants *int   `json:"descendants,omitempty"` // set when served, for stories and polls
+	Dead        bool   `json:"dead,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+}
+
+// user is an HN user as the Firebase API serves it.
+type user struct {
+	ID        string `json:"id"`
+	Created   int64  `json:"created"`
+	Karma     int    `json:"karma"`
+	About     string `json:"about,omitempty"`
+	Submitted []int  `json:"submitted,omitempty"` // set when served
+}
+
+// Fixture times are ages in seconds before the server started, so the
+// data always looks recent.
+type fixtureItem struct {
+	item
+	Age int64 `json:"age"`
+}
+
+type fixtureUser struct {
+	user
+	Age      int64  `json:"age"`
+	Password string `json:"password"` // empty means the account can't log in
+}
+
+// Server is a fake HN. It implements http.Handler.
+type Server struct {
+	// Log, if set, gets one line per request.
+	Log *log.Logger
+
+	mux *http.ServeMux
+
+	mu        sync.Mutex
+	items     map[int]*item
+	users     map[string]*user
+	passwords map[string]string
+	maxID     int
+	sessions  map[string]string // session token -> username
+	votes     marks
+	faves     marks
+	hidden    marks
+}
+
+// marks records per-user flags on items: votes, favorites, hides.
+type marks map[string]map[int]bool
+
+func (m marks) set(username string, id int, on bool) {
+	if m[username] == nil {
+		m[username] = make(map[int]bool)
+	}
+	if on {
+		m[username][id] = true
+	} else {
+		delete(m[username], id)
+	}
+}
+
+// New returns a Server loaded with the embedded fixtures.
+func New() (*Server, error) {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		items:     make(map[int]*item),
+		users:     make(map[string]*user),
+		passwords: make(map[string]string),
+		sessions:  make(map[string]string),
+		votes:     make(marks),
+		faves:     make(marks),
+		hidden:    make(marks),
+	}
+	now := time.Now().Unix()
+
+	var items []fixtureItem
+	if err := readFixture("items.json", &items); err != nil {
+		return nil, err
+	}
+	for _, fi := range items {
+		it := fi.item
+		it.Time = now - fi.Age
+		s.items[it.ID] = &it
+		s.maxID = max(s.maxID, it.ID)
+	}
+	var users []fixtureUser
+	if err := readFixture("users.json", &users); err != nil {
+		return nil, err
+	}
+	for _, fu := range users {
+		u := fu.user
+		u.Created = now - fu.Age
+		s.users[u.ID] = &u
+		if fu.Password != "" {
+			s.passwords[u.ID] = fu.Password
+		}
+	}
+
+	s.routeFirebase()
+	s.routeAlgolia()
+	s.routeSite()
+	return s, nil
+}
+
+func readFixture(name string, v any) error {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("fixture %s: %w", name, err)
+	}
+	return nil
+}
+
+// Endpoints returns the api.Endpoints for a Server listening at base,
+// e.g. http://127.0.0.1:8080.
+func Endpoints(base string) api.Endpoints {
+	base = strings.TrimRight(base, "/")
+	return api.Endpoints{
+		Firebase: base + "/v0",
+		Algolia:  base + "/api/v1",
+		HN:       base,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Log == nil {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+	s.mux.ServeHTTP(sw, r)
+	s.Log.Printf("%s %s %d %s", r.Method, r.URL.RequestURI(), sw.status, time.Since(start).Round(time.Microsecond))
+}
+
+// statusWriter remembers the status code for the request log.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// add stores a new item under the next ID and links it to its parent.
+// The caller holds s.mu.
+func (s *Server) add(it *item) {
+	s.maxID++
+	it.ID = s.maxID
+	it.Time = time.Now().Unix()
+	s.items[it.ID] = it
+	if parent := s.items[it.Parent]; parent != nil {
+		// New replies go to the top, as they tend to on HN.
+		parent.Kids = append([]int{it.ID}, parent.Kids...)
+	}
+}
+
+// descendants counts the visible comments under it.
+func (s *Server) descendants(it *item) int {
+	n := 0
+	for _, id := range it.Kids {
+		if kid := s.items[id]; kid != nil && !kid.Dead {
+			if !kid.Deleted {
+				n++
+			}
+			n += s.descendants(kid)
+		}
+	}
+	return n
+}
+
+// story returns the story (or poll) a comment belongs to.
+func (s *Server) story(it *item) *item {
+	for it.Type == "comment" {
+		parent := s.items[it.Parent]
+		if parent == nil {
+			break
+		}
+		it = parent
+	}
+	return it
+}
+
+// submitted returns the IDs of username's items, newest first.
+func (s *Server) submitted(username string) []int {
+	var ids []int
+	for id, it := range s.items {
+		if it.By == username {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	return ids
+}
+
+// list returns a story list by its Firebase name (top, new, best, ask,
+// show, job).
+func (s *Server) list(name string) ([]int, bool) {
+	var match func(it *item) bool
+	rank := s.rank
+	switch name {
+	case "top":
+		match = func(it *item) bool { return it.Type != "comment" && it.Type != "pollopt" }
+	case "new":
+		match = func(it *item) bool { return it.Type == "story" || it.Type == "poll" }
+		rank = func(it *item) float64 { return float64(it.Time) }
+	case "best":
+		match = func(it *item) bool { return it.Type == "story" || it.Type == "poll" }
+		rank = func(it *item) float64 { return float64(it.Score) }
+	case "ask":
+		match = func(it *item) bool { return it.Type == "story" && strings.HasPrefix(it.Title, "Ask HN") }
+	case "show":
+		match = func(it *item) bool { return it.Type == "story" && strings.HasPrefix(it.Title, "Show HN") }
+	case "job":
+		match = func(it *item) bool { return it.Type == "job" }
+		rank = func(it *item) float64 { return float64(it.Time) }
+	default:
+		return nil, false
+	}
+
+	var list []*item
+	for _, it := range s.items {
+		if !it.Dead && !it.Deleted && match(it) {
+			list = append(list, it)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ri, rj := rank(list[i]), rank(list[j]); ri != rj {
+			return ri > rj
+		}
+		return list[i].ID > list[j].ID
+	})
+	ids := make([]int, len(list))
+	for i, it := range list {
+		ids[i] = it.ID
+	}
+	return ids, true
+}
+
+// rank is HN's front page formula: votes decaying with age.
+func (s *Server) rank(it *item) float64 {
+	hours := time.Since(time.Unix(it.Time, 0)).Hours()
+	return float64(it.Score-1) / math.Pow(hours+2, 1.8)
+}
+
+// frontPage returns the first page of top stories, for Algolia's
+// front_page tag.
+func (s *Server) frontPage() map[int]bool {
+	top, _ := s.list("top")
+	front := make(map[int]bool)
+	for _, id := range top[:min(len(top), 30)] {
+		front[id] = true
+	}
+	return front
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
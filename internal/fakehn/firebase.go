@@ -0,0 +1,70 @@
+package fakehn
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// routeFirebase serves the official API under /v0. Like Firebase, unknown
+// items and users are a 200 with a null body.
+func (s *Server) routeFirebase() {
+	s.mux.HandleFunc("GET /v0/item/{file}", s.firebaseItem)
+	s.mux.HandleFunc("GET /v0/user/{file}", s.firebaseUser)
+	s.mux.HandleFunc("GET /v0/{file}", s.firebaseList)
+}
+
+func (s *Server) firebaseItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("file"), ".json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[id]
+	if !ok {
+		writeJSON(w, nil)
+		return
+	}
+	out := *it
+	if it.Type == "story" || it.Type == "poll" {
+		n := s.descendants(it)
+		out.Descendants = &n
+	}
+	writeJSON(w, out)
+}
+
+func (s *Server) firebaseUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[strings.TrimSuffix(r.PathValue("file"), ".json")]
+	if !ok {
+		writeJSON(w, nil)
+		return
+	}
+	out := *u
+	out.Submitted = s.submitted(u.ID)
+	writeJSON(w, out)
+}
+
+func (s *Server) firebaseList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file := r.PathValue("file")
+	if file == "maxitem.json" {
+		writeJSON(w, s.maxID)
+		return
+	}
+	name, ok := strings.CutSuffix(file, "stories.json")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	ids, ok := s.list(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, ids[:min(len(ids), 500)])
+}
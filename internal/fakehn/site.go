@@ -0,0 +1,636 @@
+package fakehn
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/render"
+)
+
+// The website's pages follow HN's markup closely enough for auth.Session
+// and api.ParseThreadsHTML, which scrape it. The session cookie is HN's
+// "user" cookie; the auth, hmac and fnid tokens are the session token.
+
+const (
+	storiesPerPage   = 30
+	threadsPerPage   = 10
+	favoritesPerPage = 30
+)
+
+func (s *Server) routeSite() {
+	s.mux.HandleFunc("GET /{$}", s.news)
+	s.mux.HandleFunc("GET /news", s.news)
+	s.mux.HandleFunc("GET /newest", s.newest)
+	s.mux.HandleFunc("GET /login", s.loginPage)
+	s.mux.HandleFunc("POST /login", s.login)
+	s.mux.HandleFunc("GET /logout", s.logout)
+	s.mux.HandleFunc("GET /item", s.itemPage)
+	s.mux.HandleFunc("GET /vote", s.vote)
+	s.mux.HandleFunc("GET /fave", func(w http.ResponseWriter, r *http.Request) { s.toggle(w, r, s.faves) })
+	s.mux.HandleFunc("GET /hide", func(w http.ResponseWriter, r *http.Request) { s.toggle(w, r, s.hidden) })
+	s.mux.HandleFunc("GET /favorites", s.favorites)
+	s.mux.HandleFunc("GET /threads", s.threads)
+	s.mux.HandleFunc("GET /reply", s.replyPage)
+	s.mux.HandleFunc("POST /comment", s.comment)
+	s.mux.HandleFunc("GET /edit", s.editPage)
+	s.mux.HandleFunc("POST /xedit", s.edit)
+	s.mux.HandleFunc("GET /submit", s.submitPage)
+	s.mux.HandleFunc("POST /r", s.submit)
+}
+
+// visitor is who is making a request: a username and session token, or
+// zero for a logged-out visitor.
+type visitor struct {
+	name  string
+	token string
+}
+
+// visitor reads the session cookie. The caller holds s.mu.
+func (s *Server) visitor(r *http.Request) visitor {
+	c, err := r.Cookie("user")
+	if err != nil {
+		return visitor{}
+	}
+	name, token, _ := strings.Cut(c.Value, "&")
+	if token == "" || s.sessions[token] != name {
+		return visitor{}
+	}
+	return visitor{name, token}
+}
+
+// authorized reports whether a request's auth, hmac or fnid token is v's.
+func (v visitor) authorized(token string) bool {
+	return v.name != "" && token == v.token
+}
+
+// requireLogin redirects logged-out visitors to the login page and
+// reports whether it did.
+func requireLogin(w http.ResponseWriter, r *http.Request, v visitor) bool {
+	if v.name != "" {
+		return false
+	}
+	http.Redirect(w, r, "/login?goto="+url.QueryEscape(strings.TrimPrefix(r.URL.RequestURI(), "/")), http.StatusFound)
+	return true
+}
+
+// redirect sends the visitor to a goto parameter, a relative HN path.
+func redirect(w http.ResponseWriter, r *http.Request, to, fallback string) {
+	if to == "" || strings.Contains(to, "//") || strings.HasPrefix(to, "/") {
+		to = fallback
+	}
+	http.Redirect(w, r, "/"+to, http.StatusFound)
+}
+
+// message writes the bare one-line pages HN uses for errors such as
+// "Unknown." and "Bad login.".
+func message(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><body>%s</body></html>\n", html.EscapeString(text))
+}
+
+// page writes a page with HN's header around body.
+func (s *Server) page(w http.ResponseWriter, v visitor, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	account := `<a href="login?goto=news">login</a>`
+	if v.name != "" {
+		account = fmt.Sprintf(`<a id="me" href="user?id=%s">%s</a> (%d) | <a id="logout" href="logout?auth=%s&amp;goto=news">logout</a>`,
+			url.QueryEscape(v.name), html.EscapeString(v.name), s.users[v.name].Karma, v.token)
+	}
+	if title == "" {
+		title = "Hacker News"
+	} else {
+		title = html.EscapeString(title) + " | Hacker News"
+	}
+	fmt.Fprintf(w, `<html lang="en"><head><title>%s</title></head><body><center><table id="hnmain">
+<tr><td><span class="pagetop"><b class="hnname"><a href="news">Hacker News</a></b> (fake) <a href="newest">new</a> | <a href="submit">submit</a></span></td>
+<td style="text-align:right"><span class="pagetop">%s</span></td></tr>
+<tr><td colspan="2">%s</td></tr>
+</table></center></body></html>
+`, title, account, body)
+}
+
+func (s *Server) news(w http.ResponseWriter, r *http.Request) {
+	s.storyList(w, r, "top", "news")
+}
+
+func (s *Server) newest(w http.ResponseWriter, r *http.Request) {
+	s.storyList(w, r, "new", "newest")
+}
+
+func (s *Server) storyList(w http.ResponseWriter, r *http.Request, list, here string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	ids, _ := s.list(list)
+	var b strings.Builder
+	b.WriteString(`<table class="itemlist">`)
+	rank := 0
+	for _, id := range ids {
+		if s.hidden[v.name][id] {
+			continue
+		}
+		rank++
+		b.WriteString(s.storyRow(s.items[id], rank, v, here, false))
+		if rank == storiesPerPage {
+			break
+		}
+	}
+	b.WriteString(`</table>`)
+	s.page(w, v, "", b.String())
+}
+
+// storyRow renders a story, poll or job with its subtext. The item page
+// (fat) adds the hide and favorite links.
+func (s *Server) storyRow(it *item, rank int, v visitor, here string, fat bool) string {
+	var b strings.Builder
+	rankText := ""
+	if rank > 0 {
+		rankText = fmt.Sprintf("%d.", rank)
+	}
+	link := it.URL
+	if link == "" {
+		link = fmt.Sprintf("item?id=%d", it.ID)
+	}
+	fmt.Fprintf(&b, `<tr class="athing submission" id="%d"><td class="title"><span class="rank">%s</span></td><td class="votelinks"><center>%s</center></td><td class="title"><span class="titleline"><a href="%s">%s</a></span></td></tr>`,
+		it.ID, rankText, s.voteArrow(it, v, here), html.EscapeString(link), html.EscapeString(it.Title))
+
+	var sub []string
+	if it.Type != "job" {
+		sub = append(sub, fmt.Sprintf(`<span class="score" id="score_%d">%d points</span> by <a href="user?id=%s" class="hnuser">%s</a> %s`,
+			it.ID, it.Score, url.QueryEscape(it.By), html.EscapeString(it.By), age(it)))
+	} else {
+		sub = append(sub, age(it))
+	}
+	if fat {
+		sub = append(sub, s.itemLinks(it, v, here)...)
+	}
+	if it.Type != "job" {
+		sub = append(sub, fmt.Sprintf(`<a href="item?id=%d">%d&nbsp;comments</a>`, it.ID, s.descendants(it)))
+	}
+	fmt.Fprintf(&b, `<tr><td colspan="2"></td><td class="subtext">%s</td></tr><tr class="spacer"></tr>`, strings.Join(sub, " | "))
+	return b.String()
+}
+
+// voteArrow is the upvote link, for logged-in visitors on other people's
+// items. Once voted it stays, hidden, as on HN.
+func (s *Server) voteArrow(it *item, v visitor, here string) string {
+	if v.name == "" || it.By == v.name || it.Deleted {
+		return ""
+	}
+	class := "clicky"
+	if s.votes[v.name][it.ID] {
+		class += " nosee"
+	}
+	return fmt.Sprintf(`<a href='vote?id=%d&amp;how=up&amp;auth=%s&amp;goto=%s' id='up_%d' class='%s'><div class='votearrow' title='upvote'></div></a>`,
+		it.ID, v.token, url.QueryEscape(here), it.ID, class)
+}
+
+// itemLinks are the unvote, edit, hide and favorite links of an item's
+// own page.
+func (s *Server) itemLinks(it *item, v visitor, here string) []string {
+	if v.name == "" {
+		return nil
+	}
+	var links []string
+	if s.votes[v.name][it.ID] {
+		links = append(links, fmt.Sprintf(`<a id='un_%d' href='vote?id=%d&amp;how=un&amp;auth=%s&amp;goto=%s'>unvote</a>`,
+			it.ID, it.ID, v.token, url.QueryEscape(here)))
+	}
+	if it.By == v.name && !it.Deleted {
+		links = append(links, fmt.Sprintf(`<a href="edit?id=%d">edit</a>`, it.ID))
+	}
+	if it.Type != "comment" {
+		un, label := "", "hide"
+		if s.hidden[v.name][it.ID] {
+			un, label = "&amp;un=t", "un-hide"
+		}
+		links = append(links, fmt.Sprintf(`<a href="hide?id=%d%s&amp;auth=%s&amp;goto=%s">%s</a>`,
+			it.ID, un, v.token, url.QueryEscape(here), label))
+	}
+	un, label := "", "favorite"
+	if s.faves[v.name][it.ID] {
+		un, label = "&amp;un=t", "un-favorite"
+	}
+	links = append(links, fmt.Sprintf(`<a href="fave?id=%d%s&amp;auth=%s">%s</a>`, it.ID, un, v.token, label))
+	return links
+}
+
+func age(it *item) string {
+	t := time.Unix(it.Time, 0).UTC()
+	return fmt.Sprintf(`<span class="age" title="%s %d"><a href="item?id=%d">%s</a></span>`,
+		t.Format("2006-01-02T15:04:05"), it.Time, it.ID, render.TimeAgo(it.Time))
+}
+
+// commentRow renders one comment at indent. onStory adds the story it
+// belongs to, as on the threads page and a comment's own page.
+func (s *Server) commentRow(it *item, indent int, v visitor, here string, onStory bool) string {
+	var head strings.Builder
+	text := it.Text
+	if it.Deleted {
+		text = "[deleted]"
+	} else {
+		if it.By == v.name && it.Score > 0 {
+			fmt.Fprintf(&head, `<span class="score" id="score_%d">%d points</span> by `, it.ID, it.Score)
+		}
+		fmt.Fprintf(&head, `<a href="user?id=%s" class="hnuser">%s</a> `, url.QueryEscape(it.By), html.EscapeString(it.By))
+	}
+	head.WriteString(age(it))
+	if !onStory {
+		// Links on a comment's own page go in its subtext instead.
+		if v.name != "" && it.By == v.name && !it.Deleted {
+			fmt.Fprintf(&head, ` | <a href="edit?id=%d">edit</a>`, it.ID)
+		}
+	} else if story := s.story(it); story != it {
+		fmt.Fprintf(&head, ` <span class="onstory"> | on: <a href="item?id=%d" title="%s">%s</a></span>`,
+			story.ID, html.EscapeString(story.Title), html.EscapeString(story.Title))
+	}
+
+	reply := ""
+	if !it.Deleted {
+		reply = fmt.Sprintf(`<p><font size="1"><u><a href="reply?id=%d&amp;goto=%s" rel="nofollow">reply</a></u></font>`,
+			it.ID, url.QueryEscape(here))
+	}
+	return fmt.Sprintf(`<tr class="athing comtr" id="%d"><td><table border="0"><tr><td class="ind" indent="%d"><img src="s.gif" height="1" width="%d"></td><td valign="top" class="votelinks"><center>%s</center></td><td class="default"><div style="margin-top:2px; margin-bottom:-10px;"><span class="comhead">%s</span></div><br><div class="comment"><div class="commtext c00">%s</div>
+<div class="reply">%s</div></div></td></tr></table></td></tr>
+`, it.ID, indent, indent*40, s.voteArrow(it, v, here), head.String(), text, reply)
+}
+
+// commentTree renders the replies in kids and everything under them.
+// Dead comments are hidden along with their replies.
+func (s *Server) commentTree(b *strings.Builder, kids []int, indent int, v visitor, here string) {
+	for _, id := range kids {
+		kid := s.items[id]
+		if kid == nil || kid.Dead {
+			continue
+		}
+		b.WriteString(s.commentRow(kid, indent, v, here, false))
+		s.commentTree(b, kid.Kids, indent+1, v, here)
+	}
+}
+
+func (s *Server) itemPage(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	it, ok := s.items[id]
+	if !ok || it.Type == "pollopt" {
+		message(w, "No such item.")
+		return
+	}
+	here := fmt.Sprintf("item?id=%d", it.ID)
+
+	var b strings.Builder
+	b.WriteString(`<table class="fatitem">`)
+	if it.Type == "comment" {
+		b.WriteString(s.commentRow(it, 0, v, here, true))
+		if links := s.itemLinks(it, v, here); len(links) > 0 {
+			fmt.Fprintf(&b, `<tr><td class="subtext">%s</td></tr>`, strings.Join(links, " | "))
+		}
+	} else {
+		b.WriteString(s.storyRow(it, 0, v, here, true))
+		if it.Text != "" {
+			fmt.Fprintf(&b, `<tr><td colspan="2"></td><td><div class="toptext">%s</div></td></tr>`, it.Text)
+		}
+		for _, id := range it.Parts {
+			if opt := s.items[id]; opt != nil {
+				fmt.Fprintf(&b, `<tr class="athing" id="%d"><td class="votelinks">%s</td><td class="comment"><div class="commtext">%s</div></td></tr><tr><td></td><td class="default"><span class="score" id="score_%d">%d points</span></td></tr>`,
+					opt.ID, s.voteArrow(opt, v, here), opt.Text, opt.ID, opt.Score)
+			}
+		}
+	}
+	if v.name != "" && !it.Deleted && !it.Dead {
+		b.WriteString(`<tr><td colspan="3">` + replyForm(it.ID, here, v) + `</td></tr>`)
+	}
+	b.WriteString(`</table><br><table class="comment-tree">`)
+	s.commentTree(&b, it.Kids, 0, v, here)
+	b.WriteString(`</table>`)
+
+	title := it.Title
+	if it.Type == "comment" {
+		title = it.By + "'s comment"
+	}
+	s.page(w, v, title, b.String())
+}
+
+func replyForm(parent int, back string, v visitor) string {
+	return fmt.Sprintf(`<form action="comment" method="post"><input type="hidden" name="parent" value="%d"><input type="hidden" name="goto" value="%s"><input type="hidden" name="hmac" value="%s"><textarea name="text" rows="8" cols="80" wrap="virtual"></textarea><br><br><input type="submit" value="add comment"></form>`,
+		parent, html.EscapeString(back), v.token)
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	back := r.URL.Query().Get("goto")
+	s.page(w, s.visitor(r), "Login", fmt.Sprintf(`<b>Login</b><br><br><form action="login" method="post"><input type="hidden" name="goto" value="%s"><table border="0"><tr><td>username:</td><td><input type="text" name="acct" size="20"></td></tr><tr><td>password:</td><td><input type="password" name="pw" size="20"></td></tr></table><br><input type="submit" value="login"></form>`,
+		html.EscapeString(back)))
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	acct, pw := r.PostFormValue("acct"), r.PostFormValue("pw")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if want, ok := s.passwords[acct]; !ok || pw != want {
+		message(w, "Bad login.")
+		return
+	}
+	token := newToken()
+	s.sessions[token] = acct
+	http.SetCookie(w, &http.Cookie{Name: "user", Value: acct + "&" + token, Path: "/", HttpOnly: true,
+		Expires: time.Now().AddDate(1, 0, 0)})
+	redirect(w, r, r.PostFormValue("goto"), "news")
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v := s.visitor(r); v.authorized(r.URL.Query().Get("auth")) {
+		delete(s.sessions, v.token)
+		http.SetCookie(w, &http.Cookie{Name: "user", Path: "/", MaxAge: -1})
+	}
+	redirect(w, r, r.URL.Query().Get("goto"), "news")
+}
+
+func (s *Server) vote(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id, _ := strconv.Atoi(q.Get("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if requireLogin(w, r, v) {
+		return
+	}
+	it, ok := s.items[id]
+	if !ok || !v.authorized(q.Get("auth")) || it.By == v.name {
+		http.Error(w, "Can't make that vote.", http.StatusBadRequest)
+		return
+	}
+	voted := s.votes[v.name][id]
+	switch q.Get("how") {
+	case "up":
+		if !voted {
+			it.Score++
+			s.votes.set(v.name, id, true)
+		}
+	case "un":
+		if voted {
+			it.Score--
+			s.votes.set(v.name, id, false)
+		}
+	default:
+		http.Error(w, "Can't make that vote.", http.StatusBadRequest)
+		return
+	}
+	redirect(w, r, q.Get("goto"), "news")
+}
+
+// toggle serves /fave and /hide, which set or (with un=t) clear a mark.
+func (s *Server) toggle(w http.ResponseWriter, r *http.Request, m marks) {
+	q := r.URL.Query()
+	id, _ := strconv.Atoi(q.Get("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if requireLogin(w, r, v) {
+		return
+	}
+	if _, ok := s.items[id]; !ok || !v.authorized(q.Get("auth")) {
+		http.Error(w, "Bad request.", http.StatusBadRequest)
+		return
+	}
+	m.set(v.name, id, q.Get("un") != "t")
+	redirect(w, r, q.Get("goto"), fmt.Sprintf("item?id=%d", id))
+}
+
+// favorites lists a user's favorite stories, or with comments=t their
+// favorite comments, newest first.
+func (s *Server) favorites(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name, comments := q.Get("id"), q.Get("comments") == "t"
+	p, _ := strconv.Atoi(q.Get("p"))
+	p = max(p, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if _, ok := s.users[name]; !ok {
+		message(w, "No such user.")
+		return
+	}
+
+	var ids []int
+	for id := range s.faves[name] {
+		if it := s.items[id]; it != nil && (it.Type == "comment") == comments {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	start := min((p-1)*favoritesPerPage, len(ids))
+	end := min(start+favoritesPerPage, len(ids))
+
+	here := "favorites?id=" + url.QueryEscape(name)
+	if comments {
+		here += "&comments=t"
+	}
+	var b strings.Builder
+	b.WriteString(`<table class="itemlist">`)
+	for i, id := range ids[start:end] {
+		if comments {
+			b.WriteString(s.commentRow(s.items[id], 0, v, here, true))
+		} else {
+			b.WriteString(s.storyRow(s.items[id], start+i+1, v, here, false))
+		}
+	}
+	if end < len(ids) {
+		fmt.Fprintf(&b, `<tr><td colspan="3"><a href="%s&amp;p=%d" class="morelink" rel="next">More</a></td></tr>`,
+			html.EscapeString(here), p+1)
+	}
+	b.WriteString(`</table>`)
+	s.page(w, v, name+"'s favorites", b.String())
+}
+
+// threads lists a user's comments, newest first, each followed by the
+// replies under it. next pages back from a comment ID.
+func (s *Server) threads(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := q.Get("id")
+	next, _ := strconv.Atoi(q.Get("next"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if _, ok := s.users[name]; !ok {
+		message(w, "No such user.")
+		return
+	}
+
+	var mine []*item
+	for _, it := range s.items {
+		if it.Type == "comment" && it.By == name && !it.Dead && !it.Deleted && (next == 0 || it.ID < next) {
+			mine = append(mine, it)
+		}
+	}
+	sort.Slice(mine, func(i, j int) bool { return mine[i].ID > mine[j].ID })
+
+	here := "threads?id=" + url.QueryEscape(name)
+	var b strings.Builder
+	b.WriteString(`<table class="comment-tree">`)
+	for _, it := range mine[:min(len(mine), threadsPerPage)] {
+		b.WriteString(s.commentRow(it, 0, v, here, true))
+		s.commentTree(&b, it.Kids, 1, v, here)
+	}
+	b.WriteString(`</table>`)
+	if len(mine) > threadsPerPage {
+		fmt.Fprintf(&b, `<a href="threads?id=%s&amp;next=%d" class="morelink" rel="next">More</a>`,
+			url.QueryEscape(name), mine[threadsPerPage-1].ID)
+	}
+	s.page(w, v, name+"'s comments", b.String())
+}
+
+func (s *Server) replyPage(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if requireLogin(w, r, v) {
+		return
+	}
+	it, ok := s.items[id]
+	if !ok || it.Dead || it.Deleted || it.Type == "pollopt" {
+		message(w, "No such item.")
+		return
+	}
+	back := fmt.Sprintf("item?id=%d#%d", s.story(it).ID, it.ID)
+	var b strings.Builder
+	b.WriteString(`<table class="fatitem">`)
+	if it.Type == "comment" {
+		b.WriteString(s.commentRow(it, 0, v, back, true))
+	} else {
+		b.WriteString(s.storyRow(it, 0, v, back, false))
+	}
+	b.WriteString(`</table>` + replyForm(it.ID, back, v))
+	s.page(w, v, "Add Comment", b.String())
+}
+
+func (s *Server) comment(w http.ResponseWriter, r *http.Request) {
+	parent, _ := strconv.Atoi(r.PostFormValue("parent"))
+	text := strings.TrimSpace(r.PostFormValue("text"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	p, ok := s.items[parent]
+	if !v.authorized(r.PostFormValue("hmac")) || !ok || p.Dead || p.Deleted {
+		message(w, "Unknown.")
+		return
+	}
+	if text == "" {
+		message(w, "Please try again.")
+		return
+	}
+	s.add(&item{Type: "comment", By: v.name, Parent: parent, Text: formatText(text), Score: 1})
+	redirect(w, r, r.PostFormValue("goto"), fmt.Sprintf("item?id=%d", s.story(p).ID))
+}
+
+func (s *Server) editPage(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if requireLogin(w, r, v) {
+		return
+	}
+	it, ok := s.items[id]
+	if !ok || it.By != v.name || it.Deleted {
+		message(w, "You can't edit that.")
+		return
+	}
+	s.page(w, v, "Edit", fmt.Sprintf(`<form action="xedit" method="post"><input type="hidden" name="id" value="%d"><input type="hidden" name="hmac" value="%s"><textarea name="text" rows="8" cols="80" wrap="virtual">%s</textarea><br><br><input type="submit" value="update"></form>`,
+		it.ID, v.token, html.EscapeString(unformatText(it.Text))))
+}
+
+func (s *Server) edit(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PostFormValue("id"))
+	text := strings.TrimSpace(r.PostFormValue("text"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	it, ok := s.items[id]
+	if !v.authorized(r.PostFormValue("hmac")) || !ok || it.By != v.name || it.Deleted {
+		message(w, "Unknown.")
+		return
+	}
+	if text == "" {
+		message(w, "Please try again.")
+		return
+	}
+	it.Text = formatText(text)
+	redirect(w, r, "", fmt.Sprintf("item?id=%d", it.ID))
+}
+
+func (s *Server) submitPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if requireLogin(w, r, v) {
+		return
+	}
+	s.page(w, v, "Submit", fmt.Sprintf(`<form action="r" method="post"><input type="hidden" name="fnid" value="%s"><input type="hidden" name="fnop" value="submit-page"><table border="0"><tr><td>title</td><td><input type="text" name="title" size="50"></td></tr><tr><td>url</td><td><input type="url" name="url" size="50"></td></tr><tr><td>text</td><td><textarea name="text" rows="4" cols="49"></textarea></td></tr></table><br><input type="submit" value="submit"></form>`,
+		v.token))
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	title := strings.TrimSpace(r.PostFormValue("title"))
+	link := strings.TrimSpace(r.PostFormValue("url"))
+	text := strings.TrimSpace(r.PostFormValue("text"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visitor(r)
+	if !v.authorized(r.PostFormValue("fnid")) {
+		http.Error(w, "Unknown or expired link.", http.StatusBadRequest)
+		return
+	}
+	if title == "" || len(title) > 80 {
+		http.Error(w, "Please try again.", http.StatusBadRequest)
+		return
+	}
+	if link != "" {
+		if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			http.Error(w, "That's not a valid url.", http.StatusBadRequest)
+			return
+		}
+	}
+	it := &item{Type: "story", By: v.name, Title: title, URL: link, Score: 1}
+	if text != "" {
+		it.Text = formatText(text)
+	}
+	s.add(it)
+	// Submitting upvotes your own story, as on HN.
+	s.votes.set(v.name, it.ID, true)
+	redirect(w, r, "", "newest")
+}
+
+// formatText turns a plain-text comment into HN's markup: escaped, with
+// blank lines starting new <p> paragraphs.
+func formatText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paras []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paras = append(paras, html.EscapeString(p))
+		}
+	}
+	return strings.Join(paras, "<p>")
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// unformatText is formatText's inverse, for the edit form.
+func unformatText(markup string) string {
+	markup = strings.ReplaceAll(markup, "<p>", "\n\n")
+	return html.UnescapeString(tagRe.ReplaceAllString(markup, ""))
+}
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -13,7 +14,8 @@ import (
 	"github.com/fragmede/nitpick/internal/ui/messages"
 )
 
-// Monitor polls for new replies to the user's comments.
+// Monitor polls for new replies to the user's comments and for new items
+// matching the user's keyword/domain alerts.
 type Monitor struct {
 	client   *api.Client
 	cache    *cache.DB
@@ -40,6 +42,13 @@ func (m *Monitor) Start(program *tea.Program, username string) {
 	go m.loop()
 }
 
+// StartAlerts begins polling keyword/domain alerts. Unlike reply
+// monitoring this doesn't need a logged-in user.
+func (m *Monitor) StartAlerts(program *tea.Program) {
+	m.program = program
+	go m.alertLoop()
+}
+
 // Stop halts the background polling.
 func (m *Monitor) Stop() {
 	select {
@@ -141,13 +150,9 @@ func (m *Monitor) poll() {
 				m.cache.PutItem(newItem)
 
 				// Create notification.
-				preview := render.HNToText(newItem.Text, 200)
-				if len(preview) > 200 {
-					preview = preview[:200]
-				}
 				m.cache.AddNotification(
 					newItem.ID, mc.ItemID, mc.ParentStoryID,
-					newItem.By, preview, newItem.Time,
+					newItem.By, preview(newItem.Text), newItem.Time,
 				)
 
 				// Track replies-to-replies if within depth limit.
@@ -178,6 +183,129 @@ func (m *Monitor) poll() {
 	}
 }
 
+func (m *Monitor) alertLoop() {
+	m.pollAlerts()
+	ticker := time.NewTicker(m.cfg.AlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.pollAlerts()
+		}
+	}
+}
+
+// alertPageSize is how many hits each alert search asks for per page.
+const alertPageSize = 50
+
+// pollAlerts runs each alert against Algolia's search_by_date, only asking
+// for items at or after the alert's watermark. Hits created in the same
+// second as the watermark come back again and are skipped by AddMention.
+func (m *Monitor) pollAlerts() {
+	alerts, err := m.cache.ListAlerts()
+	if err != nil || len(alerts) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	found := 0
+	for _, a := range alerts {
+		select {
+		case <-m.stopCh:
+			return
+		default:
+		}
+
+		params, ok := alertParams(a)
+		if !ok {
+			continue
+		}
+		watermark := fmt.Sprintf("created_at_i>=%d", a.Watermark)
+		if params.NumericFilters == "" {
+			params.NumericFilters = watermark
+		} else {
+			params.NumericFilters += "," + watermark
+		}
+		params.ByDate = true
+		params.HitsPerPage = alertPageSize
+
+		// Page through every new hit. The watermark only moves once all of
+		// them are stored, so a failed page is retried in full next poll.
+		newest := a.Watermark
+		complete := true
+		for page := 0; ; page++ {
+			params.Page = page
+			result, err := m.client.Search(ctx, params)
+			if err != nil {
+				complete = false
+				break
+			}
+			for _, item := range result.Items {
+				text := clip(item.Title)
+				storyID := item.ID
+				if item.Type == "comment" {
+					text = preview(item.Text)
+					storyID = item.StoryID
+				}
+				added, err := m.cache.AddMention(item.ID, item.Parent, storyID, item.By, text, a.Pattern, item.Time)
+				if err != nil {
+					complete = false
+					continue
+				}
+				if added {
+					found++
+				}
+				newest = max(newest, item.Time)
+			}
+			if page+1 >= result.NbPages || len(result.Items) == 0 {
+				break
+			}
+		}
+		if complete && newest > a.Watermark {
+			m.cache.SetAlertWatermark(a.ID, newest)
+		}
+	}
+
+	if found > 0 && m.program != nil {
+		m.program.Send(messages.NewNotificationMsg{UnreadCount: m.cache.UnreadNotificationCount()})
+	}
+}
+
+// previewLen is how many characters of an item's text a notification
+// keeps.
+const previewLen = 200
+
+// preview renders HN HTML as plain text for a notification.
+func preview(html string) string {
+	return clip(render.HNToText(html, previewLen))
+}
+
+// clip cuts s to previewLen characters without splitting a multi-byte one.
+func clip(s string) string {
+	if r := []rune(s); len(r) > previewLen {
+		return string(r[:previewLen])
+	}
+	return s
+}
+
+// alertParams builds the Algolia search for an alert rule.
+func alertParams(a cache.Alert) (api.SearchParams, bool) {
+	switch a.Kind {
+	case cache.AlertDomain:
+		return api.SearchParams{Query: a.Pattern, Tags: "story", RestrictTo: "url"}, true
+	case cache.AlertKeyword:
+		q, err := api.ParseQuery(a.Pattern)
+		if err != nil {
+			return api.SearchParams{}, false
+		}
+		return q.ParamsWithTags("(story,comment)"), true
+	}
+	return api.SearchParams{}, false
+}
+
 // findStoryID walks up the parent chain to find the root story ID.
 func findStoryID(item *api.Item, db *cache.DB, cfg config.Config) int {
 	current := item
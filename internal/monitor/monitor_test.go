@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+)
+
+// fakeSearch serves search_by_date over hits, honouring the created_at_i>=
+// watermark and paging.
+func fakeSearch(t *testing.T, hits *[]map[string]any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var since int64
+		for _, f := range strings.Split(q.Get("numericFilters"), ",") {
+			if v, ok := strings.CutPrefix(f, "created_at_i>="); ok {
+				since, _ = strconv.ParseInt(v, 10, 64)
+			} else if strings.HasPrefix(f, "created_at_i>") {
+				t.Errorf("watermark filter %q drops hits from the same second", f)
+			}
+		}
+		var match []map[string]any
+		for _, h := range *hits {
+			if h["created_at_i"].(int64) >= since {
+				match = append(match, h)
+			}
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		per, _ := strconv.Atoi(q.Get("hitsPerPage"))
+		start := min(page*per, len(match))
+		json.NewEncoder(w).Encode(map[string]any{
+			"hits":    match[start:min(start+per, len(match))],
+			"nbHits":  len(match),
+			"page":    page,
+			"nbPages": (len(match) + per - 1) / per,
+		})
+	}))
+}
+
+func comment(id int, at int64, text string) map[string]any {
+	return map[string]any{
+		"objectID":     strconv.Itoa(id),
+		"author":       "someone",
+		"created_at_i": at,
+		"comment_text": text,
+		"parent_id":    id - 1,
+		"story_id":     1,
+		"story_title":  "nitpick",
+	}
+}
+
+func TestPollAlerts(t *testing.T) {
+	// More than two pages of hits, all in one second. New alerts only
+	// match items from now on, so they're dated just ahead.
+	at := time.Now().Unix() + 60
+	var hits []map[string]any
+	for i := 0; i < 2*alertPageSize+10; i++ {
+		hits = append(hits, comment(1000-i, at, "nitpick "+strings.Repeat("é", previewLen)))
+	}
+	srv := fakeSearch(t, &hits)
+	defer srv.Close()
+
+	db, err := cache.Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ToggleAlert(cache.AlertKeyword, "nitpick"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := api.NewClient(api.Options{Endpoints: api.Endpoints{Algolia: srv.URL}})
+	m := New(config.Default(), client, db)
+
+	m.pollAlerts()
+	notes, err := db.ListNotifications(1000, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != len(hits) {
+		t.Fatalf("stored %d mentions, want all %d hits", len(notes), len(hits))
+	}
+	for _, n := range notes {
+		if n.StoryID != 1 {
+			t.Errorf("mention %d has story %d, want 1", n.ItemID, n.StoryID)
+		}
+		if !utf8.ValidString(n.TextPreview) || utf8.RuneCountInString(n.TextPreview) > previewLen {
+			t.Errorf("mention %d preview is %d bytes of invalid or overlong text", n.ItemID, len(n.TextPreview))
+		}
+	}
+	alerts, _ := db.ListAlerts()
+	if alerts[0].Watermark != at {
+		t.Errorf("watermark = %d, want %d", alerts[0].Watermark, at)
+	}
+
+	// A later hit in the watermark's second is found; the ones already
+	// stored are not counted again.
+	hits = append([]map[string]any{comment(2000, at, "nitpick again")}, hits...)
+	m.pollAlerts()
+	notes, _ = db.ListNotifications(1000, false)
+	if len(notes) != len(hits) {
+		t.Errorf("after second poll stored %d mentions, want %d", len(notes), len(hits))
+	}
+}
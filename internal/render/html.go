@@ -1,7 +1,6 @@
 package render
 
 import (
-	"html"
 	"strings"
 
 	xhtml "golang.org/x/net/html"
@@ -14,9 +13,8 @@ func HNToPlainText(raw string) string {
 		return ""
 	}
 
-	// Pre-unescape HTML entities.
-	raw = html.UnescapeString(raw)
-
+	// The tokenizer decodes entities in text, so escaped markup like
+	// &lt;b&gt; stays text instead of turning into a tag.
 	tokenizer := xhtml.NewTokenizer(strings.NewReader(raw))
 	var sb strings.Builder
 	var inPre, inCode bool
@@ -0,0 +1,35 @@
+package render
+
+import "testing"
+
+func TestHNToPlainText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"paragraphs", "One<p>Two", "One\n\nTwo"},
+		{"italic and code", "Hello <i>world</i> &amp; <code>a &lt; b</code>", "Hello *world* & `a < b`"},
+		{"escaped markup", "use &lt;b&gt; for bold, not &lt;i&gt;", "use <b> for bold, not <i>"},
+		{"double escaped", "Tom &amp;amp; Jerry", "Tom &amp; Jerry"},
+		{"entities", "&#x27;q&#x27; &quot;x&quot;", `'q' "x"`},
+		{"link", `See <a href="https://x.com/">docs</a>.`, "See docs [https://x.com/]."},
+		{"bare link", `See <a href="https://x.com/">https://x.com/</a>`, "See https://x.com/"},
+		{"pre code", "Code:<pre><code>if a &lt; b {\n  x()\n}\n</code></pre>", "Code:\n    if a < b {\n      x()\n    }"},
+	}
+	for _, tt := range tests {
+		if got := HNToPlainText(tt.in); got != tt.want {
+			t.Errorf("%s: HNToPlainText(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHNToText(t *testing.T) {
+	in := "one two three four five six<p><pre><code>a code line longer than ten\n</code></pre>"
+	want := "one two\nthree four\nfive six\n\n\n    a code line longer than ten"
+	if got := HNToText(in, 10); got != want {
+		t.Errorf("HNToText(%q, 10)\n got %q\nwant %q", in, got, want)
+	}
+	if got := HNToText("one two three", 0); got != "one two three" {
+		t.Errorf("width 0 wrapped: %q", got)
+	}
+}
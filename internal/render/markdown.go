@@ -0,0 +1,178 @@
+package render
+
+import (
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// HNToMarkdown converts HN's limited HTML to Markdown: paragraphs become
+// blank-line separated blocks, <i> becomes *emphasis*, <code> becomes
+// `code`, <pre> becomes a fenced block and <a> becomes [text](url).
+// Characters Markdown would otherwise interpret are escaped, and
+// <script> and <style> are dropped with their contents.
+func HNToMarkdown(raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(raw))
+	var sb strings.Builder
+	var inPre, inCode bool
+	var anchorURL string
+	var anchorText strings.Builder
+	inAnchor := false
+	var skip string // the script or style element being dropped
+
+	// out is where text goes: link text is buffered until </a>.
+	out := func() *strings.Builder {
+		if inAnchor {
+			return &anchorText
+		}
+		return &sb
+	}
+
+	for {
+		tt := tokenizer.Next()
+		if skip != "" && tt != xhtml.ErrorToken {
+			if tt == xhtml.EndTagToken && tokenizer.Token().Data == skip {
+				skip = ""
+			}
+			continue
+		}
+		switch tt {
+		case xhtml.ErrorToken:
+			return strings.TrimSpace(sb.String())
+
+		case xhtml.StartTagToken:
+			t := tokenizer.Token()
+			switch t.Data {
+			case "script", "style":
+				skip = t.Data
+			case "p":
+				if !inPre {
+					blankLine(&sb)
+				}
+			case "i", "em":
+				out().WriteString("*")
+			case "code":
+				if !inPre {
+					out().WriteString("`")
+				}
+				inCode = true
+			case "pre":
+				inPre = true
+				blankLine(&sb)
+				sb.WriteString("```\n")
+			case "a":
+				anchorURL = ""
+				for _, attr := range t.Attr {
+					if attr.Key == "href" {
+						anchorURL = attr.Val
+					}
+				}
+				anchorText.Reset()
+				inAnchor = true
+			}
+
+		case xhtml.EndTagToken:
+			t := tokenizer.Token()
+			switch t.Data {
+			case "i", "em":
+				out().WriteString("*")
+			case "code":
+				if !inPre {
+					out().WriteString("`")
+				}
+				inCode = false
+			case "pre":
+				inPre = false
+				if !strings.HasSuffix(sb.String(), "\n") {
+					sb.WriteString("\n")
+				}
+				sb.WriteString("```\n")
+			case "a":
+				inAnchor = false
+				text := strings.TrimSpace(anchorText.String())
+				switch {
+				case !strings.HasPrefix(anchorURL, "http://") && !strings.HasPrefix(anchorURL, "https://"):
+					sb.WriteString(text)
+				case text == "" || linkTextIsURL(text, anchorURL):
+					sb.WriteString("<" + anchorURL + ">")
+				default:
+					sb.WriteString("[" + text + "](" + markdownURLEscaper.Replace(anchorURL) + ")")
+				}
+				anchorURL = ""
+			}
+
+		case xhtml.TextToken:
+			text := tokenizer.Token().Data
+			w := out()
+			switch {
+			case inPre:
+				w.WriteString(strings.TrimPrefix(text, "\n"))
+			case inCode:
+				w.WriteString(text)
+			default:
+				normalized := strings.Join(strings.Fields(text), " ")
+				if normalized == "" {
+					if w.Len() > 0 && !endsWithSpaceOrNewline(w) {
+						w.WriteString(" ")
+					}
+					continue
+				}
+				if w.Len() > 0 && isSpaceByte(text[0]) && !endsWithSpaceOrNewline(w) {
+					w.WriteString(" ")
+				}
+				w.WriteString(escapeMarkdown(normalized))
+				if isSpaceByte(text[len(text)-1]) {
+					w.WriteString(" ")
+				}
+			}
+		}
+	}
+}
+
+// blankLine ends the current block so the next one starts a paragraph.
+func blankLine(sb *strings.Builder) {
+	if sb.Len() == 0 {
+		return
+	}
+	for !strings.HasSuffix(sb.String(), "\n\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// linkTextIsURL reports whether the (escaped) link text is just the URL,
+// possibly shortened with "..." as HN does for long links.
+func linkTextIsURL(text, href string) bool {
+	href = escapeMarkdown(href)
+	if text == href {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(text, "...")
+	return ok && strings.HasPrefix(href, prefix)
+}
+
+var markdownURLEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+)
+
+// escapeMarkdown escapes characters that would start emphasis, code, links
+// or HTML in Markdown. A leading # or > is escaped too so comment text
+// can't turn into a heading or quote.
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+	if strings.HasPrefix(s, "#") || strings.HasPrefix(s, ">") {
+		s = `\` + s
+	}
+	return s
+}
@@ -0,0 +1,29 @@
+package render
+
+import "testing"
+
+func TestHNToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"paragraphs", "One<p>Two<p>Three", "One\n\nTwo\n\nThree"},
+		{"italic and code", "Hello <i>world</i> and <code>a*b</code>", "Hello *world* and `a*b`"},
+		{"escaping", "*stars* _under_ [x] `tick` a\\b", "\\*stars\\* \\_under\\_ \\[x\\] \\`tick\\` a\\\\b"},
+		{"no headings or quotes", "# one<p>&gt; two", "\\# one\n\n\\> two"},
+		{"entities", "&#x27;q&#x27; &amp; &quot;x&quot; a&lt;b", "'q' & \"x\" a\\<b"},
+		{"pre code", "Code:<p><pre><code>  for i := 0; i &lt; n; i++ {\n    *p = `x`\n  }\n</code></pre>After",
+			"Code:\n\n```\n  for i := 0; i < n; i++ {\n    *p = `x`\n  }\n```\nAfter"},
+		{"link", `See <a href="https://x.com/a b">the docs</a>.`, "See [the docs](https://x.com/a%20b)."},
+		{"bare link", `<a href="https://e.com/a_b(1)" rel="nofollow">https://e.com/a_b(1)</a>`, "<https://e.com/a_b(1)>"},
+		{"shortened link", `<a href="https://e.com/a/long/path">https://e.com/a/...</a>`, "<https://e.com/a/long/path>"},
+		{"javascript link", `<a href="javascript:alert(1)">click</a> me`, "click me"},
+		{"script", "a<script>alert(1)</script> b<style>p {}</style>", "a b"},
+		{"italic link text", `<a href="https://e.com/"><i>e</i></a>`, "[*e*](https://e.com/)"},
+	}
+	for _, tt := range tests {
+		if got := HNToMarkdown(tt.in); got != tt.want {
+			t.Errorf("%s: HNToMarkdown(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
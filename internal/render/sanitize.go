@@ -0,0 +1,66 @@
+package render
+
+import (
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// SanitizeHN re-emits HN comment HTML keeping only the tags HN itself
+// produces (<p>, <i>, <em>, <code>, <pre>, and <a> with an http(s) href).
+// Everything else is escaped, so the result is safe to embed in a page.
+// The contents of <script> and <style> are dropped along with the tags.
+func SanitizeHN(raw string) string {
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(raw))
+	var sb strings.Builder
+	var skip string // the script or style element being dropped
+	for {
+		tt := tokenizer.Next()
+		if skip != "" && tt != xhtml.ErrorToken {
+			if tt == xhtml.EndTagToken && tokenizer.Token().Data == skip {
+				skip = ""
+			}
+			continue
+		}
+		switch tt {
+		case xhtml.ErrorToken:
+			return sb.String()
+
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			t := tokenizer.Token()
+			switch t.Data {
+			case "script", "style":
+				if tt == xhtml.StartTagToken {
+					skip = t.Data
+				}
+			case "p", "i", "em", "code", "pre":
+				sb.WriteString("<" + t.Data + ">")
+			case "a":
+				href := ""
+				for _, attr := range t.Attr {
+					if attr.Key == "href" {
+						href = attr.Val
+					}
+				}
+				if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+					sb.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow">`)
+				} else {
+					sb.WriteString("<a>")
+				}
+			}
+
+		case xhtml.EndTagToken:
+			t := tokenizer.Token()
+			switch t.Data {
+			case "p":
+				// HN never closes paragraphs; neither do we.
+			case "i", "em", "code", "pre", "a":
+				sb.WriteString("</" + t.Data + ">")
+			}
+
+		case xhtml.TextToken:
+			sb.WriteString(html.EscapeString(tokenizer.Token().Data))
+		}
+	}
+}
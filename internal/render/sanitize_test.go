@@ -0,0 +1,28 @@
+package render
+
+import "testing"
+
+func TestSanitizeHN(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"kept tags", `a<p><i>b</i> <em>c</em> <code>d</code>`, `a<p><i>b</i> <em>c</em> <code>d</code>`},
+		{"pre code", "<pre><code>  if a &lt; b {\n  }\n</code></pre>", "<pre><code>  if a &lt; b {\n  }\n</code></pre>"},
+		{"script", `a<script>alert(1)</script>b`, `ab`},
+		{"unclosed script", `a<script>alert(1)`, `a`},
+		{"style", `<style>body { display: none }</style>a`, `a`},
+		{"event attributes", `<p onclick="x()"><i onmouseover="y()">a</i>`, `<p><i>a</i>`},
+		{"other tags", `<img src=x onerror=alert(1)><b>a</b><iframe src="https://e.com"></iframe>`, `a`},
+		{"javascript href", `<a href="javascript:alert(1)">a</a>`, `<a>a</a>`},
+		{"relative href", `<a href="//evil.com/">a</a><a href="item?id=1">b</a>`, `<a>a</a><a>b</a>`},
+		{"http href", `<a href="https://e.com/?a=1&amp;b=&quot;2&quot;" onclick="x()">a</a>`,
+			`<a href="https://e.com/?a=1&amp;b=&#34;2&#34;" rel="nofollow">a</a>`},
+		{"entities", `a &amp; b &lt;script&gt; &#x27;c&#x27; &quot;d&quot;`, `a &amp; b &lt;script&gt; &#39;c&#39; &#34;d&#34;`},
+		{"stray closing tags", `a</p></div></script>`, `a`},
+	}
+	for _, tt := range tests {
+		if got := SanitizeHN(tt.in); got != tt.want {
+			t.Errorf("%s: SanitizeHN(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package syncer downloads the full comment trees of the top stories in
+// selected tabs into the cache, so they can be read offline. It backs the
+// nitpick sync command and the optional background sync in the TUI.
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/tree"
+)
+
+// ErrBudget is returned when a sync stops because it hit Options.MaxBytes.
+var ErrBudget = errors.New("sync byte budget exhausted")
+
+// Options controls what a sync downloads.
+type Options struct {
+	Tabs        []api.StoryType
+	Stories     int   // stories per tab
+	Concurrency int   // parallel item requests
+	MaxBytes    int64 // stop after downloading this much; 0 = no limit
+}
+
+// OptionsFromConfig returns the sync options set in cfg.
+func OptionsFromConfig(cfg config.Config) Options {
+	tabs := make([]api.StoryType, 0, len(cfg.SyncTabs))
+	for _, t := range cfg.SyncTabs {
+		tabs = append(tabs, api.StoryType(t))
+	}
+	return Options{
+		Tabs:        tabs,
+		Stories:     cfg.SyncStories,
+		Concurrency: cfg.SyncConcurrency,
+		MaxBytes:    cfg.SyncMaxBytes,
+	}
+}
+
+// CanSync reports whether st is a tab sync knows how to list: one of the
+// Firebase story lists or Past.
+func CanSync(st api.StoryType) bool {
+	switch st {
+	case api.StoryTypeTop, api.StoryTypeNew, api.StoryTypeBest, api.StoryTypeAsk,
+		api.StoryTypeShow, api.StoryTypeJobs, api.StoryTypePast:
+		return true
+	}
+	return false
+}
+
+// Progress describes how far a sync has got.
+type Progress struct {
+	Tab     api.StoryType
+	Story   int   // stories finished in Tab
+	Stories int   // stories to sync in Tab
+	Items   int   // items downloaded so far, all tabs
+	Bytes   int64 // bytes downloaded so far, all tabs
+}
+
+// String formats p for a status line.
+func (p Progress) String() string {
+	return fmt.Sprintf("%s %d/%d stories, %d items, %s", p.Tab, p.Story, p.Stories, p.Items, FormatBytes(p.Bytes))
+}
+
+// Syncer runs syncs against one client and cache.
+type Syncer struct {
+	client *api.Client
+	db     *cache.DB
+	cfg    config.Config
+	opts   Options
+
+	mu       sync.Mutex
+	progress Progress
+	start    int64 // client.BytesRead() when the sync began
+}
+
+// New creates a syncer.
+func New(client *api.Client, db *cache.DB, cfg config.Config, opts Options) *Syncer {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return &Syncer{client: client, db: db, cfg: cfg, opts: opts}
+}
+
+// Run syncs every tab in order, calling report (if non-nil) after each
+// story. It returns the final progress, and ErrBudget if the byte budget
+// ran out before everything was downloaded. Bytes are counted across the
+// whole client, so other requests made during the sync count too.
+func (s *Syncer) Run(ctx context.Context, report func(Progress)) (Progress, error) {
+	s.mu.Lock()
+	s.progress = Progress{}
+	s.start = s.client.BytesRead()
+	s.mu.Unlock()
+
+	for _, tab := range s.opts.Tabs {
+		ids, err := s.storyIDs(ctx, tab)
+		if err != nil {
+			return s.snapshot(), err
+		}
+		if len(ids) > s.opts.Stories {
+			ids = ids[:s.opts.Stories]
+		}
+
+		s.mu.Lock()
+		s.progress.Tab, s.progress.Story, s.progress.Stories = tab, 0, len(ids)
+		s.mu.Unlock()
+
+		for _, id := range ids {
+			if err := s.syncTree(ctx, id); err != nil {
+				return s.snapshot(), err
+			}
+			s.mu.Lock()
+			s.progress.Story++
+			s.mu.Unlock()
+			if report != nil {
+				report(s.snapshot())
+			}
+		}
+	}
+	return s.snapshot(), nil
+}
+
+func (s *Syncer) snapshot() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.progress
+	p.Bytes = s.client.BytesRead() - s.start
+	return p
+}
+
+func (s *Syncer) overBudget() bool {
+	return s.opts.MaxBytes > 0 && s.client.BytesRead()-s.start >= s.opts.MaxBytes
+}
+
+// storyIDs fetches and caches a tab's ranked story list.
+func (s *Syncer) storyIDs(ctx context.Context, tab api.StoryType) ([]int, error) {
+	var ids []int
+	if tab == api.StoryTypePast {
+		items, err := s.client.GetPastStories(ctx, s.opts.Stories)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+	} else {
+		var err error
+		if ids, err = s.client.GetStoryIDs(ctx, tab); err != nil {
+			return nil, err
+		}
+	}
+	s.db.PutStoryList(string(tab), ids)
+	return ids, nil
+}
+
+// syncTree downloads a story and all of its comments one tree level at a
+// time. The story is always refetched so its kids are current; comments
+// still fresh in the cache are reused. Items that fail to fetch are
+// skipped along with their replies. It stops with ErrBudget when the
+// byte budget runs out, and with api.ErrOffline when the client goes
+// offline.
+func (s *Syncer) syncTree(ctx context.Context, storyID int) error {
+	l := tree.Loader{
+		Client:      s.client,
+		DB:          s.db,
+		TTL:         s.cfg.CommentTTL,
+		Concurrency: s.opts.Concurrency,
+		Check: func() error {
+			switch {
+			case s.client.Offline():
+				return api.ErrOffline
+			case s.overBudget():
+				return ErrBudget
+			}
+			return nil
+		},
+		Fetched: func(*api.Item) {
+			s.mu.Lock()
+			s.progress.Items++
+			s.mu.Unlock()
+		},
+	}
+	story := l
+	story.Reuse = func(*api.Item, bool) bool { return false }
+	items, err := story.Level(ctx, []int{storyID})
+	if err != nil {
+		return err
+	}
+	if items[0] == nil {
+		return nil
+	}
+	_, err = l.Walk(ctx, items[0].Kids())
+	return err
+}
+
+// RunEvery syncs immediately and then once per interval until ctx is
+// done, skipping rounds while the client is offline. done (if non-nil) is
+// called after each round.
+func (s *Syncer) RunEvery(ctx context.Context, interval time.Duration, report func(Progress), done func(Progress, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if !s.client.Offline() {
+			p, err := s.Run(ctx, report)
+			if ctx.Err() != nil {
+				return
+			}
+			if done != nil {
+				done(p, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FormatBytes renders n as a human-readable size.
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,115 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/fakehn"
+)
+
+func newSyncer(t *testing.T, h http.Handler, opts Options) (*Syncer, *cache.DB) {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	db, err := cache.Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	client := api.NewClient(api.Options{Endpoints: fakehn.Endpoints(srv.URL), Transport: http.DefaultTransport})
+	return New(client, db, config.Default(), opts), db
+}
+
+func fakeHN(t *testing.T) http.Handler {
+	t.Helper()
+	srv, err := fakehn.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// countTree counts the items cached under id, including it.
+func countTree(db *cache.DB, id int) int {
+	item, _, _ := db.GetItem(id, 0)
+	if item == nil {
+		return 0
+	}
+	n := 1
+	for _, kid := range item.Kids() {
+		n += countTree(db, kid)
+	}
+	return n
+}
+
+func TestRun(t *testing.T) {
+	s, db := newSyncer(t, fakeHN(t), Options{Tabs: []api.StoryType{api.StoryTypeTop}, Stories: 3, Concurrency: 4})
+	var reports int
+	p, err := s.Run(context.Background(), func(Progress) { reports++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Story != 3 || p.Stories != 3 || reports != 3 {
+		t.Errorf("progress %+v after %d reports, want 3 stories", p, reports)
+	}
+
+	ids, _, _ := db.GetStoryList(string(api.StoryTypeTop), 0)
+	items := 0
+	for _, id := range ids[:3] {
+		items += countTree(db, id)
+	}
+	if items < 4 || p.Items != items {
+		t.Errorf("fetched %d items, cache has %d under the first 3 stories", p.Items, items)
+	}
+	if item, _, _ := db.GetItem(0, 0); item != nil {
+		t.Errorf("cache has an empty item: %+v", item)
+	}
+
+	// A second sync refetches only the stories.
+	if p, err = s.Run(context.Background(), nil); err != nil || p.Items != 3 {
+		t.Errorf("second sync fetched %d items (%v), want just the 3 stories", p.Items, err)
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	s, _ := newSyncer(t, fakeHN(t), Options{Tabs: []api.StoryType{api.StoryTypeTop}, Stories: 10, MaxBytes: 1})
+	p, err := s.Run(context.Background(), nil)
+	if !errors.Is(err, ErrBudget) {
+		t.Errorf("err = %v, want ErrBudget", err)
+	}
+	if p.Items != 0 {
+		t.Errorf("fetched %d items past the budget", p.Items)
+	}
+}
+
+func TestRunStopsOffline(t *testing.T) {
+	// The story list loads, then the connection drops on every item.
+	hn := fakeHN(t)
+	var items atomic.Int64
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/item/") {
+			items.Add(1)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		hn.ServeHTTP(w, r)
+	})
+	s, _ := newSyncer(t, h, Options{Tabs: []api.StoryType{api.StoryTypeTop}, Stories: 10})
+	_, err := s.Run(context.Background(), nil)
+	if !errors.Is(err, api.ErrOffline) {
+		t.Errorf("err = %v, want ErrOffline", err)
+	}
+	if n := items.Load(); n >= 10 {
+		t.Errorf("tried %d items after going offline", n)
+	}
+}
@@ -0,0 +1,287 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CassetteFile is the name of the cassette a Recorder writes in its
+// directory and a Replayer reads.
+const CassetteFile = "cassette.jsonl"
+
+// interaction is one request and its response (or error), one per line
+// of a cassette.
+type interaction struct {
+	Time   time.Time  `json:"time"`
+	Method string     `json:"method"`
+	URL    string     `json:"url"`
+	Form   url.Values `json:"form,omitempty"` // posted form, with passwords and tokens redacted
+
+	Status     int         `json:"status,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"` // instead of Body when it isn't UTF-8
+	Error      string      `json:"error,omitempty"`
+}
+
+// key matches a request to its recorded interactions. Tokens are
+// redacted, as they are in the cassette, and the times in created_at_i
+// filters are dropped: yesterday's stories and alert watermarks are
+// computed from the clock, so they never repeat on replay.
+func key(method, url string) string {
+	url = redactURL(url)
+	url = timeFilter.ReplaceAllString(url, "${1}")
+	return method + " " + url
+}
+
+// timeFilter matches a created_at_i comparison in a query-escaped
+// numericFilters value, e.g. created_at_i%3E%3D1700000000.
+var timeFilter = regexp.MustCompile(`(?i)(created_at_i(?:%3[CE]|[<>])(?:%3D|=)?)\d+`)
+
+// redacted replaces secrets in a cassette, which is meant to be attached
+// to bug reports.
+const redacted = "REDACTED"
+
+// Form fields that carry a password or the session's tokens: hmac signs
+// replies and edits, fnid submissions.
+var secretFields = []string{"pw", "hmac", "fnid"}
+
+var (
+	// authParam matches the auth token in a vote, hide, fave or logout
+	// link, in a URL or in the page that has it.
+	authParam = regexp.MustCompile(`([?&;]auth=)[^&"'\s<>#]+`)
+	// hiddenToken matches the value of an hmac or fnid form input.
+	hiddenToken = regexp.MustCompile(`(name=["']?(?:hmac|fnid)["']?\s+value=["']?)[^"'\s>]+`)
+)
+
+// redactURL blanks the auth token in a URL.
+func redactURL(u string) string {
+	return authParam.ReplaceAllString(u, "${1}"+redacted)
+}
+
+// redactBody blanks the auth, hmac and fnid tokens in a page.
+func redactBody(s string) string {
+	s = authParam.ReplaceAllString(s, "${1}"+redacted)
+	return hiddenToken.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// Recorder is an http.RoundTripper that sends requests through another
+// round tripper and appends each exchange to a cassette. Login passwords,
+// cookie values and the auth, hmac and fnid tokens are redacted; bodies
+// are otherwise kept as HN sent them.
+type Recorder struct {
+	next http.RoundTripper
+	path string
+
+	mu    sync.Mutex
+	f     *os.File
+	count int
+}
+
+// NewRecorder records to dir/CassetteFile, appending if it exists.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, CassetteFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{next: next, path: path, f: f}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	in := interaction{Time: time.Now().UTC(), Method: req.Method, URL: redactURL(req.URL.String())}
+	if req.GetBody != nil && req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			if form, err := url.ParseQuery(string(data)); err == nil {
+				for _, f := range secretFields {
+					if form.Has(f) {
+						form.Set(f, redacted)
+					}
+				}
+				in.Form = form
+			}
+		}
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		if req.Context().Err() == nil {
+			// A network failure worth replaying, unlike a cancelled request.
+			in.Error = err.Error()
+			r.write(in)
+		}
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		in.Error = err.Error()
+		r.write(in)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	in.Status = resp.StatusCode
+	in.Header = resp.Header.Clone()
+	if cookies := in.Header.Values("Set-Cookie"); len(cookies) > 0 {
+		in.Header.Del("Set-Cookie")
+		for _, c := range cookies {
+			in.Header.Add("Set-Cookie", redactCookie(c))
+		}
+	}
+	if loc := in.Header.Get("Location"); loc != "" {
+		in.Header.Set("Location", redactURL(loc))
+	}
+	if utf8.Valid(data) {
+		in.Body = redactBody(string(data))
+	} else {
+		in.BodyBase64 = data
+	}
+	r.write(in)
+	return resp, nil
+}
+
+// redactCookie blanks the value of a Set-Cookie header, keeping its name
+// and attributes.
+func redactCookie(v string) string {
+	name, rest, _ := strings.Cut(v, "=")
+	_, attrs, ok := strings.Cut(rest, ";")
+	if !ok {
+		return name + "=" + redacted
+	}
+	return name + "=" + redacted + ";" + attrs
+}
+
+func (r *Recorder) write(in interaction) {
+	line, err := json.Marshal(in)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.f.Write(append(line, '\n')); err == nil {
+		r.count++
+	}
+}
+
+// Path returns the cassette's file name.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Count returns how many exchanges have been recorded.
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Close closes the cassette file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// ErrNotRecorded is returned by a Replayer for a request the cassette
+// has no answer for.
+var ErrNotRecorded = errors.New("request not in cassette")
+
+// Replayer is an http.RoundTripper that answers requests from a cassette
+// instead of the network. Requests match on method and URL, ignoring
+// tokens and created_at_i times (see key); a request made several times
+// gets the recorded answers in order, then the last one again.
+type Replayer struct {
+	mu    sync.Mutex
+	byKey map[string][]interaction
+	next  map[string]int
+}
+
+// NewReplayer loads dir/CassetteFile.
+func NewReplayer(dir string) (*Replayer, error) {
+	path := filepath.Join(dir, CassetteFile)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &Replayer{byKey: make(map[string][]interaction), next: make(map[string]int)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var in interaction
+		if err := json.Unmarshal(sc.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		k := key(in.Method, in.URL)
+		r.byKey[k] = append(r.byKey[k], in)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	k := key(req.Method, req.URL.String())
+	r.mu.Lock()
+	recorded := r.byKey[k]
+	if len(recorded) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, k)
+	}
+	i := min(r.next[k], len(recorded)-1)
+	r.next[k] = i + 1
+	in := recorded[i]
+	r.mu.Unlock()
+
+	if in.Error != "" {
+		return nil, errors.New(in.Error)
+	}
+	body := []byte(in.Body)
+	if in.BodyBase64 != nil {
+		body = in.BodyBase64
+	}
+	header := in.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package transport_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/fakehn"
+	"github.com/fragmede/nitpick/internal/transport"
+)
+
+// tokenSpy sits between the recorder and the network and keeps the
+// session token from the login cookie, to look for it in the cassette.
+type tokenSpy struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (s *tokenSpy) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range resp.Cookies() {
+		if _, token, ok := strings.Cut(c.Value, "&"); c.Name == "user" && ok {
+			s.mu.Lock()
+			s.token = token
+			s.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// useSite logs in and does everything that sends a token: votes, hides
+// and faves (auth), replies (hmac) and submits (fnid).
+func useSite(t *testing.T, base string, rt http.RoundTripper) {
+	t.Helper()
+	session := auth.NewSession(auth.Options{BaseURL: base, Transport: rt})
+	if err := session.Login("demo", "demo"); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		do   func() error
+	}{
+		{"vote", func() error { return session.Vote(2) }},
+		{"hide", func() error { return session.Hide(3, false) }},
+		{"fave", func() error { return session.Fave(4, false) }},
+		{"reply", func() error { return session.Reply(1, "Recorded reply.") }},
+		{"submit", func() error { return session.Submit("Recorded story", "https://example.com/recorded", "") }},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+}
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	// The session logs its form fields.
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	srv, err := fakehn.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	spy := &tokenSpy{}
+	rec, err := transport.NewRecorder(dir, spy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useSite(t, ts.URL, rec)
+	rec.Close()
+	if spy.token == "" {
+		t.Fatal("never saw a session token")
+	}
+
+	data, err := os.ReadFile(rec.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), spy.token) {
+		t.Error("cassette contains the session token")
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	sc.Buffer(nil, 64<<20)
+	seen := make(map[string]bool)
+	for sc.Scan() {
+		var in struct {
+			URL  string     `json:"url"`
+			Form url.Values `json:"form"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &in); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []string{"pw", "hmac", "fnid"} {
+			if in.Form.Has(f) {
+				seen[f] = true
+				if v := in.Form.Get(f); v != "REDACTED" {
+					t.Errorf("%s form field %s = %q", in.URL, f, v)
+				}
+			}
+		}
+		if strings.Contains(in.URL, "auth=") {
+			seen["auth"] = true
+			if !strings.Contains(in.URL, "auth=REDACTED") {
+				t.Errorf("URL not redacted: %s", in.URL)
+			}
+		}
+	}
+	for _, f := range []string{"pw", "hmac", "fnid", "auth"} {
+		if !seen[f] {
+			t.Errorf("no %s token was recorded; the test no longer covers it", f)
+		}
+	}
+
+	// The redacted cassette still replays: the tokens scraped from
+	// recorded pages are REDACTED, and so are the recorded requests.
+	rep, err := transport.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+	useSite(t, ts.URL, rep)
+}
+
+func TestReplayerIgnoresTimeFilters(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hits":[]}`))
+	}))
+	defer srv.Close()
+	rec, err := transport.NewRecorder(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(rt http.RoundTripper, query string) error {
+		req, _ := http.NewRequest("GET", srv.URL+"/search?"+query, nil)
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	recorded := url.Values{"numericFilters": {"created_at_i>1700000000,created_at_i<1700086400"}, "tags": {"front_page"}}
+	if err := get(rec, recorded.Encode()); err != nil {
+		t.Fatal(err)
+	}
+	rec.Close()
+
+	rep, err := transport.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := url.Values{"numericFilters": {"created_at_i>1700086400,created_at_i<1700172800"}, "tags": {"front_page"}}
+	if err := get(rep, later.Encode()); err != nil {
+		t.Errorf("a later day's request didn't match: %v", err)
+	}
+	other := url.Values{"numericFilters": {"created_at_i>1700086400,created_at_i<1700172800"}, "tags": {"story"}}
+	if err := get(rep, other.Encode()); err == nil {
+		t.Error("a different search matched")
+	}
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// bucket is a token-bucket rate limiter.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; 0 means unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(l Limit) bucket {
+	burst := float64(max(l.Burst, 1))
+	return bucket{rate: l.Rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *bucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// breaker is a circuit breaker: closed while requests succeed, open (all
+// requests refused) for cooldown after threshold consecutive failures,
+// then half-open, letting one trial request decide.
+type breaker struct {
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if wait := time.Until(b.openUntil); wait > 0 || b.trial {
+		return fmt.Errorf("%w (retrying in %s)", ErrCircuitOpen, max(wait, 0).Round(time.Second))
+	}
+	b.trial = true
+	return nil
+}
+
+// record notes the outcome of a request that allow let through.
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// abandon notes that a request allow let through ended without telling
+// anything about the host, freeing the half-open trial slot.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
@@ -0,0 +1,250 @@
+// Package transport is the HTTP layer shared by every request nitpick
+// makes to HN, Firebase and Algolia: retries with backoff, per-host rate
+// limiting and a per-host circuit breaker.
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a token-bucket rate: Rate requests per second on average, with
+// bursts of up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Options configures a Transport.
+type Options struct {
+	MaxAttempts    int           // tries per request, including the first
+	BaseDelay      time.Duration // backoff before the first retry; doubles after each
+	MaxDelay       time.Duration // cap on one backoff; a longer Retry-After gives up
+	AttemptTimeout time.Duration // per try, including reading the body
+
+	RateLimit  Limit            // per host
+	HostLimits map[string]Limit // overrides RateLimit for these hosts
+
+	BreakerThreshold int           // consecutive failed requests that open a host's breaker
+	BreakerCooldown  time.Duration // how long it stays open before a trial request
+}
+
+// DefaultOptions are the settings of Default.
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:    3,
+		BaseDelay:      250 * time.Millisecond,
+		MaxDelay:       10 * time.Second,
+		AttemptTimeout: 10 * time.Second,
+		RateLimit:      Limit{Rate: 50, Burst: 50},
+		HostLimits: map[string]Limit{
+			// The website proper throttles scrapers much harder than the APIs.
+			"news.ycombinator.com": {Rate: 1, Burst: 4},
+		},
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// Default is the transport shared by the API client and the login session,
+// so they draw on the same rate limits and breakers.
+var Default = New(DefaultOptions())
+
+// Transport is an http.RoundTripper that retries, rate-limits and trips a
+// circuit breaker per host. Only idempotent requests (GET and HEAD) are
+// retried.
+type Transport struct {
+	base http.RoundTripper
+	opts Options
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host is the rate limit and breaker state for one host.
+type host struct {
+	bucket  bucket
+	breaker breaker
+}
+
+// New returns a Transport over http.DefaultTransport.
+func New(opts Options) *Transport {
+	return NewWithBase(http.DefaultTransport, opts)
+}
+
+// NewWithBase returns a Transport that sends requests through base.
+func NewWithBase(base http.RoundTripper, opts Options) *Transport {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	return &Transport{base: base, opts: opts, hosts: make(map[string]*host)}
+}
+
+type noRetryKey struct{}
+
+// WithoutRetries marks requests made with ctx to be tried only once, for
+// probes that should report the network's state as it is.
+func WithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.host(req.URL.Hostname())
+	if err := h.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := t.send(h, req)
+	if req.Context().Err() != nil {
+		// Cancelled by the caller; says nothing about the host.
+		h.breaker.abandon()
+	} else {
+		h.breaker.record(err != nil || retryStatus(resp.StatusCode))
+	}
+	return resp, err
+}
+
+// send tries req until it succeeds, fails for good or runs out of
+// attempts, backing off in between.
+func (t *Transport) send(h *host, req *http.Request) (*http.Response, error) {
+	attempts := t.opts.MaxAttempts
+	if !retryable(req) {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		if err := h.bucket.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := t.try(req)
+		if err == nil && !retryStatus(resp.StatusCode) || attempt >= attempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > t.opts.MaxDelay {
+					// Not worth waiting for; let the caller see the 429/503.
+					return resp, nil
+				}
+				delay = max(delay, after)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// try makes one attempt, bounded by AttemptTimeout until the body is
+// closed.
+func (t *Transport) try(req *http.Request) (*http.Response, error) {
+	if t.opts.AttemptTimeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.opts.AttemptTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases an attempt's timeout once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (t *Transport) host(name string) *host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[name]
+	if !ok {
+		limit, ok := t.opts.HostLimits[name]
+		if !ok {
+			limit = t.opts.RateLimit
+		}
+		h = &host{
+			bucket:  newBucket(limit),
+			breaker: breaker{threshold: t.opts.BreakerThreshold, cooldown: t.opts.BreakerCooldown},
+		}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// backoff is the delay before retry number attempt: exponential, with
+// half of it jittered so concurrent requests don't retry in lockstep.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.opts.BaseDelay << (attempt - 1)
+	if d <= 0 || d > t.opts.MaxDelay {
+		d = t.opts.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func retryable(req *http.Request) bool {
+	if req.Context().Value(noRetryKey{}) != nil {
+		return false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryStatus reports whether a response status is worth retrying.
+func retryStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// ErrCircuitOpen is returned without sending the request while a host's
+// circuit breaker is open after repeated failures.
+var ErrCircuitOpen = errors.New("too many recent failures; backing off")
@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testOptions retries quickly, with no rate limit or breaker unless a
+// test sets one.
+func testOptions() Options {
+	return Options{
+		MaxAttempts:    3,
+		BaseDelay:      time.Millisecond,
+		MaxDelay:       2 * time.Second,
+		AttemptTimeout: 5 * time.Second,
+	}
+}
+
+// flaky answers the first fail requests with status, then 200.
+func flaky(fail int, status int, header http.Header) (*httptest.Server, *atomic.Int64) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) <= int64(fail) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return srv, &n
+}
+
+func do(t *testing.T, rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	t.Helper()
+	resp, err := rt.RoundTrip(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      func(url string) *http.Request
+		fail     int
+		attempts int64
+		status   int
+	}{
+		{"GET retried", func(url string) *http.Request {
+			req, _ := http.NewRequest("GET", url, nil)
+			return req
+		}, 2, 3, 200},
+		{"HEAD retried", func(url string) *http.Request {
+			req, _ := http.NewRequest("HEAD", url, nil)
+			return req
+		}, 1, 2, 200},
+		{"GET gives up", func(url string) *http.Request {
+			req, _ := http.NewRequest("GET", url, nil)
+			return req
+		}, 10, 3, 503},
+		{"POST not retried", func(url string) *http.Request {
+			req, _ := http.NewRequest("POST", url, strings.NewReader("text=hi"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}, 1, 1, 503},
+		{"probe not retried", func(url string) *http.Request {
+			req, _ := http.NewRequestWithContext(WithoutRetries(context.Background()), "GET", url, nil)
+			return req
+		}, 1, 1, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, n := flaky(tt.fail, http.StatusServiceUnavailable, nil)
+			defer srv.Close()
+			resp, err := do(t, New(testOptions()), tt.req(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || n.Load() != tt.attempts {
+				t.Errorf("status %d after %d attempts, want %d after %d", resp.StatusCode, n.Load(), tt.status, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	// Honoured: the retry waits the second asked for, not the backoff.
+	srv, n := flaky(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	defer srv.Close()
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	start := time.Now()
+	resp, err := do(t, New(testOptions()), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || n.Load() != 2 {
+		t.Errorf("status %d after %d attempts, want 200 after 2", resp.StatusCode, n.Load())
+	}
+	if took := time.Since(start); took < time.Second {
+		t.Errorf("retried after %s, before Retry-After", took)
+	}
+
+	// Longer than MaxDelay: not worth waiting for, so the 429 is returned
+	// at once.
+	srv, n = flaky(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"120"}})
+	defer srv.Close()
+	req, _ = http.NewRequest("GET", srv.URL, nil)
+	start = time.Now()
+	resp, err = do(t, New(testOptions()), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests || n.Load() != 1 {
+		t.Errorf("status %d after %d attempts, want 429 after 1", resp.StatusCode, n.Load())
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("waited %s for a Retry-After past MaxDelay", took)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		v    string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(&http.Response{Header: http.Header{"Retry-After": {tt.v}}})
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %v; want %s, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := retryAfter(&http.Response{Header: http.Header{"Retry-After": {future}}}); !ok || got < 58*time.Second || got > time.Minute {
+		t.Errorf("retryAfter(%q) = %s, %v; want about a minute", future, got, ok)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tr := New(Options{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond})
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 40: 300 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if d := tr.backoff(attempt); d < want/2 || d > want {
+				t.Errorf("backoff(%d) = %s, want %s to %s", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	b := breaker{threshold: 2, cooldown: 50 * time.Millisecond}
+	b.record(true)
+	if err := b.allow(); err != nil {
+		t.Fatalf("open after one failure: %v", err)
+	}
+	b.record(true)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after %d failures = %v, want ErrCircuitOpen", b.threshold, err)
+	}
+
+	// Half-open: one trial at a time; a failed trial opens it again.
+	time.Sleep(60 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("no trial after the cooldown: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during the trial = %v, want ErrCircuitOpen", err)
+	}
+	b.record(true)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after a failed trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// An abandoned trial frees the slot; a successful one closes it.
+	time.Sleep(60 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.abandon()
+	if err := b.allow(); err != nil {
+		t.Fatalf("no trial after an abandoned one: %v", err)
+	}
+	b.record(false)
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("closed breaker refused a request: %v", err)
+		}
+	}
+	b.record(true)
+	if err := b.allow(); err != nil {
+		t.Errorf("a success didn't reset the failure count: %v", err)
+	}
+}
+
+func TestBreakerPerHost(t *testing.T) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		if strings.HasPrefix(r.Host, "127.0.0.1") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	opts := testOptions()
+	opts.MaxAttempts = 1
+	opts.BreakerThreshold = 2
+	opts.BreakerCooldown = time.Minute
+	tr := New(opts)
+
+	get := func(url string) (*http.Response, error) {
+		req, _ := http.NewRequest("GET", url, nil)
+		return do(t, tr, req)
+	}
+	for i := 0; i < 2; i++ {
+		if resp, err := get(srv.URL); err != nil || resp.StatusCode != 500 {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if _, err := get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("after two 500s: err = %v, want ErrCircuitOpen", err)
+	}
+	if n.Load() != 2 {
+		t.Errorf("server saw %d requests, want the open breaker to stop the third", n.Load())
+	}
+
+	// The same server under another name is another host.
+	other := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	if resp, err := get(other); err != nil || resp.StatusCode != 200 {
+		t.Errorf("other host: %v", err)
+	}
+}
+
+// roundTripFunc counts requests without a network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestHostRateLimits(t *testing.T) {
+	var n atomic.Int64
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n.Add(1)
+		return &http.Response{StatusCode: 200, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewWithBase(base, DefaultOptions())
+	get := func(ctx context.Context, url string) error {
+		req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+		_, err := tr.RoundTrip(req)
+		return err
+	}
+
+	// The website: a burst of four, then one a second.
+	hn := DefaultOptions().HostLimits["news.ycombinator.com"]
+	if hn.Rate != 1 || hn.Burst != 4 {
+		t.Errorf("news.ycombinator.com limit = %+v, want 1/s in bursts of 4", hn)
+	}
+	start := time.Now()
+	for i := 0; i < hn.Burst; i++ {
+		if err := get(context.Background(), "https://news.ycombinator.com/item?id=1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if took := time.Since(start); took > 200*time.Millisecond {
+		t.Errorf("burst took %s", took)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := get(ctx, "https://news.ycombinator.com/item?id=1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("request past the burst: err = %v, want it held back", err)
+	}
+	if n.Load() != int64(hn.Burst) {
+		t.Errorf("%d requests sent, want %d", n.Load(), hn.Burst)
+	}
+
+	// The APIs aren't held back by the website's limit.
+	start = time.Now()
+	for i := 0; i < 20; i++ {
+		if err := get(context.Background(), "https://hacker-news.firebaseio.com/v0/item/1.json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if took := time.Since(start); took > 200*time.Millisecond {
+		t.Errorf("20 Firebase requests took %s", took)
+	}
+}
+
+func TestBucketRefills(t *testing.T) {
+	b := newBucket(Limit{Rate: 1, Burst: 4})
+	b.tokens = 0
+	b.last = time.Now().Add(-1100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); err != nil {
+		t.Errorf("no token a second after the last: %v", err)
+	}
+	if err := b.wait(ctx); err == nil {
+		t.Error("a second token within the same second")
+	}
+
+	b = newBucket(Limit{Rate: 1, Burst: 4})
+	b.last = time.Now().Add(-time.Hour)
+	for i := 0; i < 4; i++ {
+		b.wait(context.Background())
+	}
+	if b.tokens > 1 {
+		t.Errorf("an idle hour saved up %.0f more tokens than the burst", b.tokens)
+	}
+}
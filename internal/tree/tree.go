@@ -0,0 +1,119 @@
+// Package tree loads HN comment trees into the cache one level at a time:
+// the replies found on one level are the next level to load. The story
+// view, export and sync all load threads this way.
+package tree
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+)
+
+// defaultConcurrency matches api.Client.BatchGetItems.
+const defaultConcurrency = 10
+
+// Loader loads items through the cache, fetching the ones it can't reuse.
+type Loader struct {
+	Client *api.Client
+	DB     *cache.DB
+	TTL    time.Duration // how long a cached item stays fresh
+
+	// Reuse reports whether a cached item will do without a request. The
+	// default reuses fresh items.
+	Reuse func(item *api.Item, fresh bool) bool
+	// Concurrency bounds parallel requests; 0 means defaultConcurrency.
+	Concurrency int
+	// Check, if set, is called before each request. An error stops the
+	// load and is returned, e.g. when a byte budget runs out.
+	Check func() error
+	// Fetched, if set, is called with each item fetched and stored.
+	Fetched func(item *api.Item)
+}
+
+// Level loads ids, returning the item for each in order: reused from the
+// cache, or fetched and stored. An item that fails to fetch keeps its
+// stale cached copy, if any, and is nil otherwise, as are items HN doesn't
+// have. The error is Check's or ctx's; the items loaded before it are
+// still returned.
+func (l *Loader) Level(ctx context.Context, ids []int) ([]*api.Item, error) {
+	items := make([]*api.Item, len(ids))
+	var fetch []int // indexes into ids
+	for i, id := range ids {
+		cached, fresh, _ := l.DB.GetItem(id, l.TTL)
+		items[i] = cached
+		if cached == nil || !l.reuse(cached, fresh) {
+			fetch = append(fetch, i)
+		}
+	}
+	if len(fetch) == 0 {
+		return items, ctx.Err()
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(l.concurrency())
+	for _, i := range fetch {
+		i := i
+		g.Go(func() error {
+			if l.Check != nil {
+				if err := l.Check(); err != nil {
+					return err
+				}
+			}
+			item, err := l.Client.GetItem(gctx, ids[i])
+			if err != nil || item == nil || item.ID == 0 {
+				return nil
+			}
+			l.DB.PutItem(item)
+			if l.Fetched != nil {
+				l.Fetched(item)
+			}
+			items[i] = item
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return items, err
+	}
+	return items, ctx.Err()
+}
+
+// Walk loads ids and every reply below them, level by level, and returns
+// how many items it loaded. Items that can't be loaded are skipped along
+// with their replies.
+func (l *Loader) Walk(ctx context.Context, ids []int) (int, error) {
+	loaded := 0
+	for len(ids) > 0 {
+		items, err := l.Level(ctx, ids)
+		var next []int
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			loaded++
+			next = append(next, item.Kids()...)
+		}
+		if err != nil {
+			return loaded, err
+		}
+		ids = next
+	}
+	return loaded, nil
+}
+
+func (l *Loader) reuse(item *api.Item, fresh bool) bool {
+	if l.Reuse == nil {
+		return fresh
+	}
+	return l.Reuse(item, fresh)
+}
+
+func (l *Loader) concurrency() int {
+	if l.Concurrency < 1 {
+		return defaultConcurrency
+	}
+	return l.Concurrency
+}
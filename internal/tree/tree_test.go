@@ -0,0 +1,147 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+)
+
+// thread is a story with three replies, the second of which HN no longer
+// has, and a reply under each of the others.
+var thread = map[string]string{
+	"/item/1.json": `{"id":1,"type":"story","title":"Story","kids":[2,3,5]}`,
+	"/item/2.json": `{"id":2,"type":"comment","parent":1,"text":"a","kids":[4]}`,
+	"/item/4.json": `{"id":4,"type":"comment","parent":2,"text":"b"}`,
+	"/item/5.json": `{"id":5,"type":"comment","parent":1,"text":"c","kids":[6]}`,
+	"/item/6.json": `{"id":6,"type":"comment","parent":5,"text":"d"}`,
+}
+
+// fakeFirebase serves thread, answering null for anything else, or 500
+// for everything while down is set.
+type fakeFirebase struct {
+	*httptest.Server
+	requests atomic.Int64
+	down     atomic.Bool
+}
+
+func newFake(t *testing.T) *fakeFirebase {
+	f := &fakeFirebase{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		if f.down.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, ok := thread[r.URL.Path]
+		if !ok {
+			body = "null"
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newLoader(t *testing.T, f *fakeFirebase) *Loader {
+	db, err := cache.Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	client := api.NewClient(api.Options{Endpoints: api.Endpoints{Firebase: f.URL}, Transport: http.DefaultTransport})
+	return &Loader{Client: client, DB: db, TTL: time.Hour}
+}
+
+func TestLevel(t *testing.T) {
+	f := newFake(t)
+	l := newLoader(t, f)
+	items, err := l.Level(context.Background(), []int{5, 3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, item := range items {
+		if item == nil {
+			ids = append(ids, 0)
+			continue
+		}
+		ids = append(ids, item.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{5, 0, 2}) {
+		t.Errorf("Level returned items %v, want [5 0 2] with the missing one nil", ids)
+	}
+	if item, _, _ := l.DB.GetItem(0, 0); item != nil {
+		t.Errorf("cache has an empty item: %+v", item)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	f := newFake(t)
+	l := newLoader(t, f)
+	var fetched atomic.Int64
+	l.Fetched = func(*api.Item) { fetched.Add(1) }
+	ctx := context.Background()
+
+	n, err := l.Walk(ctx, []int{2, 3, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 || fetched.Load() != 4 || f.requests.Load() != 5 {
+		t.Errorf("loaded %d, fetched %d in %d requests; want 4, 4 in 5", n, fetched.Load(), f.requests.Load())
+	}
+	for _, id := range []int{2, 4, 5, 6} {
+		if item, fresh, _ := l.DB.GetItem(id, l.TTL); item == nil || !fresh {
+			t.Errorf("item %d not cached", id)
+		}
+	}
+
+	// Fresh items come from the cache.
+	f.requests.Store(0)
+	if n, err := l.Walk(ctx, []int{2, 3, 5}); err != nil || n != 4 || f.requests.Load() != 1 {
+		t.Errorf("second walk: loaded %d (%v) in %d requests; want 4 in 1, for the missing item", n, err, f.requests.Load())
+	}
+
+	// Refetched items that fail keep their stale copies.
+	f.down.Store(true)
+	l.Reuse = func(*api.Item, bool) bool { return false }
+	if n, err := l.Walk(ctx, []int{2, 3, 5}); err != nil || n != 4 {
+		t.Errorf("walk with the server down: loaded %d (%v), want the 4 cached", n, err)
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	f := newFake(t)
+	l := newLoader(t, f)
+	l.Concurrency = 1
+	errStop := errors.New("stop")
+	var checks atomic.Int64
+	l.Check = func() error {
+		if checks.Add(1) > 2 {
+			return errStop
+		}
+		return nil
+	}
+	n, err := l.Walk(context.Background(), []int{2, 3, 5})
+	if !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want Check's", err)
+	}
+	if f.requests.Load() != 2 || n != 1 {
+		t.Errorf("loaded %d in %d requests after Check said stop, want 1 in 2", n, f.requests.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Check = nil
+	if _, err := l.Walk(ctx, []int{2}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled walk: err = %v", err)
+	}
+}
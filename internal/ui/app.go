@@ -1,10 +1,15 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"os/exec"
 	"runtime"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
@@ -13,12 +18,16 @@ import (
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/config"
 	"github.com/fragmede/nitpick/internal/monitor"
+	"github.com/fragmede/nitpick/internal/syncer"
+	"github.com/fragmede/nitpick/internal/ui/bookmarks"
 	"github.com/fragmede/nitpick/internal/ui/commentfeed"
 	"github.com/fragmede/nitpick/internal/ui/edit"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/login"
 	"github.com/fragmede/nitpick/internal/ui/messages"
 	"github.com/fragmede/nitpick/internal/ui/notifications"
 	"github.com/fragmede/nitpick/internal/ui/reply"
+	"github.com/fragmede/nitpick/internal/ui/search"
 	"github.com/fragmede/nitpick/internal/ui/statusbar"
 	"github.com/fragmede/nitpick/internal/ui/storylist"
 	"github.com/fragmede/nitpick/internal/ui/storyview"
@@ -39,6 +48,8 @@ const (
 	ViewSubmit
 	ViewNotifications
 	ViewUserProfile
+	ViewSearch
+	ViewBookmarks
 )
 
 // App is the root Bubble Tea model.
@@ -57,6 +68,8 @@ type App struct {
 	submitForm    submit.Model
 	notifications notifications.Model
 	userProfile   userprofile.Model
+	search        search.Model
+	bookmarks     bookmarks.Model
 	statusBar     statusbar.Model
 
 	// Storyview cache: preserves collapse/scroll/selection state.
@@ -70,28 +83,42 @@ type App struct {
 	monitor     *monitor.Monitor
 	unreadCount int
 
+	// Saved search names, shown as extra tabs after tabOrder.
+	savedSearches []string
+
 	// Status message auto-clear
 	statusSeq int
 
+	// Help overlay for the active view, closed by the next key.
+	showHelp bool
+
 	// Dimensions
 	width  int
 	height int
 
 	// For passing program reference to monitor
 	program *tea.Program
+
+	// Cancels background goroutines (connectivity probe, sync) on quit.
+	stopBackground context.CancelFunc
+
+	// Set once the cache prune has been started after the first load.
+	pruneStarted bool
 }
 
 // NewApp creates the root application model.
 func NewApp(cfg config.Config, client *api.Client, db *cache.DB) *App {
-	session := auth.NewSession()
+	session := auth.NewSession(auth.Options{BaseURL: client.Endpoints().HN, Transport: client.Transport()})
 	mon := monitor.New(cfg, client, db)
 
-	return &App{
+	a := &App{
 		activeView:     ViewStoryList,
 		storyList:      storylist.New(cfg, client, db),
 		commentFeed:    commentfeed.New(cfg, client),
 		statusBar:      statusbar.New(),
-		notifications:  notifications.New(db),
+		notifications:  notifications.New(client, db),
+		search:         search.New(cfg, client, db),
+		bookmarks:      bookmarks.New(cfg, client, db, session),
 		storyViewCache: make(map[int]storyview.Model),
 		cfg:            cfg,
 		client:         client,
@@ -99,11 +126,75 @@ func NewApp(cfg config.Config, client *api.Client, db *cache.DB) *App {
 		session:        session,
 		monitor:        mon,
 	}
+	a.reloadSavedSearches()
+	return a
+}
+
+// reloadSavedSearches refreshes the saved search tabs from the cache.
+func (a *App) reloadSavedSearches() {
+	saved, _ := a.cache.ListSavedSearches()
+	a.savedSearches = a.savedSearches[:0]
+	for _, s := range saved {
+		a.savedSearches = append(a.savedSearches, s.Name)
+	}
+	a.statusBar.SetSavedSearches(a.savedSearches)
 }
 
 // SetProgram stores the tea.Program reference for the background monitor.
 func (a *App) SetProgram(p *tea.Program) {
 	a.program = p
+	a.monitor.StartAlerts(p)
+	a.client.OnConnectivityChange(func(offline bool) {
+		p.Send(messages.ConnectivityMsg{Offline: offline})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopBackground = cancel
+	go a.client.WatchConnectivity(ctx, a.cfg.ProbeInterval)
+	if a.cfg.SyncInterval > 0 {
+		s := syncer.New(a.client, a.cache, a.cfg, syncer.OptionsFromConfig(a.cfg))
+		go s.RunEvery(ctx, a.cfg.SyncInterval,
+			func(prog syncer.Progress) {
+				p.Send(messages.SyncProgressMsg{Text: "Syncing " + prog.String()})
+			},
+			func(prog syncer.Progress, err error) {
+				p.Send(messages.SyncDoneMsg{Items: prog.Items, Bytes: syncer.FormatBytes(prog.Bytes), Err: err})
+			})
+	}
+}
+
+// shutdown stops background work before quitting.
+func (a *App) shutdown() {
+	a.monitor.Stop()
+	if a.stopBackground != nil {
+		a.stopBackground()
+	}
+}
+
+// pruneCache applies the configured retention policy, pinning the
+// logged-in user's own items; see cache.RetentionPolicy.
+func (a *App) pruneCache() tea.Cmd {
+	if a.cfg.CacheMaxAge == 0 && a.cfg.CacheMaxSize == 0 {
+		return nil
+	}
+	cfg, db := a.cfg, a.cache
+	// The session may still be being restored, so read the user from disk.
+	session := auth.NewSession(auth.Options{BaseURL: a.client.Endpoints().HN, Transport: a.client.Transport()})
+	return func() tea.Msg {
+		session.Load(cfg.SessionPath)
+		res, err := db.Prune(cache.RetentionPolicy{
+			MaxAge:  cfg.CacheMaxAge,
+			MaxSize: cfg.CacheMaxSize,
+			PinUser: session.Username,
+		})
+		if err != nil {
+			log.Printf("pruning cache: %v", err)
+			return nil
+		}
+		log.Printf("pruned cache: %d items, %d users, %d lists, %d notifications, %d visits; %d -> %d bytes in use",
+			res.Items, res.Users, res.StoryLists, res.Notifications, res.Visits, res.UsedBefore, res.UsedAfter)
+		return nil
+	}
 }
 
 // Init starts the application.
@@ -134,6 +225,8 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Always resize persistent views.
 		a.storyList.SetSize(msg.Width, contentHeight)
 		a.commentFeed.SetSize(msg.Width, contentHeight)
+		a.search.SetSize(msg.Width, contentHeight)
+		a.bookmarks.SetSize(msg.Width, contentHeight)
 		a.statusBar.SetSize(msg.Width)
 		// Only resize lazily-created views if they're currently active.
 		switch a.activeView {
@@ -155,61 +248,79 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, nil
 
 	case tea.KeyMsg:
+		km := keys.Keys.Global
+		if a.showHelp {
+			// Any key closes the help overlay.
+			a.showHelp = false
+			if key.Matches(msg, km.ForceQuit) {
+				a.shutdown()
+				return a, tea.Quit
+			}
+			return a, nil
+		}
 		// Global keys (only when not in text input views).
-		if a.activeView != ViewLogin && a.activeView != ViewReply && a.activeView != ViewEdit && a.activeView != ViewSubmit {
-			switch msg.String() {
-			case "ctrl+c":
-				a.monitor.Stop()
+		if !a.inTextInput() {
+			switch {
+			case key.Matches(msg, km.ForceQuit):
+				a.shutdown()
 				return a, tea.Quit
-			case "q":
-				if a.activeView == ViewStoryList || a.activeView == ViewCommentFeed {
-					a.monitor.Stop()
+			case key.Matches(msg, km.Quit):
+				if a.isTabView() {
+					a.shutdown()
 					return a, tea.Quit
 				}
 				return a, a.goBackToRoot()
-			case "esc":
+			case key.Matches(msg, km.Back):
 				if len(a.previousViews) > 0 {
 					return a, a.goBack()
 				}
-				if a.activeView != ViewStoryList && a.activeView != ViewCommentFeed {
+				if !a.isTabView() {
 					a.activeView = ViewStoryList
 					return a, nil
 				}
-			case "?":
-				// TODO: help overlay
-				return a, nil
-			case "tab":
+			case key.Matches(msg, km.Help):
+				return a, func() tea.Msg { return messages.ShowHelpMsg{} }
+			case key.Matches(msg, km.NextTab):
 				return a, a.nextTab()
-			case "shift+tab":
+			case key.Matches(msg, km.PrevTab):
 				return a, a.prevTab()
-			case "1":
+			case key.Matches(msg, km.Top):
 				return a, a.switchTab(api.StoryTypeTop)
-			case "2":
+			case key.Matches(msg, km.New):
 				return a, a.switchTab(api.StoryTypeNew)
-			case "3":
+			case key.Matches(msg, km.Threads):
 				return a, a.switchTab(api.StoryTypeThreads)
-			case "4":
+			case key.Matches(msg, km.Past):
 				return a, a.switchTab(api.StoryTypePast)
-			case "5":
+			case key.Matches(msg, km.Comments):
 				return a, a.switchTab(api.StoryTypeComments)
-			case "6":
+			case key.Matches(msg, km.Ask):
 				return a, a.switchTab(api.StoryTypeAsk)
-			case "7":
+			case key.Matches(msg, km.Show):
 				return a, a.switchTab(api.StoryTypeShow)
-			case "8":
+			case key.Matches(msg, km.Jobs):
 				return a, a.switchTab(api.StoryTypeJobs)
-			case "L":
+			case key.Matches(msg, km.SavedSearch):
+				return a, a.nextSavedSearchTab()
+			case key.Matches(msg, km.Bookmarks):
+				return a, a.switchTab(api.StoryTypeBookmarks)
+			case key.Matches(msg, km.Login):
 				if !a.session.LoggedIn {
 					a.pushView(ViewLogin)
 					a.loginForm = login.New(a.session)
 					a.loginForm.SetSize(a.width, a.height-1)
 				}
 				return a, nil
-			case "n":
+			case key.Matches(msg, km.Notifications):
 				a.pushView(ViewNotifications)
 				a.notifications.Load()
 				return a, nil
-			case "s":
+			case key.Matches(msg, km.Search):
+				if a.activeView != ViewSearch {
+					a.pushView(ViewSearch)
+				}
+				return a, a.search.Focus()
+			case key.Matches(msg, km.Submit):
 				if !a.session.LoggedIn {
 					a.pushView(ViewLogin)
 					a.loginForm = login.New(a.session)
@@ -222,23 +333,33 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, nil
 			}
 		} else {
-			// Esc in text input views goes back.
-			if msg.String() == "esc" {
-				return a, a.goBack()
+			// Cancel in the forms goes back. Views with a prompt of their
+			// own (search, the story list filter, bookmark tags, poll
+			// votes) handle it themselves to close just the prompt.
+			switch a.activeView {
+			case ViewLogin, ViewReply, ViewEdit, ViewSubmit:
+				if key.Matches(msg, keys.Keys.Form.Cancel) {
+					return a, a.goBack()
+				}
 			}
-			if msg.String() == "ctrl+c" {
-				a.monitor.Stop()
+			if key.Matches(msg, km.ForceQuit) {
+				a.shutdown()
 				return a, tea.Quit
 			}
 		}
 
+	case messages.ShowHelpMsg:
+		a.showHelp = true
+		return a, nil
+
 	// View transitions.
 	case messages.OpenStoryMsg:
 		a.pushView(ViewStoryDetail)
 		if cached, ok := a.storyViewCache[msg.StoryID]; ok {
-			a.storyView = cached
+			var cmd tea.Cmd
+			a.storyView, cmd = cached.Resume()
 			a.storyView.SetSize(a.width, a.height-1)
-			return a, nil
+			return a, cmd
 		}
 		a.storyView = storyview.New(msg.StoryID, a.cfg, a.client, a.cache, a.session.Username)
 		a.storyView.SetSize(a.width, a.height-1)
@@ -247,6 +368,19 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.GoBackMsg:
 		return a, a.goBack()
 
+	case messages.CommentsBatchMsg:
+		// Comments keep loading under a reply form, or after going back
+		// with the story view cached.
+		if story := a.storyView.Story(); story == nil || story.ID != msg.StoryID {
+			return a, nil
+		}
+		var cmd tea.Cmd
+		a.storyView, cmd = a.storyView.Update(msg)
+		if _, ok := a.storyViewCache[msg.StoryID]; ok {
+			a.storyViewCache[msg.StoryID] = a.storyView
+		}
+		return a, cmd
+
 	case messages.OpenReplyMsg:
 		if !a.session.LoggedIn {
 			a.pushView(ViewLogin)
@@ -322,6 +456,24 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, a.goBack()
 		}
 
+	case messages.VoteMsg:
+		if !a.session.LoggedIn {
+			a.pushView(ViewLogin)
+			a.loginForm = login.New(a.session)
+			a.loginForm.SetSize(a.width, a.height-1)
+			return a, nil
+		}
+		session := a.session
+		return a, func() tea.Msg {
+			var err error
+			if msg.Poll != 0 {
+				err = session.VotePollOption(msg.Poll, msg.ItemID)
+			} else {
+				err = session.Vote(msg.ItemID)
+			}
+			return messages.VoteResultMsg{ItemID: msg.ItemID, Err: err}
+		}
+
 	case messages.VoteResultMsg:
 		if msg.Err != nil {
 			a.statusBar.SetStatus("Vote failed: " + msg.Err.Error())
@@ -329,6 +481,104 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.statusBar.SetStatus("Voted!")
 		}
 
+	case messages.SaveSearchMsg:
+		err := a.cache.PutSavedSearch(cache.SavedSearch{
+			Name: msg.Name, Query: msg.Query, Tags: msg.Tags, ByDate: msg.ByDate,
+		})
+		if err != nil {
+			a.statusBar.SetStatus("Save failed: " + err.Error())
+			return a, nil
+		}
+		a.reloadSavedSearches()
+		a.statusBar.SetStatus("Saved tab " + msg.Name)
+		return a, nil
+
+	case messages.ToggleAlertMsg:
+		on, err := a.cache.ToggleAlert(msg.Kind, msg.Pattern)
+		switch {
+		case err != nil:
+			a.statusBar.SetStatus("Alert failed: " + err.Error())
+		case on:
+			a.statusBar.SetStatus("Watching " + msg.Pattern)
+		default:
+			a.statusBar.SetStatus("Stopped watching " + msg.Pattern)
+		}
+		return a, nil
+
+	case messages.ToggleBookmarkMsg:
+		on, err := a.cache.ToggleBookmark(msg.Item)
+		if err != nil {
+			a.statusBar.SetStatus("Bookmark failed: " + err.Error())
+			return a, nil
+		}
+		a.bookmarks.Load()
+		if on {
+			a.statusBar.SetStatus("Bookmarked")
+		} else {
+			a.statusBar.SetStatus("Removed bookmark")
+		}
+		if !a.cfg.SyncFavorites || !a.session.LoggedIn {
+			return a, nil
+		}
+		session, id := a.session, msg.Item.ID
+		return a, func() tea.Msg {
+			if err := session.Fave(id, !on); err != nil {
+				return messages.StatusMsg{Text: "HN favorite sync failed: " + err.Error(), IsError: true}
+			}
+			return nil
+		}
+
+	case messages.StoryHiddenMsg:
+		a.statusBar.SetStatus(fmt.Sprintf("Hidden; run nitpick killfile unhide %d to undo", msg.ID))
+		if !a.cfg.SyncHidden || !a.session.LoggedIn {
+			return a, nil
+		}
+		session, id := a.session, msg.ID
+		return a, func() tea.Msg {
+			if err := session.Hide(id, false); err != nil {
+				return messages.StatusMsg{Text: "HN hide sync failed: " + err.Error(), IsError: true}
+			}
+			return nil
+		}
+
+	case messages.DeleteSearchMsg:
+		a.cache.DeleteSavedSearch(msg.Name)
+		a.reloadSavedSearches()
+		a.statusBar.SetStatus("Removed tab " + msg.Name)
+		return a, a.switchTab(api.StoryTypeTop)
+
+	case messages.ConnectivityMsg:
+		a.statusBar.SetOffline(msg.Offline)
+		if msg.Offline {
+			return a, func() tea.Msg {
+				return messages.StatusMsg{Text: "Network unreachable, showing cached data", IsError: true}
+			}
+		}
+		return a, tea.Batch(a.refreshActiveView(), func() tea.Msg {
+			return messages.StatusMsg{Text: "Back online"}
+		})
+
+	case messages.SyncProgressMsg:
+		a.statusBar.SetStatus(msg.Text)
+		return a, nil
+
+	case messages.SyncDoneMsg:
+		text := fmt.Sprintf("Sync done: %d items, %s", msg.Items, msg.Bytes)
+		if errors.Is(msg.Err, syncer.ErrBudget) {
+			text = fmt.Sprintf("Sync stopped at byte budget: %d items, %s", msg.Items, msg.Bytes)
+		} else if msg.Err != nil {
+			text = "Sync failed: " + msg.Err.Error()
+		}
+		return a, func() tea.Msg { return messages.StatusMsg{Text: text, IsError: msg.Err != nil} }
+
+	case messages.StoriesLoadedMsg:
+		// Trim the cache once the first screen is up, so pruning doesn't
+		// hold the database while the UI is loading it.
+		if !a.pruneStarted {
+			a.pruneStarted = true
+			cmds = append(cmds, a.pruneCache())
+		}
+
 	case messages.NewNotificationMsg:
 		a.unreadCount = msg.UnreadCount
 		a.statusBar.SetUnread(msg.UnreadCount)
@@ -386,6 +636,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ViewUserProfile:
 		a.userProfile, cmd = a.userProfile.Update(msg)
 		cmds = append(cmds, cmd)
+	case ViewSearch:
+		a.search, cmd = a.search.Update(msg)
+		cmds = append(cmds, cmd)
+	case ViewBookmarks:
+		a.bookmarks, cmd = a.bookmarks.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	a.statusBar, cmd = a.statusBar.Update(msg)
@@ -416,11 +672,46 @@ func (a *App) View() string {
 		content = a.notifications.View()
 	case ViewUserProfile:
 		content = a.userProfile.View()
+	case ViewSearch:
+		content = a.search.View()
+	case ViewBookmarks:
+		content = a.bookmarks.View()
+	}
+	if a.showHelp {
+		content = a.helpView()
 	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, content, a.statusBar.View())
 }
 
+// inTextInput reports whether the active view is capturing raw keystrokes,
+// in which case global single-letter bindings must not fire.
+func (a *App) inTextInput() bool {
+	switch a.activeView {
+	case ViewLogin, ViewReply, ViewEdit, ViewSubmit:
+		return true
+	case ViewStoryList:
+		return a.storyList.Filtering() || a.storyList.Removing()
+	case ViewSearch:
+		return a.search.Typing()
+	case ViewStoryDetail:
+		return a.storyView.Choosing()
+	case ViewBookmarks:
+		return a.bookmarks.Typing()
+	}
+	return false
+}
+
+// isTabView reports whether the active view is a tab rather than a view
+// pushed on top of one.
+func (a *App) isTabView() bool {
+	switch a.activeView {
+	case ViewStoryList, ViewCommentFeed, ViewBookmarks:
+		return true
+	}
+	return false
+}
+
 func (a *App) pushView(v ViewType) {
 	a.previousViews = append(a.previousViews, a.activeView)
 	a.activeView = v
@@ -467,36 +758,68 @@ func (a *App) cacheStoryView() {
 var tabOrder = []api.StoryType{
 	api.StoryTypeTop, api.StoryTypeNew, api.StoryTypeThreads,
 	api.StoryTypePast, api.StoryTypeComments, api.StoryTypeAsk,
-	api.StoryTypeShow, api.StoryTypeJobs,
+	api.StoryTypeShow, api.StoryTypeJobs, api.StoryTypeBookmarks,
 }
 
 func (a *App) currentTab() api.StoryType {
 	if a.activeView == ViewCommentFeed {
 		return a.commentFeed.FeedType()
 	}
+	if a.activeView == ViewBookmarks {
+		return api.StoryTypeBookmarks
+	}
 	return a.storyList.StoryType()
 }
 
+// tabs returns the built-in tabs followed by the saved search tabs.
+func (a *App) tabs() []api.StoryType {
+	tabs := append([]api.StoryType(nil), tabOrder...)
+	for _, name := range a.savedSearches {
+		tabs = append(tabs, api.SavedSearchType(name))
+	}
+	return tabs
+}
+
 func (a *App) nextTab() tea.Cmd {
+	tabs := a.tabs()
 	current := a.currentTab()
-	for i, st := range tabOrder {
+	for i, st := range tabs {
 		if st == current {
-			next := tabOrder[(i+1)%len(tabOrder)]
+			next := tabs[(i+1)%len(tabs)]
 			return a.switchTab(next)
 		}
 	}
-	return a.switchTab(tabOrder[0])
+	return a.switchTab(tabs[0])
 }
 
 func (a *App) prevTab() tea.Cmd {
+	tabs := a.tabs()
 	current := a.currentTab()
-	for i, st := range tabOrder {
+	for i, st := range tabs {
 		if st == current {
-			prev := tabOrder[(i-1+len(tabOrder))%len(tabOrder)]
+			prev := tabs[(i-1+len(tabs))%len(tabs)]
 			return a.switchTab(prev)
 		}
 	}
-	return a.switchTab(tabOrder[0])
+	return a.switchTab(tabs[0])
+}
+
+// nextSavedSearchTab jumps to the first saved search tab, or the next one
+// if a saved search is already showing.
+func (a *App) nextSavedSearchTab() tea.Cmd {
+	if len(a.savedSearches) == 0 {
+		a.statusBar.SetStatus("No saved searches (S to search, w to save)")
+		return nil
+	}
+	next := 0
+	if name, ok := a.currentTab().SavedSearchName(); ok {
+		for i, s := range a.savedSearches {
+			if s == name {
+				next = (i + 1) % len(a.savedSearches)
+			}
+		}
+	}
+	return a.switchTab(api.SavedSearchType(a.savedSearches[next]))
 }
 
 func isCommentTab(st api.StoryType) bool {
@@ -513,6 +836,11 @@ func (a *App) switchTab(st api.StoryType) tea.Cmd {
 		a.commentFeed = m
 		return cmd
 	}
+	if st == api.StoryTypeBookmarks {
+		a.activeView = ViewBookmarks
+		a.bookmarks.Load()
+		return nil
+	}
 
 	a.activeView = ViewStoryList
 	m, cmd := a.storyList.Update(messages.SwitchTabMsg{StoryType: st})
@@ -520,6 +848,25 @@ func (a *App) switchTab(st api.StoryType) tea.Cmd {
 	return cmd
 }
 
+// refreshActiveView reloads the visible view after connectivity returns,
+// replacing whatever was served from the cache while offline.
+func (a *App) refreshActiveView() tea.Cmd {
+	var cmd tea.Cmd
+	switch a.activeView {
+	case ViewStoryList:
+		a.storyList, cmd = a.storyList.Refresh()
+	case ViewCommentFeed:
+		a.commentFeed, cmd = a.commentFeed.SwitchFeed(a.commentFeed.FeedType())
+	case ViewStoryDetail:
+		a.storyView, cmd = a.storyView.Refresh()
+	case ViewUserProfile:
+		cmd = a.userProfile.Init()
+	case ViewBookmarks:
+		a.bookmarks.Load()
+	}
+	return cmd
+}
+
 type clearStatusMsg struct{ seq int }
 
 func openBrowser(url string) {
@@ -0,0 +1,379 @@
+package bookmarks
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
+)
+
+var (
+	titleStyle    lipgloss.Style
+	entryStyle    lipgloss.Style
+	selectedStyle lipgloss.Style
+	storyStyle    lipgloss.Style
+	authorStyle   lipgloss.Style
+	tagStyle      lipgloss.Style
+	metaStyle     lipgloss.Style
+	noteStyle     lipgloss.Style
+)
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Padding(1, 0)
+		entryStyle = lipgloss.NewStyle().Padding(0, 1)
+		selectedStyle = lipgloss.NewStyle().Background(t.Selection).Padding(0, 1)
+		storyStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true)
+		authorStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		tagStyle = lipgloss.NewStyle().Foreground(t.Highlight)
+		metaStyle = lipgloss.NewStyle().Foreground(t.Dim)
+		noteStyle = lipgloss.NewStyle().Foreground(t.Text).Italic(true)
+	})
+}
+
+// entryLines is the height of one bookmark in the list.
+const entryLines = 3
+
+// editField is which bookmark field the prompt is editing.
+type editField int
+
+const (
+	editNone editField = iota
+	editTags
+	editNote
+)
+
+// favoritesMsg reports the result of importing HN favorites.
+type favoritesMsg struct {
+	added int
+	err   error
+}
+
+// Model is the Bookmarks tab: saved stories and comments with their tags
+// and notes.
+type Model struct {
+	bookmarks   []cache.Bookmark
+	tags        []string
+	tag         string // show only bookmarks with this tag
+	selectedIdx int
+	offset      int
+	editing     editField
+	input       textinput.Model
+	importing   bool
+	db          *cache.DB
+	client      *api.Client
+	session     *auth.Session
+	cfg         config.Config
+	width       int
+	height      int
+}
+
+// New creates the bookmarks view.
+func New(cfg config.Config, client *api.Client, db *cache.DB, session *auth.Session) Model {
+	ti := textinput.New()
+	ti.CharLimit = 500
+	return Model{input: ti, db: db, client: client, session: session, cfg: cfg}
+}
+
+// SetSize sets the view dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.Width = w - 12
+}
+
+// Load refreshes the list from the database.
+func (m *Model) Load() {
+	m.tags, _ = m.db.BookmarkTags()
+	if m.tag != "" && !contains(m.tags, m.tag) {
+		m.tag = ""
+	}
+	m.bookmarks, _ = m.db.ListBookmarks(m.tag)
+	if m.selectedIdx >= len(m.bookmarks) {
+		m.selectedIdx = max(len(m.bookmarks)-1, 0)
+	}
+	m.scrollToCursor()
+}
+
+// Typing reports whether the tag or note prompt has focus.
+func (m Model) Typing() bool {
+	return m.editing != editNone
+}
+
+// Update handles messages.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case favoritesMsg:
+		m.importing = false
+		m.Load()
+		if msg.err != nil {
+			return m, status("Importing favorites failed: "+msg.err.Error(), true)
+		}
+		return m, status(fmt.Sprintf("Imported %d HN favorites", msg.added), false)
+
+	case tea.KeyMsg:
+		if m.editing != editNone {
+			return m.updateEditing(msg)
+		}
+		km, nav := keys.Keys.Bookmarks, keys.Keys.Nav
+		switch {
+		case key.Matches(msg, nav.Down):
+			if m.selectedIdx < len(m.bookmarks)-1 {
+				m.selectedIdx++
+				m.scrollToCursor()
+			}
+		case key.Matches(msg, nav.Up):
+			if m.selectedIdx > 0 {
+				m.selectedIdx--
+				m.scrollToCursor()
+			}
+		case key.Matches(msg, nav.Home):
+			m.selectedIdx = 0
+			m.scrollToCursor()
+		case key.Matches(msg, nav.End):
+			m.selectedIdx = max(len(m.bookmarks)-1, 0)
+			m.scrollToCursor()
+		case key.Matches(msg, nav.PageDown):
+			m.selectedIdx = max(min(m.selectedIdx+m.visibleEntries(), len(m.bookmarks)-1), 0)
+			m.scrollToCursor()
+		case key.Matches(msg, nav.PageUp):
+			m.selectedIdx = max(m.selectedIdx-m.visibleEntries(), 0)
+			m.scrollToCursor()
+		case key.Matches(msg, nav.Open):
+			if b, ok := m.selected(); ok {
+				return m, func() tea.Msg { return messages.OpenStoryMsg{StoryID: b.ItemID} }
+			}
+		case key.Matches(msg, nav.Browser):
+			if b, ok := m.selected(); ok {
+				u := m.client.Endpoints().ItemURL(b.ItemID)
+				return m, status("Opening: "+u, false)
+			}
+		case key.Matches(msg, km.Tags):
+			if b, ok := m.selected(); ok {
+				return m, m.edit(editTags, "Tags: ", strings.Join(b.Tags, " "))
+			}
+		case key.Matches(msg, km.Note):
+			if b, ok := m.selected(); ok {
+				return m, m.edit(editNote, "Note: ", b.Note)
+			}
+		case key.Matches(msg, km.Remove):
+			if b, ok := m.selected(); ok {
+				item := &api.Item{ID: b.ItemID, Type: b.Type}
+				return m, func() tea.Msg { return messages.ToggleBookmarkMsg{Item: item} }
+			}
+		case key.Matches(msg, km.FilterTag):
+			m.tag = nextTag(m.tags, m.tag)
+			m.selectedIdx, m.offset = 0, 0
+			m.Load()
+		case key.Matches(msg, km.Import):
+			if !m.session.LoggedIn {
+				return m, status("Login required to import HN favorites", true)
+			}
+			if m.importing {
+				return m, nil
+			}
+			m.importing = true
+			return m, tea.Batch(status("Importing HN favorites...", false), m.importFavorites())
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateEditing(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Keys.Form.Accept):
+		b, ok := m.selected()
+		field := m.editing
+		m.editing = editNone
+		m.input.Blur()
+		if !ok {
+			return m, nil
+		}
+		var err error
+		if field == editTags {
+			err = m.db.SetBookmarkTags(b.ItemID, cache.ParseTags(m.input.Value()))
+		} else {
+			err = m.db.SetBookmarkNote(b.ItemID, strings.TrimSpace(m.input.Value()))
+		}
+		if err != nil {
+			return m, status("Saving bookmark failed: "+err.Error(), true)
+		}
+		m.Load()
+		return m, nil
+	case key.Matches(msg, keys.Keys.Form.Cancel):
+		m.editing = editNone
+		m.input.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) edit(field editField, prompt, value string) tea.Cmd {
+	m.editing = field
+	m.input.Prompt = prompt
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	return m.input.Focus()
+}
+
+// importFavorites bookmarks the user's HN favorite stories and comments
+// that aren't bookmarked yet.
+func (m Model) importFavorites() tea.Cmd {
+	session, client, db, cfg := m.session, m.client, m.db, m.cfg
+	return func() tea.Msg {
+		var ids []int
+		for _, comments := range []bool{false, true} {
+			favs, err := session.Favorites(session.Username, comments)
+			if err != nil {
+				return favoritesMsg{err: err}
+			}
+			ids = append(ids, favs...)
+		}
+		added := 0
+		for _, id := range ids {
+			if db.IsBookmarked(id) {
+				continue
+			}
+			item, _, _ := db.GetItem(id, cfg.ItemTTL)
+			if item == nil {
+				fetched, err := client.GetItem(context.Background(), id)
+				if err != nil {
+					continue
+				}
+				db.PutItem(fetched)
+				item = fetched
+			}
+			if ok, err := db.BookmarkItem(item); err == nil && ok {
+				added++
+			}
+		}
+		return favoritesMsg{added: added}
+	}
+}
+
+func (m Model) selected() (cache.Bookmark, bool) {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.bookmarks) {
+		return cache.Bookmark{}, false
+	}
+	return m.bookmarks[m.selectedIdx], true
+}
+
+func (m Model) visibleEntries() int {
+	// The padded title, filter line and hint take five lines.
+	return max((m.height-5)/entryLines, 1)
+}
+
+func (m *Model) scrollToCursor() {
+	n := m.visibleEntries()
+	if m.selectedIdx < m.offset {
+		m.offset = m.selectedIdx
+	} else if m.selectedIdx >= m.offset+n {
+		m.offset = m.selectedIdx - n + 1
+	}
+}
+
+// View renders the bookmarks list.
+func (m Model) View() string {
+	var sb strings.Builder
+
+	km := keys.Keys.Bookmarks
+	sb.WriteString(titleStyle.Render("Bookmarks"))
+	sb.WriteString("\n")
+	filter := "all tags"
+	if m.tag != "" {
+		filter = "tag: " + m.tag
+	}
+	sb.WriteString(metaStyle.Render(fmt.Sprintf("  %d bookmarks · %s", len(m.bookmarks), filter)))
+	if m.editing != editNone {
+		sb.WriteString("\n  " + m.input.View() + "\n")
+	} else {
+		sb.WriteString("\n" + metaStyle.Render("  "+keys.ShortHelp(km.Tags, km.Note, km.Remove, km.FilterTag, km.Import, keys.Keys.Global.Help)) + "\n")
+	}
+
+	if len(m.bookmarks) == 0 {
+		sb.WriteString("\n  No bookmarks yet. Press b on a story or comment to add one.\n")
+		return sb.String()
+	}
+
+	end := min(m.offset+m.visibleEntries(), len(m.bookmarks))
+	for i := m.offset; i < end; i++ {
+		entry := m.renderEntry(m.bookmarks[i])
+		if i == m.selectedIdx {
+			entry = selectedStyle.Render(entry)
+		} else {
+			entry = entryStyle.Render(entry)
+		}
+		sb.WriteString(entry + "\n")
+	}
+	return sb.String()
+}
+
+func (m Model) renderEntry(b cache.Bookmark) string {
+	title := html.UnescapeString(b.Title)
+	if title == "" {
+		title = fmt.Sprintf("#%d", b.ItemID)
+	}
+	if b.Type == "comment" {
+		title = "Comment on: " + title
+	}
+
+	meta := authorStyle.Render(b.By) + metaStyle.Render(" · saved "+render.TimeAgo(b.CreatedAt.Unix()))
+	for _, t := range b.Tags {
+		meta += " " + tagStyle.Render("#"+t)
+	}
+
+	lines := []string{storyStyle.Render(title), meta, ""}
+	if b.Note != "" {
+		note := strings.Join(strings.Fields(b.Note), " ")
+		if r := []rune(note); m.width > 16 && len(r) > m.width-6 {
+			note = string(r[:m.width-6]) + "..."
+		}
+		lines[2] = noteStyle.Render(note)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nextTag cycles the tag filter through every tag and back to none.
+func nextTag(tags []string, current string) string {
+	if current == "" {
+		if len(tags) > 0 {
+			return tags[0]
+		}
+		return ""
+	}
+	for i, t := range tags {
+		if t == current && i+1 < len(tags) {
+			return tags[i+1]
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func status(text string, isError bool) tea.Cmd {
+	return func() tea.Msg { return messages.StatusMsg{Text: text, IsError: isError} }
+}
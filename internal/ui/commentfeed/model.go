@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -13,21 +14,35 @@ import (
 	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/config"
 	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
 const maxCommentLines = 20
 
 var (
-	selectedBorderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00BFFF")).Bold(true)
-	normalBorderStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#444444"))
-	authorStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	metaStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282"))
-	storyRefStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
-	headerStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	errorMsgStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+	selectedBorderStyle lipgloss.Style
+	normalBorderStyle   lipgloss.Style
+	authorStyle         lipgloss.Style
+	metaStyle           lipgloss.Style
+	storyRefStyle       lipgloss.Style
+	headerStyle         lipgloss.Style
+	errorMsgStyle       lipgloss.Style
 )
 
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		selectedBorderStyle = lipgloss.NewStyle().Foreground(t.Highlight).Bold(true)
+		normalBorderStyle = lipgloss.NewStyle().Foreground(t.Border)
+		authorStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		metaStyle = lipgloss.NewStyle().Foreground(t.Meta)
+		storyRefStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true)
+		headerStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		errorMsgStyle = lipgloss.NewStyle().Foreground(t.Error)
+	})
+}
+
 // feedEntry represents a single item in the threaded comment feed.
 type feedEntry struct {
 	item  *api.Item
@@ -80,6 +95,7 @@ type Model struct {
 // New creates a new comment feed model.
 func New(cfg config.Config, client *api.Client) Model {
 	vp := viewport.New(0, 0)
+	vp.KeyMap = keys.Keys.Nav.ViewportKeyMap()
 	return Model{
 		viewport: vp,
 		client:   client,
@@ -175,8 +191,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "j", "down":
+		km, nav := keys.Keys.Feed, keys.Keys.Nav
+		switch {
+		case key.Matches(msg, nav.Down):
 			if m.cursor < len(m.entries)-1 {
 				m.cursor++
 				m.rebuildContent()
@@ -186,14 +203,14 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, cmd
 			}
 			return m, nil
-		case "k", "up":
+		case key.Matches(msg, nav.Up):
 			if m.cursor > 0 {
 				m.cursor--
 				m.rebuildContent()
 				m.scrollToCursor()
 			}
 			return m, nil
-		case "enter":
+		case key.Matches(msg, nav.Open):
 			if m.cursor < len(m.entries) {
 				item := m.entries[m.cursor].item
 				return m, func() tea.Msg {
@@ -201,16 +218,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 			return m, nil
-		case "o":
+		case key.Matches(msg, nav.Browser):
 			if m.cursor < len(m.entries) {
 				item := m.entries[m.cursor].item
-				hnURL := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.ID)
+				hnURL := m.client.Endpoints().ItemURL(item.ID)
 				return m, func() tea.Msg {
 					return messages.StatusMsg{Text: "Opening: " + hnURL}
 				}
 			}
 			return m, nil
-		case "e":
+		case key.Matches(msg, km.Edit):
 			if m.cursor >= len(m.entries) {
 				return m, nil
 			}
@@ -233,16 +250,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			return m, func() tea.Msg {
 				return messages.OpenEditMsg{ItemID: item.ID, CurrentText: item.Text}
 			}
-		case "r", "ctrl+r":
+		case key.Matches(msg, km.Refresh):
 			m.loading = true
 			m.viewport.SetContent("Refreshing...")
 			return m, m.loadFeed()
-		case "g", "home":
+		case key.Matches(msg, nav.Home):
 			m.cursor = 0
 			m.rebuildContent()
 			m.viewport.GotoTop()
 			return m, nil
-		case "G", "end":
+		case key.Matches(msg, nav.End):
 			if len(m.entries) > 0 {
 				m.cursor = len(m.entries) - 1
 				m.rebuildContent()
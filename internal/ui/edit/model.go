@@ -4,20 +4,27 @@ import (
 	"log"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/fragmede/nitpick/internal/auth"
 	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
-var (
-	titleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	hintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282"))
-	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
-)
+var titleStyle, hintStyle, errorStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		hintStyle = lipgloss.NewStyle().Foreground(t.Meta)
+		errorStyle = lipgloss.NewStyle().Foreground(t.Error)
+	})
+}
 
 // Model is the comment edit view.
 type Model struct {
@@ -66,8 +73,8 @@ func (m *Model) SetSize(w, h int) {
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+s":
+		switch {
+		case key.Matches(msg, keys.Keys.Form.Send):
 			text := strings.TrimSpace(m.textarea.Value())
 			if text == "" {
 				m.err = "Comment cannot be empty"
@@ -115,10 +122,11 @@ func (m Model) View() string {
 		sb.WriteString("\n")
 	}
 
+	form := keys.Keys.Form
 	if m.submitting {
 		sb.WriteString("Submitting...")
 	} else {
-		sb.WriteString(hintStyle.Render("Ctrl+S to save | Esc to cancel"))
+		sb.WriteString(hintStyle.Render(form.Send.Help().Key + " to save | " + form.Cancel.Help().Key + " to cancel"))
 	}
 
 	content := sb.String()
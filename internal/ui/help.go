@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/theme"
+)
+
+var helpTitleStyle, helpSectionStyle, helpKeyStyle, helpDescStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		helpTitleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Padding(1, 1)
+		helpSectionStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true)
+		helpKeyStyle = lipgloss.NewStyle().Foreground(t.Accent)
+		helpDescStyle = lipgloss.NewStyle().Foreground(t.Text)
+	})
+}
+
+// helpColumnWidth is the width of one column of the help overlay.
+const helpColumnWidth = 38
+
+type helpSection struct {
+	title    string
+	bindings []key.Binding
+}
+
+// helpSections returns the bindings that apply in the active view, its
+// own first and the global ones last.
+func (a *App) helpSections() []helpSection {
+	km := keys.Keys
+	nav := helpSection{"Navigation", keys.Bindings(km.Nav)}
+
+	var sections []helpSection
+	switch a.activeView {
+	case ViewStoryList:
+		sections = []helpSection{{"Story list", keys.Bindings(km.List)}, nav}
+	case ViewCommentFeed:
+		sections = []helpSection{{"Comments", keys.Bindings(km.Feed)}, nav}
+	case ViewStoryDetail:
+		sections = []helpSection{{"Thread", keys.Bindings(km.Story)}, nav, {"Poll vote", keys.Bindings(km.Poll)}}
+	case ViewSearch:
+		sections = []helpSection{{"Search", keys.Bindings(km.Search)}, nav}
+	case ViewBookmarks:
+		sections = []helpSection{{"Bookmarks", keys.Bindings(km.Bookmarks)}, nav}
+	case ViewNotifications:
+		sections = []helpSection{nav}
+	}
+	return append(sections, helpSection{"Global", keys.Bindings(km.Global)})
+}
+
+// helpView renders the help overlay, flowing the sections into as many
+// columns as the screen height needs.
+func (a *App) helpView() string {
+	var lines []string
+	for _, sec := range a.helpSections() {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, helpSectionStyle.Render(sec.title))
+		for _, b := range sec.bindings {
+			if !b.Enabled() {
+				continue
+			}
+			h := b.Help()
+			lines = append(lines, helpKeyStyle.Render(padRight(h.Key, 10))+" "+helpDescStyle.Render(h.Desc))
+		}
+	}
+
+	title := helpTitleStyle.Render("Keys  (any key to close)")
+	rows := max(a.height-1-lipgloss.Height(title), 1)
+	var columns []string
+	for len(lines) > 0 {
+		n := min(rows, len(lines))
+		col := lipgloss.NewStyle().Width(helpColumnWidth).PaddingLeft(2).Render(strings.Join(lines[:n], "\n"))
+		columns = append(columns, col)
+		lines = lines[n:]
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, lipgloss.JoinHorizontal(lipgloss.Top, columns...))
+}
+
+func padRight(s string, n int) string {
+	if w := lipgloss.Width(s); w < n {
+		return s + strings.Repeat(" ", n-w)
+	}
+	return s
+}
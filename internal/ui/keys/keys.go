@@ -0,0 +1,298 @@
+// Package keys defines nitpick's key bindings. Views match keystrokes
+// against these bindings with key.Matches rather than literal strings, and
+// the help overlay is generated from them, so the two can't disagree.
+package keys
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// KeyMap holds every binding, grouped by the view that handles them.
+// Global and Nav bindings apply alongside each view's own group.
+type KeyMap struct {
+	Global    GlobalKeys
+	Nav       NavKeys
+	List      ListKeys
+	Story     StoryKeys
+	Poll      PollKeys
+	Search    SearchKeys
+	Bookmarks BookmarkKeys
+	Feed      FeedKeys
+	Form      FormKeys
+}
+
+// GlobalKeys work in every view that isn't taking text input.
+type GlobalKeys struct {
+	Quit          key.Binding
+	ForceQuit     key.Binding
+	Back          key.Binding
+	Help          key.Binding
+	NextTab       key.Binding
+	PrevTab       key.Binding
+	Top           key.Binding
+	New           key.Binding
+	Threads       key.Binding
+	Past          key.Binding
+	Comments      key.Binding
+	Ask           key.Binding
+	Show          key.Binding
+	Jobs          key.Binding
+	SavedSearch   key.Binding
+	Bookmarks     key.Binding
+	Login         key.Binding
+	Notifications key.Binding
+	Search        key.Binding
+	Submit        key.Binding
+}
+
+// NavKeys move the cursor in every list-like view.
+type NavKeys struct {
+	Up       key.Binding
+	Down     key.Binding
+	Home     key.Binding
+	End      key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Open     key.Binding
+	Browser  key.Binding
+}
+
+// ListKeys are the story list's.
+type ListKeys struct {
+	Upvote            key.Binding
+	Bookmark          key.Binding
+	Hide              key.Binding
+	WatchDomain       key.Binding
+	RemoveSavedSearch key.Binding
+	Filter            key.Binding
+	Refresh           key.Binding
+}
+
+// StoryKeys are the comment tree's.
+type StoryKeys struct {
+	Collapse      key.Binding
+	FoldAll       key.Binding
+	CollapseAll   key.Binding
+	Parent        key.Binding
+	Child         key.Binding
+	NextSibling   key.Binding
+	Root          key.Binding
+	NextNew       key.Binding
+	PrevNew       key.Binding
+	Upvote        key.Binding
+	Reply         key.Binding
+	Edit          key.Binding
+	Profile       key.Binding
+	Bookmark      key.Binding
+	BookmarkStory key.Binding
+	Mute          key.Binding
+	Vote          key.Binding
+	Export        key.Binding
+	Refresh       key.Binding
+}
+
+// PollKeys apply while picking a poll option to vote for.
+type PollKeys struct {
+	Pick   key.Binding
+	Vote   key.Binding
+	Cancel key.Binding
+}
+
+// SearchKeys are the search view's, outside the query prompt.
+type SearchKeys struct {
+	EditQuery key.Binding
+	CycleType key.Binding
+	ByDate    key.Binding
+	Local     key.Binding
+	SaveTab   key.Binding
+	Alert     key.Binding
+}
+
+// BookmarkKeys are the Bookmarks tab's.
+type BookmarkKeys struct {
+	Tags      key.Binding
+	Note      key.Binding
+	Remove    key.Binding
+	FilterTag key.Binding
+	Import    key.Binding
+}
+
+// FeedKeys are the comment feed's.
+type FeedKeys struct {
+	Edit    key.Binding
+	Refresh key.Binding
+}
+
+// FormKeys apply in text prompts, y/n questions and the login, reply,
+// edit and submit forms.
+type FormKeys struct {
+	Send      key.Binding
+	Accept    key.Binding
+	Cancel    key.Binding
+	NextField key.Binding
+	PrevField key.Binding
+	Confirm   key.Binding
+}
+
+// Keys is the active key map.
+var Keys = Default()
+
+// Default returns the built-in key map.
+func Default() KeyMap {
+	return KeyMap{
+		Global: GlobalKeys{
+			Quit:          key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit, or back to the tabs")),
+			ForceQuit:     key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+			Back:          key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+			Help:          key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
+			NextTab:       key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next tab")),
+			PrevTab:       key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous tab")),
+			Top:           key.NewBinding(key.WithKeys("1"), key.WithHelp("1", "top")),
+			New:           key.NewBinding(key.WithKeys("2"), key.WithHelp("2", "new")),
+			Threads:       key.NewBinding(key.WithKeys("3"), key.WithHelp("3", "threads")),
+			Past:          key.NewBinding(key.WithKeys("4"), key.WithHelp("4", "past")),
+			Comments:      key.NewBinding(key.WithKeys("5"), key.WithHelp("5", "comments")),
+			Ask:           key.NewBinding(key.WithKeys("6"), key.WithHelp("6", "ask")),
+			Show:          key.NewBinding(key.WithKeys("7"), key.WithHelp("7", "show")),
+			Jobs:          key.NewBinding(key.WithKeys("8"), key.WithHelp("8", "jobs")),
+			SavedSearch:   key.NewBinding(key.WithKeys("9"), key.WithHelp("9", "next saved search")),
+			Bookmarks:     key.NewBinding(key.WithKeys("0"), key.WithHelp("0", "bookmarks")),
+			Login:         key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "login")),
+			Notifications: key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "notifications")),
+			Search:        key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "search")),
+			Submit:        key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "submit a story")),
+		},
+		Nav: NavKeys{
+			Up:       key.NewBinding(key.WithKeys("k", "up"), key.WithHelp("k/↑", "up")),
+			Down:     key.NewBinding(key.WithKeys("j", "down"), key.WithHelp("j/↓", "down")),
+			Home:     key.NewBinding(key.WithKeys("g", "home"), key.WithHelp("g", "top")),
+			End:      key.NewBinding(key.WithKeys("G", "end"), key.WithHelp("G", "bottom")),
+			PageUp:   key.NewBinding(key.WithKeys("ctrl+u", "pgup"), key.WithHelp("ctrl+u", "page up")),
+			PageDown: key.NewBinding(key.WithKeys("ctrl+d", "pgdown"), key.WithHelp("ctrl+d", "page down")),
+			Open:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+			Browser:  key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open in browser")),
+		},
+		List: ListKeys{
+			Upvote:            key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "upvote")),
+			Bookmark:          key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "bookmark")),
+			Hide:              key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "hide story")),
+			WatchDomain:       key.NewBinding(key.WithKeys("W"), key.WithHelp("W", "alert on this domain")),
+			RemoveSavedSearch: key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "remove saved search tab")),
+			Filter:            key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+			Refresh:           key.NewBinding(key.WithKeys("r", "ctrl+r"), key.WithHelp("r", "refresh")),
+		},
+		Story: StoryKeys{
+			Collapse:      key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "collapse / expand")),
+			FoldAll:       key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "fold / unfold all")),
+			CollapseAll:   key.NewBinding(key.WithKeys("Z"), key.WithHelp("Z", "collapse all")),
+			Parent:        key.NewBinding(key.WithKeys("[", "p", "h"), key.WithHelp("h/p/[", "parent")),
+			Child:         key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "first reply")),
+			NextSibling:   key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "next sibling")),
+			Root:          key.NewBinding(key.WithKeys("^"), key.WithHelp("^", "thread root")),
+			NextNew:       key.NewBinding(key.WithKeys("."), key.WithHelp(".", "next new comment")),
+			PrevNew:       key.NewBinding(key.WithKeys(","), key.WithHelp(",", "previous new comment")),
+			Upvote:        key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "upvote")),
+			Reply:         key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reply")),
+			Edit:          key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit your comment")),
+			Profile:       key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "author's profile")),
+			Bookmark:      key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "bookmark comment")),
+			BookmarkStory: key.NewBinding(key.WithKeys("B"), key.WithHelp("B", "bookmark story")),
+			Mute:          key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "mute author")),
+			Vote:          key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "vote in poll")),
+			Export:        key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export thread")),
+			Refresh:       key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "refresh")),
+		},
+		Poll: PollKeys{
+			Pick:   key.NewBinding(key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"), key.WithHelp("1-9", "pick option")),
+			Vote:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "vote")),
+			Cancel: key.NewBinding(key.WithKeys("esc", "q", "v"), key.WithHelp("esc", "cancel")),
+		},
+		Search: SearchKeys{
+			EditQuery: key.NewBinding(key.WithKeys("/", "i"), key.WithHelp("/", "edit query")),
+			CycleType: key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "stories / comments / both")),
+			ByDate:    key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "relevance / date sort")),
+			Local:     key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "Algolia / local cache")),
+			SaveTab:   key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "save as tab")),
+			Alert:     key.NewBinding(key.WithKeys("A"), key.WithHelp("A", "alert on new matches")),
+		},
+		Bookmarks: BookmarkKeys{
+			Tags:      key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "edit tags")),
+			Note:      key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit note")),
+			Remove:    key.NewBinding(key.WithKeys("d", "b"), key.WithHelp("d", "remove")),
+			FilterTag: key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "cycle tag filter")),
+			Import:    key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "import HN favorites")),
+		},
+		Feed: FeedKeys{
+			Edit:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit your comment")),
+			Refresh: key.NewBinding(key.WithKeys("r", "ctrl+r"), key.WithHelp("r", "refresh")),
+		},
+		Form: FormKeys{
+			Send:      key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "send")),
+			Accept:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "accept")),
+			Cancel:    key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+			NextField: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next field")),
+			PrevField: key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous field")),
+			Confirm:   key.NewBinding(key.WithKeys("y", "Y"), key.WithHelp("y", "yes")),
+		},
+	}
+}
+
+// Bindings returns the bindings in a group (such as Keys.Story), in
+// field order.
+func Bindings(group any) []key.Binding {
+	v := reflect.ValueOf(group)
+	var result []key.Binding
+	for i := 0; i < v.NumField(); i++ {
+		if b, ok := v.Field(i).Interface().(key.Binding); ok {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// ShortHelp renders bindings as a one-line hint, such as
+// "r:reply  M:mute author", skipping disabled ones.
+func ShortHelp(bindings ...key.Binding) string {
+	parts := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		if b.Enabled() {
+			parts = append(parts, b.Help().Key+":"+b.Help().Desc)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// ViewportKeyMap makes a viewport scroll with the Nav bindings only, so
+// stray keys like space or f don't page it.
+func (n NavKeys) ViewportKeyMap() viewport.KeyMap {
+	return viewport.KeyMap{
+		Up:           n.Up,
+		Down:         n.Down,
+		HalfPageUp:   n.PageUp,
+		HalfPageDown: n.PageDown,
+	}
+}
+
+// ListKeyMap makes a bubbles list navigate and filter with the Nav and
+// List bindings. Its own quit and help keys are disabled; the app handles
+// those.
+func (km KeyMap) ListKeyMap() list.KeyMap {
+	m := list.DefaultKeyMap()
+	m.CursorUp = km.Nav.Up
+	m.CursorDown = km.Nav.Down
+	m.GoToStart = km.Nav.Home
+	m.GoToEnd = km.Nav.End
+	m.PrevPage = km.Nav.PageUp
+	m.NextPage = km.Nav.PageDown
+	m.Filter = km.List.Filter
+	m.ShowFullHelp = key.NewBinding(key.WithDisabled())
+	m.CloseFullHelp = key.NewBinding(key.WithDisabled())
+	m.Quit = key.NewBinding(key.WithDisabled())
+	m.ForceQuit = key.NewBinding(key.WithDisabled())
+	return m
+}
@@ -0,0 +1,209 @@
+package keys
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Presets are the built-in key maps, as overrides of the default (vim)
+// bindings. Actions are named group.action, e.g. story.parent.
+var Presets = map[string]map[string][]string{
+	"vim": nil,
+	"emacs": {
+		"nav.up":             {"ctrl+p", "up"},
+		"nav.down":           {"ctrl+n", "down"},
+		"nav.home":           {"alt+<", "home"},
+		"nav.end":            {"alt+>", "end"},
+		"nav.page_up":        {"alt+v", "pgup"},
+		"nav.page_down":      {"ctrl+v", "pgdown"},
+		"global.back":        {"esc", "ctrl+g"},
+		"story.parent":       {"ctrl+b", "left"},
+		"story.child":        {"ctrl+f", "right"},
+		"story.next_sibling": {"alt+f"},
+		"story.root":         {"ctrl+a"},
+		"search.edit_query":  {"ctrl+s", "/"},
+		"poll.cancel":        {"ctrl+g", "esc"},
+		"form.cancel":        {"ctrl+g", "esc"},
+	},
+	"less": {
+		"nav.up":               {"k", "y", "ctrl+y", "ctrl+p", "up"},
+		"nav.down":             {"j", "ctrl+e", "ctrl+n", "down"},
+		"nav.home":             {"g", "<", "home"},
+		"nav.end":              {"G", ">", "end"},
+		"nav.page_up":          {"b", "ctrl+b", "ctrl+u", "pgup"},
+		"nav.page_down":        {"f", "ctrl+f", "ctrl+d", "pgdown"},
+		"list.bookmark":        {"m"},
+		"story.bookmark":       {"m"},
+		"bookmarks.remove":     {"d"},
+		"bookmarks.filter_tag": {"T"},
+	},
+}
+
+// contexts lists what is active together in each view: whole groups, or
+// single group.action bindings. A key bound to two actions within one
+// context is a conflict, since only one of them can ever fire.
+var contexts = [][]string{
+	{"global", "nav", "list"},
+	{"global", "nav", "story"},
+	{"global", "nav", "search"},
+	{"global", "nav", "bookmarks"},
+	{"global", "nav", "feed"},
+	{"poll", "nav.up", "nav.down"},
+	{"form"},
+}
+
+// Load returns the named preset with overrides applied on top, each
+// replacing an action's keys; an empty list unbinds it. It fails on
+// unknown presets or actions and on conflicting bindings.
+func Load(preset string, overrides map[string][]string) (KeyMap, error) {
+	if preset == "" {
+		preset = "vim"
+	}
+	base, ok := Presets[preset]
+	if !ok {
+		return KeyMap{}, fmt.Errorf("unknown key preset %q (want vim, emacs or less)", preset)
+	}
+	km := Default()
+	if err := km.apply(base); err != nil {
+		return KeyMap{}, err
+	}
+	if err := km.apply(overrides); err != nil {
+		return KeyMap{}, fmt.Errorf("[keys]: %w", err)
+	}
+	if err := km.checkConflicts(); err != nil {
+		return KeyMap{}, fmt.Errorf("[keys]: %w", err)
+	}
+	return km, nil
+}
+
+// Action is one named binding, as listed by Actions.
+type Action struct {
+	Name    string // group.action
+	Binding key.Binding
+}
+
+// Actions returns every binding in km with its name, in declaration order.
+func (km KeyMap) Actions() []Action {
+	var result []Action
+	km.each(func(name string, b *key.Binding) {
+		result = append(result, Action{name, *b})
+	})
+	return result
+}
+
+func (km *KeyMap) apply(overrides map[string][]string) error {
+	bindings := make(map[string]*key.Binding)
+	km.each(func(name string, b *key.Binding) { bindings[name] = b })
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, ok := bindings[name]
+		if !ok {
+			return fmt.Errorf("unknown action %q", name)
+		}
+		ks := overrides[name]
+		if len(ks) == 0 {
+			*b = key.NewBinding(key.WithDisabled(), key.WithHelp("", b.Help().Desc))
+			continue
+		}
+		b.SetKeys(ks...)
+		b.SetHelp(helpKey(ks), b.Help().Desc)
+		b.SetEnabled(true)
+	}
+	return nil
+}
+
+func (km KeyMap) checkConflicts() error {
+	bindings := make(map[string]key.Binding)
+	km.each(func(name string, b *key.Binding) { bindings[name] = *b })
+
+	for _, ctx := range contexts {
+		owner := make(map[string]string)
+		for _, name := range sortedNames(bindings) {
+			if !inContext(ctx, name) || !bindings[name].Enabled() {
+				continue
+			}
+			for _, k := range bindings[name].Keys() {
+				if other, ok := owner[k]; ok && other != name {
+					return fmt.Errorf("key %q is bound to both %s and %s", k, other, name)
+				}
+				owner[k] = name
+			}
+		}
+	}
+	return nil
+}
+
+func inContext(ctx []string, name string) bool {
+	group, _, _ := strings.Cut(name, ".")
+	for _, c := range ctx {
+		if c == name || c == group {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedNames(m map[string]key.Binding) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// each calls fn with the name and address of every binding in km.
+func (km *KeyMap) each(fn func(name string, b *key.Binding)) {
+	groups := reflect.ValueOf(km).Elem()
+	for i := 0; i < groups.NumField(); i++ {
+		group := groups.Field(i)
+		prefix := snakeCase(groups.Type().Field(i).Name)
+		for j := 0; j < group.NumField(); j++ {
+			if b, ok := group.Field(j).Addr().Interface().(*key.Binding); ok {
+				fn(prefix+"."+snakeCase(group.Type().Field(j).Name), b)
+			}
+		}
+	}
+}
+
+// snakeCase turns a field name like PageDown into page_down.
+func snakeCase(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// helpKey is how a rebound action's keys show in help: the first two,
+// with arrows and space spelled out.
+func helpKey(ks []string) string {
+	names := map[string]string{" ": "space", "up": "↑", "down": "↓", "left": "←", "right": "→"}
+	var parts []string
+	for _, k := range ks {
+		if n, ok := names[k]; ok {
+			k = n
+		}
+		parts = append(parts, k)
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
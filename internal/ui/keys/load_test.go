@@ -0,0 +1,85 @@
+package keys
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPresets(t *testing.T) {
+	for name := range Presets {
+		if _, err := Load(name, nil); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+	km, err := Load("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(km.Actions(), Default().Actions()) {
+		t.Error("no preset isn't the default (vim) map")
+	}
+	km, err = Load("emacs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := km.Nav.Down.Keys(); !reflect.DeepEqual(got, []string{"ctrl+n", "down"}) {
+		t.Errorf("emacs nav.down = %q", got)
+	}
+	if _, err := Load("nano", nil); err == nil || !strings.Contains(err.Error(), `unknown key preset "nano"`) {
+		t.Errorf("unknown preset: err = %v", err)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	km, err := Load("vim", map[string][]string{
+		"story.reply":   {"R", "ctrl+r"},
+		"story.refresh": {"F5"},
+		"list.hide":     {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := km.Story.Reply.Keys(); !reflect.DeepEqual(got, []string{"R", "ctrl+r"}) {
+		t.Errorf("story.reply keys = %q", got)
+	}
+	if h := km.Story.Reply.Help(); h.Key != "R/ctrl+r" || h.Desc != "reply" {
+		t.Errorf("story.reply help = %+v", h)
+	}
+	if km.List.Hide.Enabled() {
+		t.Error("an empty list didn't unbind list.hide")
+	}
+	if !km.Story.Upvote.Enabled() || km.Story.Upvote.Keys()[0] != "u" {
+		t.Error("an action that wasn't overridden changed")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		preset    string
+		overrides map[string][]string
+		want      string
+	}{
+		{"vim", map[string][]string{"global.quit": {"j"}}, `[keys]: key "j" is bound to both global.quit and nav.down`},
+		{"vim", map[string][]string{"story.reply": {"1"}}, `[keys]: key "1" is bound to both global.top and story.reply`},
+		{"vim", map[string][]string{"quit": {"x"}}, `[keys]: unknown action "quit"`},
+		{"vim", map[string][]string{"story.frobnicate": {"x"}}, `[keys]: unknown action "story.frobnicate"`},
+		{"less", map[string][]string{"list.upvote": {"b"}}, `[keys]: key "b" is bound to both list.upvote and nav.page_up`},
+		{"vim", map[string][]string{"poll.cancel": {"k"}}, `[keys]: key "k" is bound to both nav.up and poll.cancel`},
+	}
+	for _, tt := range tests {
+		_, err := Load(tt.preset, tt.overrides)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s %v: err = %v, want %s", tt.preset, tt.overrides, err, tt.want)
+		}
+	}
+
+	// Keys only clash within a view: the search and bookmarks views never
+	// see each other's bindings, and the poll only listens to nav.up and
+	// nav.down.
+	for _, o := range []map[string][]string{{"search.alert": {"f"}}, {"poll.cancel": {"g"}}} {
+		if _, err := Load("vim", o); err != nil {
+			t.Errorf("%v: %v", o, err)
+		}
+	}
+}
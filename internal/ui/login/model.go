@@ -3,21 +3,28 @@ package login
 import (
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
-var (
-	focusedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600"))
-	labelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
-	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
-	titleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true).
+var focusedStyle, labelStyle, errorStyle, titleStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		focusedStyle = lipgloss.NewStyle().Foreground(t.Accent)
+		labelStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true)
+		errorStyle = lipgloss.NewStyle().Foreground(t.Error)
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).
 			Padding(1, 0)
-)
+	})
+}
 
 // Model is the login form view.
 type Model struct {
@@ -60,8 +67,9 @@ func (m *Model) SetSize(w, h int) {
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "tab", "shift+tab":
+		form := keys.Keys.Form
+		switch {
+		case key.Matches(msg, form.NextField, form.PrevField):
 			if m.focusIndex == 0 {
 				m.focusIndex = 1
 				m.usernameInput.Blur()
@@ -72,7 +80,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.usernameInput.Focus()
 			}
 			return m, nil
-		case "enter":
+		case key.Matches(msg, form.Accept):
 			if m.submitting {
 				return m, nil
 			}
@@ -132,10 +140,11 @@ func (m Model) View() string {
 		sb.WriteString("\n\n")
 	}
 
+	form := keys.Keys.Form
 	if m.submitting {
 		sb.WriteString("Logging in...")
 	} else {
-		sb.WriteString(focusedStyle.Render("Enter") + " to submit, " + focusedStyle.Render("Esc") + " to cancel")
+		sb.WriteString(focusedStyle.Render(form.Accept.Help().Key) + " to submit, " + focusedStyle.Render(form.Cancel.Help().Key) + " to cancel")
 	}
 
 	content := sb.String()
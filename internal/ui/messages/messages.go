@@ -18,11 +18,39 @@ type (
 	ShowHelpMsg   struct{}
 )
 
+// Saved search messages.
+type (
+	SaveSearchMsg struct {
+		Name   string
+		Query  string
+		Tags   string
+		ByDate bool
+	}
+	DeleteSearchMsg struct{ Name string }
+)
+
+// ToggleAlertMsg adds or removes a keyword/domain alert (see cache.Alert).
+type ToggleAlertMsg struct {
+	Kind    string
+	Pattern string
+}
+
+// ToggleBookmarkMsg bookmarks an item, or removes its bookmark.
+type ToggleBookmarkMsg struct {
+	Item *api.Item
+}
+
+// StoryHiddenMsg reports that a story was hidden from the story lists.
+type StoryHiddenMsg struct {
+	ID int
+}
+
 // Data messages.
 type (
 	StoriesLoadedMsg struct {
 		StoryType api.StoryType
 		Items     []*api.Item
+		Stale     bool // served from cache because the fetch failed
 		Err       error
 	}
 
@@ -34,9 +62,20 @@ type (
 	CommentsLoadedMsg struct {
 		StoryID int
 		Items   []*api.Item
+		Tree    bool // the comment tree was prefetched into the cache in one request
 		Err     error
 	}
 
+	// CommentsBatchMsg is one batch of a story view's breadth-first
+	// comment load. The app delivers it even while another view is on top.
+	CommentsBatchMsg struct {
+		StoryID int
+		Gen     int   // which load of the story view it belongs to
+		Kids    []int // replies to the batch's comments, queued after it
+		Live    int   // comments in the batch that aren't deleted or flagged
+		Failed  []int // comments that couldn't be fetched or found in the cache
+	}
+
 	LoginResultMsg struct {
 		Username string
 		Err      error
@@ -59,6 +98,13 @@ type (
 
 	OpenSubmitMsg struct{}
 
+	// VoteMsg asks the app to upvote an item. Poll is set when the item
+	// is an option of that poll.
+	VoteMsg struct {
+		ItemID int
+		Poll   int
+	}
+
 	VoteResultMsg struct {
 		ItemID int
 		Err    error
@@ -76,4 +122,20 @@ type (
 	SessionRestoredMsg struct {
 		Username string
 	}
+
+	// ConnectivityMsg reports that the API client went offline or came
+	// back online.
+	ConnectivityMsg struct {
+		Offline bool
+	}
+
+	// SyncProgressMsg and SyncDoneMsg report on the background sync.
+	SyncProgressMsg struct {
+		Text string
+	}
+	SyncDoneMsg struct {
+		Items int
+		Bytes string
+		Err   error
+	}
 )
@@ -4,48 +4,54 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
 var (
-	titleStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true).Padding(1, 0)
-	notifStyle     = lipgloss.NewStyle().Padding(0, 1)
-	selectedStyle  = lipgloss.NewStyle().Background(lipgloss.Color("#333333")).Padding(0, 1)
-	authorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	unreadDotStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true)
-	metaStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
-	previewStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#CCCCCC"))
+	titleStyle     lipgloss.Style
+	notifStyle     lipgloss.Style
+	selectedStyle  lipgloss.Style
+	authorStyle    lipgloss.Style
+	unreadDotStyle lipgloss.Style
+	metaStyle      lipgloss.Style
+	previewStyle   lipgloss.Style
 )
 
-// Notification represents a single notification entry.
-type Notification struct {
-	ID          int
-	ItemID      int
-	ParentID    int
-	StoryID     int
-	ByUser      string
-	TextPreview string
-	CreatedAt   int64
-	Read        bool
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Padding(1, 0)
+		notifStyle = lipgloss.NewStyle().Padding(0, 1)
+		selectedStyle = lipgloss.NewStyle().Background(t.Selection).Padding(0, 1)
+		authorStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		unreadDotStyle = lipgloss.NewStyle().Foreground(t.Error).Bold(true)
+		metaStyle = lipgloss.NewStyle().Foreground(t.Dim)
+		previewStyle = lipgloss.NewStyle().Foreground(t.Text)
+	})
 }
 
 // Model is the notifications view.
 type Model struct {
-	notifications []Notification
+	notifications []cache.Notification
+	alerts        []cache.Alert
 	selectedIdx   int
+	client        *api.Client
 	db            *cache.DB
 	width         int
 	height        int
 }
 
 // New creates a new notifications model.
-func New(db *cache.DB) Model {
-	return Model{db: db}
+func New(client *api.Client, db *cache.DB) Model {
+	return Model{client: client, db: db}
 }
 
 // SetSize sets the viewport dimensions.
@@ -56,29 +62,45 @@ func (m *Model) SetSize(w, h int) {
 
 // Load refreshes the notification list from the database.
 func (m *Model) Load() {
-	m.notifications = loadNotifications(m.db)
+	m.notifications, _ = m.db.ListNotifications(50, false)
+	m.alerts, _ = m.db.ListAlerts()
 }
 
 // Update handles messages.
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "j", "down":
+		nav := keys.Keys.Nav
+		switch {
+		case key.Matches(msg, nav.Down):
 			if m.selectedIdx < len(m.notifications)-1 {
 				m.selectedIdx++
 			}
-		case "k", "up":
+		case key.Matches(msg, nav.Up):
 			if m.selectedIdx > 0 {
 				m.selectedIdx--
 			}
-		case "enter":
+		case key.Matches(msg, nav.Home, nav.PageUp):
+			m.selectedIdx = 0
+		case key.Matches(msg, nav.End, nav.PageDown):
+			m.selectedIdx = max(len(m.notifications)-1, 0)
+		case key.Matches(msg, nav.Browser):
+			if m.selectedIdx >= 0 && m.selectedIdx < len(m.notifications) {
+				u := m.client.Endpoints().ItemURL(m.notifications[m.selectedIdx].ItemID)
+				return m, func() tea.Msg { return messages.StatusMsg{Text: "Opening: " + u} }
+			}
+		case key.Matches(msg, nav.Open):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.notifications) {
 				n := m.notifications[m.selectedIdx]
-				markRead(m.db, n.ID)
+				m.db.MarkNotificationRead(n.ID)
 				m.notifications[m.selectedIdx].Read = true
+				// Mentions open the matching item itself; replies open the story.
+				id := n.StoryID
+				if n.Kind == cache.NotificationMention || id == 0 {
+					id = n.ItemID
+				}
 				return m, func() tea.Msg {
-					return messages.OpenStoryMsg{StoryID: n.StoryID}
+					return messages.OpenStoryMsg{StoryID: id}
 				}
 			}
 		}
@@ -92,6 +114,17 @@ func (m Model) View() string {
 
 	sb.WriteString(titleStyle.Render("Notifications"))
 	sb.WriteString("\n")
+	if len(m.alerts) > 0 {
+		watching := make([]string, len(m.alerts))
+		for i, a := range m.alerts {
+			watching[i] = a.Pattern
+			if a.Kind == cache.AlertDomain {
+				watching[i] = "site:" + a.Pattern
+			}
+		}
+		sb.WriteString(metaStyle.Render("  Watching: " + strings.Join(watching, ", ")))
+		sb.WriteString("\n")
+	}
 
 	if len(m.notifications) == 0 {
 		sb.WriteString("\n  No notifications yet.\n")
@@ -108,7 +141,11 @@ func (m Model) View() string {
 		}
 
 		line.WriteString(authorStyle.Render(n.ByUser))
-		line.WriteString(metaStyle.Render(fmt.Sprintf(" replied %s", render.TimeAgo(n.CreatedAt))))
+		if n.Kind == cache.NotificationMention {
+			line.WriteString(metaStyle.Render(fmt.Sprintf(" mentioned %q %s", n.Topic, render.TimeAgo(n.CreatedAt))))
+		} else {
+			line.WriteString(metaStyle.Render(fmt.Sprintf(" replied %s", render.TimeAgo(n.CreatedAt))))
+		}
 		line.WriteString("\n")
 		if n.TextPreview != "" {
 			preview := n.TextPreview
@@ -140,29 +177,3 @@ func (m Model) UnreadCount() int {
 	}
 	return count
 }
-
-func loadNotifications(db *cache.DB) []Notification {
-	rows, err := db.Query(`SELECT id, item_id, parent_id, story_id, by_user, text_preview, created_at, read
-		FROM notifications ORDER BY created_at DESC LIMIT 50`)
-	if err != nil {
-		return nil
-	}
-	defer rows.Close()
-
-	var result []Notification
-	for rows.Next() {
-		var n Notification
-		var readInt int
-		if err := rows.Scan(&n.ID, &n.ItemID, &n.ParentID, &n.StoryID, &n.ByUser,
-			&n.TextPreview, &n.CreatedAt, &readInt); err != nil {
-			continue
-		}
-		n.Read = readInt != 0
-		result = append(result, n)
-	}
-	return result
-}
-
-func markRead(db *cache.DB, id int) {
-	db.Exec("UPDATE notifications SET read = 1 WHERE id = ?", id)
-}
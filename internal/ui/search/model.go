@@ -0,0 +1,537 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
+)
+
+const maxSnippetLines = 4
+
+var (
+	headerStyle         lipgloss.Style
+	selectedBorderStyle lipgloss.Style
+	normalBorderStyle   lipgloss.Style
+	titleStyle          lipgloss.Style
+	authorStyle         lipgloss.Style
+	metaStyle           lipgloss.Style
+	hintStyle           lipgloss.Style
+	errorMsgStyle       lipgloss.Style
+)
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		headerStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		selectedBorderStyle = lipgloss.NewStyle().Foreground(t.Highlight).Bold(true)
+		normalBorderStyle = lipgloss.NewStyle().Foreground(t.Border)
+		titleStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true)
+		authorStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		metaStyle = lipgloss.NewStyle().Foreground(t.Meta)
+		hintStyle = lipgloss.NewStyle().Foreground(t.Dim)
+		errorMsgStyle = lipgloss.NewStyle().Foreground(t.Error)
+	})
+}
+
+// hitKind restricts which Algolia hit types are returned.
+type hitKind int
+
+const (
+	kindAll hitKind = iota
+	kindStories
+	kindComments
+)
+
+func (k hitKind) tags() string {
+	switch k {
+	case kindStories:
+		return "story"
+	case kindComments:
+		return "comment"
+	default:
+		return "(story,comment)"
+	}
+}
+
+// types is the item types a local search includes.
+func (k hitKind) types() []string {
+	switch k {
+	case kindStories:
+		return []string{"story"}
+	case kindComments:
+		return []string{"comment"}
+	default:
+		return []string{"story", "comment"}
+	}
+}
+
+func (k hitKind) String() string {
+	switch k {
+	case kindStories:
+		return "stories"
+	case kindComments:
+		return "comments"
+	default:
+		return "stories+comments"
+	}
+}
+
+// resultsMsg carries one page of search results. seq ties the response
+// to the query that produced it so stale responses are dropped.
+type resultsMsg struct {
+	seq    int
+	result *api.SearchResult
+	more   bool
+	err    error
+}
+
+type itemOffset struct {
+	startLine int
+	endLine   int
+}
+
+// Model is the search view: a query prompt over a scrollable hit list.
+// Queries go to Algolia, or to the local cache's full-text index in local
+// mode, which is switched on automatically while offline.
+type Model struct {
+	input    textinput.Model
+	nameIn   textinput.Model // name prompt when saving the search as a tab
+	viewport viewport.Model
+	items    []*api.Item
+	offsets  []itemOffset
+	cursor   int
+	kind     hitKind
+	byDate   bool
+	local    bool
+	client   *api.Client
+	db       *cache.DB
+	cfg      config.Config
+	width    int
+	height   int
+
+	// Query state.
+	query       string // last submitted query
+	parsed      *api.Query
+	seq         int
+	page        int
+	nbPages     int
+	nbHits      int
+	loading     bool
+	loadingMore bool
+	err         string
+}
+
+// New creates a new search view with the prompt focused.
+func New(cfg config.Config, client *api.Client, db *cache.DB) Model {
+	ti := textinput.New()
+	ti.Placeholder = `search HN, e.g. rust author:pg points>100 type:ask after:2024-01-01 "exact phrase"`
+	ti.Prompt = "/ "
+	ti.CharLimit = 256
+	ti.Focus()
+
+	ni := textinput.New()
+	ni.Placeholder = "tab name"
+	ni.Prompt = "Save as: "
+	ni.CharLimit = 32
+
+	vp := viewport.New(0, 0)
+	vp.KeyMap = keys.Keys.Nav.ViewportKeyMap()
+
+	return Model{
+		input:    ti,
+		nameIn:   ni,
+		viewport: vp,
+		client:   client,
+		db:       db,
+		cfg:      cfg,
+	}
+}
+
+// Init starts the cursor blink.
+func (m Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetSize updates viewport dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.Width = w - 4
+	m.viewport.Width = w
+	m.viewport.Height = h - 4 // title, prompt, status, blank line
+	if m.viewport.Height < 1 {
+		m.viewport.Height = 1
+	}
+	m.rebuildContent()
+}
+
+// Typing reports whether the query prompt has focus and is consuming keys.
+func (m Model) Typing() bool {
+	return m.input.Focused() || m.nameIn.Focused()
+}
+
+// Focus moves keyboard focus to the query prompt.
+func (m *Model) Focus() tea.Cmd {
+	return m.input.Focus()
+}
+
+// Update handles messages.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case resultsMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		m.loading = false
+		m.loadingMore = false
+		if msg.err != nil {
+			if msg.more {
+				return m, func() tea.Msg {
+					return messages.StatusMsg{Text: "Error loading more: " + msg.err.Error(), IsError: true}
+				}
+			}
+			m.err = msg.err.Error()
+			m.items = nil
+			m.rebuildContent()
+			return m, nil
+		}
+		m.err = ""
+		if msg.more {
+			m.items = append(m.items, msg.result.Items...)
+		} else {
+			m.items = msg.result.Items
+			m.cursor = 0
+			m.viewport.SetYOffset(0)
+		}
+		m.page = msg.result.Page
+		m.nbPages = msg.result.NbPages
+		m.nbHits = msg.result.NbHits
+		m.rebuildContent()
+		return m, nil
+
+	case tea.KeyMsg:
+		form := keys.Keys.Form
+		if m.nameIn.Focused() {
+			switch {
+			case key.Matches(msg, form.Accept):
+				name := strings.TrimSpace(m.nameIn.Value())
+				if name == "" {
+					return m, nil
+				}
+				m.nameIn.Blur()
+				save := messages.SaveSearchMsg{
+					Name:   name,
+					Query:  m.query,
+					Tags:   m.kind.tags(),
+					ByDate: m.byDate,
+				}
+				return m, func() tea.Msg { return save }
+			case key.Matches(msg, form.Cancel):
+				m.nameIn.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.nameIn, cmd = m.nameIn.Update(msg)
+			return m, cmd
+		}
+
+		if m.input.Focused() {
+			switch {
+			case key.Matches(msg, form.Accept):
+				m.input.Blur()
+				return m, m.submit()
+			case key.Matches(msg, form.Cancel):
+				if m.query == "" {
+					return m, func() tea.Msg { return messages.GoBackMsg{} }
+				}
+				m.input.Blur()
+				m.input.SetValue(m.query)
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+
+		km, nav := keys.Keys.Search, keys.Keys.Nav
+		switch {
+		case key.Matches(msg, km.EditQuery):
+			return m, m.input.Focus()
+		case key.Matches(msg, nav.Down):
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+				m.rebuildContent()
+				m.scrollToCursor()
+			}
+			return m, m.maybeLoadMore()
+		case key.Matches(msg, nav.Up):
+			if m.cursor > 0 {
+				m.cursor--
+				m.rebuildContent()
+				m.scrollToCursor()
+			}
+			return m, nil
+		case key.Matches(msg, nav.Home):
+			m.cursor = 0
+			m.rebuildContent()
+			m.viewport.GotoTop()
+			return m, nil
+		case key.Matches(msg, nav.End):
+			if len(m.items) > 0 {
+				m.cursor = len(m.items) - 1
+				m.rebuildContent()
+				m.viewport.GotoBottom()
+			}
+			return m, m.maybeLoadMore()
+		case key.Matches(msg, km.CycleType):
+			m.kind = (m.kind + 1) % 3
+			return m, m.submit()
+		case key.Matches(msg, km.ByDate):
+			m.byDate = !m.byDate
+			return m, m.submit()
+		case key.Matches(msg, km.Local):
+			m.local = !m.local
+			return m, m.submit()
+		case key.Matches(msg, km.SaveTab):
+			if m.query == "" {
+				return m, nil
+			}
+			m.nameIn.SetValue("")
+			return m, m.nameIn.Focus()
+		case key.Matches(msg, km.Alert):
+			if m.query == "" {
+				return m, nil
+			}
+			query := m.query
+			return m, func() tea.Msg {
+				return messages.ToggleAlertMsg{Kind: cache.AlertKeyword, Pattern: query}
+			}
+		case key.Matches(msg, nav.Open):
+			if m.cursor < len(m.items) {
+				id := m.items[m.cursor].ID
+				return m, func() tea.Msg { return messages.OpenStoryMsg{StoryID: id} }
+			}
+			return m, nil
+		case key.Matches(msg, nav.Browser):
+			if m.cursor < len(m.items) {
+				item := m.items[m.cursor]
+				u := item.URL
+				if u == "" {
+					u = m.client.Endpoints().ItemURL(item.ID)
+				}
+				return m, func() tea.Msg { return messages.StatusMsg{Text: "Opening: " + u} }
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// View renders the search view.
+func (m Model) View() string {
+	var sb strings.Builder
+	sb.WriteString(headerStyle.Render("Search"))
+	sb.WriteString("\n")
+	sb.WriteString(m.input.View())
+	sb.WriteString("\n")
+	if m.nameIn.Focused() {
+		sb.WriteString(m.nameIn.View())
+	} else {
+		sb.WriteString(m.statusLine())
+	}
+	sb.WriteString("\n")
+	return sb.String() + m.viewport.View()
+}
+
+func (m Model) statusLine() string {
+	sort := "relevance"
+	if m.byDate {
+		sort = "date"
+	}
+	var status string
+	switch {
+	case m.loading:
+		status = "searching..."
+	case m.loadingMore:
+		status = fmt.Sprintf("%d of %d results (loading more...)", len(m.items), m.nbHits)
+	case m.query != "":
+		status = fmt.Sprintf("%d of %d results", len(m.items), m.nbHits)
+	}
+	source := "Algolia"
+	if m.local {
+		source = "local cache"
+	}
+	parts := []string{source, m.kind.String(), "by " + sort}
+	if status != "" {
+		parts = append([]string{status}, parts...)
+	}
+	hint := "  " + keys.ShortHelp(keys.Keys.Form.Accept, keys.Keys.Form.Cancel)
+	if !m.input.Focused() {
+		km := keys.Keys.Search
+		hint = "  " + keys.ShortHelp(km.EditQuery, km.CycleType, km.Local, km.SaveTab, keys.Keys.Global.Help)
+	}
+	return metaStyle.Render(strings.Join(parts, " · ")) + hintStyle.Render(hint)
+}
+
+// submit runs the current query from page 0.
+func (m *Model) submit() tea.Cmd {
+	q := strings.TrimSpace(m.input.Value())
+	if q == "" {
+		return nil
+	}
+	parsed, err := api.ParseQuery(q)
+	if err != nil {
+		m.err = err.Error()
+		m.items = nil
+		m.rebuildContent()
+		return m.input.Focus()
+	}
+	if m.client.Offline() {
+		m.local = true
+	}
+	m.query = q
+	m.parsed = parsed
+	m.seq++
+	m.loading = true
+	m.err = ""
+	return m.fetch(0, false)
+}
+
+// maybeLoadMore fetches the next page when the cursor nears the end.
+func (m *Model) maybeLoadMore() tea.Cmd {
+	if m.loading || m.loadingMore || m.query == "" || m.page+1 >= m.nbPages {
+		return nil
+	}
+	if m.cursor < len(m.items)-5 {
+		return nil
+	}
+	m.loadingMore = true
+	return m.fetch(m.page+1, true)
+}
+
+func (m Model) fetch(page int, more bool) tea.Cmd {
+	client := m.client
+	seq := m.seq
+	if m.local {
+		db := m.db
+		parsed := m.parsed
+		opts := cache.SearchOptions{
+			Types:       m.kind.types(),
+			ByDate:      m.byDate,
+			Page:        page,
+			HitsPerPage: m.cfg.FetchPageSize,
+		}
+		return func() tea.Msg {
+			result, err := db.Search(parsed, opts)
+			return resultsMsg{seq: seq, result: result, more: more, err: err}
+		}
+	}
+	// An explicit type: filter in the query wins over the t toggle.
+	params := m.parsed.ParamsWithTags(m.kind.tags())
+	params.ByDate = m.byDate
+	params.Page = page
+	params.HitsPerPage = m.cfg.FetchPageSize
+	return func() tea.Msg {
+		result, err := client.Search(context.Background(), params)
+		return resultsMsg{seq: seq, result: result, more: more, err: err}
+	}
+}
+
+func (m *Model) rebuildContent() {
+	if m.err != "" {
+		m.offsets = nil
+		m.viewport.SetContent(errorMsgStyle.Render("Error: " + m.err))
+		return
+	}
+	if len(m.items) == 0 {
+		m.offsets = nil
+		if m.query != "" && !m.loading {
+			m.viewport.SetContent("  No results.")
+		} else {
+			m.viewport.SetContent("")
+		}
+		return
+	}
+
+	var sb strings.Builder
+	m.offsets = make([]itemOffset, len(m.items))
+	bodyWidth := m.width - 6
+	if bodyWidth < 20 {
+		bodyWidth = 20
+	}
+
+	lineCount := 0
+	for i, item := range m.items {
+		startLine := lineCount
+		border := normalBorderStyle.Render("▎")
+		if i == m.cursor {
+			border = selectedBorderStyle.Render("▎")
+		}
+		prefix := border + " "
+
+		for _, line := range renderHit(item, bodyWidth) {
+			sb.WriteString(prefix + line + "\n")
+			lineCount++
+		}
+		sb.WriteString("\n")
+		lineCount++
+
+		m.offsets[i] = itemOffset{startLine: startLine, endLine: lineCount - 1}
+	}
+	m.viewport.SetContent(sb.String())
+}
+
+// renderHit formats a story or comment hit as display lines.
+func renderHit(item *api.Item, width int) []string {
+	sep := metaStyle.Render(" · ")
+	if item.Type == "story" {
+		title := titleStyle.Render(html.UnescapeString(item.Title))
+		if u, err := url.Parse(item.URL); err == nil && u.Hostname() != "" {
+			title += " " + metaStyle.Render("("+u.Hostname()+")")
+		}
+		meta := metaStyle.Render(fmt.Sprintf("%d points", item.Score)) + sep +
+			authorStyle.Render(item.By) + sep +
+			metaStyle.Render(render.TimeAgo(item.Time)) + sep +
+			metaStyle.Render(fmt.Sprintf("%d comments", item.Descendants))
+		return []string{title, meta}
+	}
+
+	meta := authorStyle.Render(item.By) + sep + metaStyle.Render(render.TimeAgo(item.Time))
+	if item.StoryTitle != "" {
+		meta += sep + metaStyle.Render("on: ") + titleStyle.Render(html.UnescapeString(item.StoryTitle))
+	}
+	lines := []string{meta}
+	body := strings.Split(render.HNToText(item.Text, width), "\n")
+	if len(body) > maxSnippetLines {
+		body = append(body[:maxSnippetLines], metaStyle.Render("[...]"))
+	}
+	return append(lines, body...)
+}
+
+func (m *Model) scrollToCursor() {
+	if m.cursor >= len(m.offsets) {
+		return
+	}
+	off := m.offsets[m.cursor]
+	if off.startLine < m.viewport.YOffset || off.endLine >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(off.startLine)
+	}
+}
@@ -6,46 +6,59 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
 var (
-	barStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333333")).
-			Foreground(lipgloss.Color("#FFFFFF"))
+	barStyle         lipgloss.Style
+	activeTabStyle   lipgloss.Style
+	inactiveTabStyle lipgloss.Style
+	userStyle        lipgloss.Style
+	notifyStyle      lipgloss.Style
+	statusTextStyle  lipgloss.Style
+	offlineStyle     lipgloss.Style
+)
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		barStyle = lipgloss.NewStyle().
+			Background(t.Bar).
+			Foreground(t.BarText)
 
-	activeTabStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#FF6600")).
-			Foreground(lipgloss.Color("#FFFFFF")).
+		activeTabStyle = lipgloss.NewStyle().
+			Background(t.Accent).
+			Foreground(t.OnTab).
 			Bold(true).
 			Padding(0, 1)
 
-	inactiveTabStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#555555")).
-				Foreground(lipgloss.Color("#CCCCCC")).
-				Padding(0, 1)
+		inactiveTabStyle = lipgloss.NewStyle().
+			Background(t.Tab).
+			Foreground(t.TabText).
+			Padding(0, 1)
 
-	userStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333333")).
-			Foreground(lipgloss.Color("#00FF00")).
+		userStyle = lipgloss.NewStyle().
+			Background(t.Bar).
+			Foreground(t.User).
 			Padding(0, 1)
 
-	notifyStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#FF0000")).
-			Foreground(lipgloss.Color("#FFFFFF")).
+		notifyStyle = lipgloss.NewStyle().
+			Background(t.Error).
+			Foreground(t.OnAlert).
 			Bold(true).
 			Padding(0, 1)
 
-	statusTextStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333333")).
-			Foreground(lipgloss.Color("#AAAAAA")).
+		statusTextStyle = lipgloss.NewStyle().
+			Background(t.Bar).
+			Foreground(t.BarDim).
 			Padding(0, 1)
 
-	offlineStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#8B0000")).
-			Foreground(lipgloss.Color("#FFFFFF")).
+		offlineStyle = lipgloss.NewStyle().
+			Background(t.Offline).
+			Foreground(t.OnAlert).
 			Bold(true).
 			Padding(0, 1)
-)
+	})
+}
 
 type tab struct {
 	label     string
@@ -61,6 +74,7 @@ var tabs = []tab{
 	{"Ask", api.StoryTypeAsk},
 	{"Show", api.StoryTypeShow},
 	{"Jobs", api.StoryTypeJobs},
+	{"Bookmarks", api.StoryTypeBookmarks},
 }
 
 // Model is the status bar at the bottom of the screen.
@@ -71,6 +85,7 @@ type Model struct {
 	unreadCount int
 	statusText  string
 	offline     bool
+	saved       []tab // saved search tabs after the built-in ones
 }
 
 // New creates a new status bar.
@@ -93,6 +108,14 @@ func (m *Model) SetUser(username string) {
 	m.username = username
 }
 
+// SetSavedSearches replaces the saved search tabs shown after the built-in tabs.
+func (m *Model) SetSavedSearches(names []string) {
+	m.saved = m.saved[:0]
+	for _, name := range names {
+		m.saved = append(m.saved, tab{name, api.SavedSearchType(name)})
+	}
+}
+
 // SetUnread sets the unread notification count.
 func (m *Model) SetUnread(count int) {
 	m.unreadCount = count
@@ -117,7 +140,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 func (m Model) View() string {
 	// Tabs.
 	var tabsStr string
-	for _, t := range tabs {
+	for _, t := range append(tabs[:len(tabs):len(tabs)], m.saved...) {
 		if t.storyType == m.activeType {
 			tabsStr += activeTabStyle.Render(t.label)
 		} else {
@@ -7,39 +7,54 @@ import (
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
 var (
-	indexStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6600")).
+	indexStyle           lipgloss.Style
+	titleNormal          lipgloss.Style
+	titleSelected        lipgloss.Style
+	domainStyle          lipgloss.Style
+	metaStyle            lipgloss.Style
+	commentStyle         lipgloss.Style
+	commentSelectedStyle lipgloss.Style
+	separatorStyle       lipgloss.Style
+)
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		indexStyle = lipgloss.NewStyle().
+			Foreground(t.Accent).
 			Width(4).
 			Align(lipgloss.Right)
 
-	titleNormal = lipgloss.NewStyle().
+		titleNormal = lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF"))
+			Foreground(t.Title)
 
-	titleSelected = lipgloss.NewStyle().
+		titleSelected = lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FF6600"))
+			Foreground(t.Accent)
 
-	domainStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#828282"))
+		domainStyle = lipgloss.NewStyle().
+			Foreground(t.Meta)
 
-	metaStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#828282"))
+		metaStyle = lipgloss.NewStyle().
+			Foreground(t.Meta)
 
-	commentStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6600"))
+		commentStyle = lipgloss.NewStyle().
+			Foreground(t.Accent)
 
-	commentSelectedStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FF6600")).
-				Bold(true).
-				Underline(true)
+		commentSelectedStyle = lipgloss.NewStyle().
+			Foreground(t.Accent).
+			Bold(true).
+			Underline(true)
 
-	separatorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#555555"))
-)
+		separatorStyle = lipgloss.NewStyle().
+			Foreground(t.Faint)
+	})
+}
 
 type Delegate struct{}
 
@@ -97,4 +112,3 @@ func (d Delegate) renderStory(w io.Writer, idx string, item StoryItem, selected
 
 	fmt.Fprintf(w, "%s %s\n     %s", idx, title, metaStr)
 }
-
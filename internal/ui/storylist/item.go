@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"html"
 	"net/url"
+	"strings"
 
 	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/render"
 )
 
+// maxCommentTitle caps the comment snippet used as a list title.
+const maxCommentTitle = 100
+
 // StoryItem wraps an API item for the bubbles list.
 type StoryItem struct {
 	*api.Item
@@ -19,6 +23,14 @@ func (s StoryItem) Title() string {
 	if s.Item.Title != "" {
 		return html.UnescapeString(s.Item.Title)
 	}
+	if s.Item.Type == "comment" {
+		// Saved search tabs can list comments; show who said what.
+		text := []rune(render.HNToPlainText(s.Item.Text))
+		if len(text) > maxCommentTitle {
+			text = append(text[:maxCommentTitle], '…')
+		}
+		return s.Item.By + ": " + strings.Join(strings.Fields(string(text)), " ")
+	}
 	return fmt.Sprintf("[%s]", s.Item.Type)
 }
 
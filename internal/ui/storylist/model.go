@@ -2,13 +2,16 @@ package storylist
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
 )
 
@@ -20,6 +23,7 @@ type Model struct {
 	cache     *cache.DB
 	cfg       config.Config
 	loading   bool
+	removing  string // saved search waiting on a y/n answer before removal
 	width     int
 	height    int
 }
@@ -32,6 +36,7 @@ func New(cfg config.Config, client *api.Client, db *cache.DB) Model {
 	l.SetShowStatusBar(true)
 	l.SetShowHelp(false)
 	l.SetFilteringEnabled(true)
+	l.KeyMap = keys.Keys.ListKeyMap()
 
 	return Model{
 		list:      l,
@@ -58,56 +63,101 @@ func (m *Model) SetSize(w, h int) {
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case messages.StoriesLoadedMsg:
-		if msg.Err != nil {
-			m.list.Title = "Error: " + msg.Err.Error()
-			m.loading = false
+		if msg.StoryType != m.storyType {
 			return m, nil
 		}
-		if msg.StoryType != m.storyType {
+		if msg.Err != nil {
+			m.loading = false
+			if errors.Is(msg.Err, api.ErrOffline) {
+				m.list.SetItems(nil)
+				m.list.Title = storyTypeTitle(m.storyType) + ": offline, nothing cached yet"
+				return m, nil
+			}
+			m.list.Title = "Error: " + msg.Err.Error()
 			return m, nil
 		}
+		kf, _ := m.cache.LoadKillfile()
 		items := make([]list.Item, 0, len(msg.Items))
 		for i, item := range msg.Items {
-			if item != nil {
+			if item != nil && !kf.HidesStory(item) {
 				items = append(items, StoryItem{Item: item, Index: i})
 			}
 		}
 		m.list.SetItems(items)
 		m.list.Title = storyTypeTitle(m.storyType)
+		if msg.Stale {
+			m.list.Title += " (cached)"
+		}
 		m.loading = false
 		return m, nil
 
 	case messages.SwitchTabMsg:
+		m.removing = ""
 		m.storyType = msg.StoryType
 		m.list.Title = storyTypeTitle(m.storyType) + " (loading...)"
 		m.loading = true
 		return m, m.loadStories()
 
 	case tea.KeyMsg:
+		if m.removing != "" {
+			return m.updateRemoving(msg)
+		}
 		if m.list.FilterState() == list.Filtering {
 			break
 		}
-		switch msg.String() {
-		case "enter":
+		km := keys.Keys.List
+		switch {
+		case key.Matches(msg, keys.Keys.Nav.Open):
 			if item, ok := m.list.SelectedItem().(StoryItem); ok {
 				return m, func() tea.Msg {
 					return messages.OpenStoryMsg{StoryID: item.Item.ID}
 				}
 			}
-		case "o":
+		case key.Matches(msg, keys.Keys.Nav.Browser):
 			if item, ok := m.list.SelectedItem().(StoryItem); ok {
 				u := item.Item.URL
 				if u == "" {
-					u = fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.Item.ID)
+					u = m.client.Endpoints().ItemURL(item.Item.ID)
 				}
 				return m, func() tea.Msg {
 					return messages.StatusMsg{Text: "Opening: " + u}
 				}
 			}
-		case "r", "ctrl+r":
-			m.loading = true
-			m.list.Title = storyTypeTitle(m.storyType) + " (refreshing...)"
-			return m, m.loadStoriesForce()
+		case key.Matches(msg, km.Upvote):
+			if item, ok := m.list.SelectedItem().(StoryItem); ok {
+				id := item.Item.ID
+				return m, func() tea.Msg { return messages.VoteMsg{ItemID: id} }
+			}
+		case key.Matches(msg, km.Bookmark):
+			if item, ok := m.list.SelectedItem().(StoryItem); ok {
+				story := item.Item
+				return m, func() tea.Msg { return messages.ToggleBookmarkMsg{Item: story} }
+			}
+		case key.Matches(msg, km.Hide):
+			if item, ok := m.list.SelectedItem().(StoryItem); ok {
+				if err := m.cache.HideStory(item.Item.ID); err != nil {
+					return m, func() tea.Msg {
+						return messages.StatusMsg{Text: "Hide failed: " + err.Error(), IsError: true}
+					}
+				}
+				m.list.RemoveItem(m.list.Index())
+				id := item.Item.ID
+				return m, func() tea.Msg { return messages.StoryHiddenMsg{ID: id} }
+			}
+		case key.Matches(msg, km.WatchDomain):
+			if item, ok := m.list.SelectedItem().(StoryItem); ok && item.Domain() != "" {
+				domain := item.Domain()
+				return m, func() tea.Msg {
+					return messages.ToggleAlertMsg{Kind: cache.AlertDomain, Pattern: domain}
+				}
+			}
+		case key.Matches(msg, km.RemoveSavedSearch):
+			if name, ok := m.storyType.SavedSearchName(); ok {
+				m.removing = name
+				return m, nil
+			}
+		case key.Matches(msg, km.Refresh):
+			return m.Refresh()
 		}
 	}
 
@@ -116,11 +166,38 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateRemoving answers the question asked before removing a saved
+// search tab: y removes it, any other key keeps it.
+func (m Model) updateRemoving(msg tea.KeyMsg) (Model, tea.Cmd) {
+	name := m.removing
+	m.removing = ""
+	if !key.Matches(msg, keys.Keys.Form.Confirm) {
+		return m, nil
+	}
+	return m, func() tea.Msg { return messages.DeleteSearchMsg{Name: name} }
+}
+
 // View renders the story list.
 func (m Model) View() string {
+	if m.removing != "" {
+		l := m.list
+		l.Title = fmt.Sprintf("Remove saved search %q? (%s/n)", m.removing, keys.Keys.Form.Confirm.Help().Key)
+		return l.View()
+	}
 	return m.list.View()
 }
 
+// Filtering reports whether the filter prompt has focus.
+func (m Model) Filtering() bool {
+	return m.list.FilterState() == list.Filtering
+}
+
+// Removing reports whether the view is asking before removing a saved
+// search tab, in which case it takes the next key itself.
+func (m Model) Removing() bool {
+	return m.removing != ""
+}
+
 // StoryType returns the current story type.
 func (m Model) StoryType() api.StoryType {
 	return m.storyType
@@ -135,8 +212,7 @@ func (m Model) loadStories() tea.Cmd {
 	// Past stories use Algolia (returns stories, not comments).
 	if st == api.StoryTypePast {
 		return func() tea.Msg {
-			items, err := client.GetPastStories(context.Background(), cfg.FetchPageSize)
-			return messages.StoriesLoadedMsg{StoryType: st, Items: items, Err: err}
+			return loadPast(client, db, cfg)
 		}
 	}
 
@@ -158,17 +234,25 @@ func (m Model) loadStoriesForce() tea.Cmd {
 
 	if st == api.StoryTypePast {
 		return func() tea.Msg {
-			items, err := client.GetPastStories(context.Background(), cfg.FetchPageSize)
-			return messages.StoriesLoadedMsg{StoryType: st, Items: items, Err: err}
+			return loadPast(client, db, cfg)
 		}
 	}
 
 	return func() tea.Msg {
-		db.InvalidateStoryList(string(st))
-		return fetchAndCache(st, client, db, cfg, nil)
+		// Keep the cached list as a fallback in case the fetch fails.
+		ids, _, _ := db.GetStoryList(string(st), cfg.StoryListTTL)
+		return fetchAndCache(st, client, db, cfg, ids)
 	}
 }
 
+// Refresh reloads the current tab from the network, keeping what's shown
+// if the fetch fails.
+func (m Model) Refresh() (Model, tea.Cmd) {
+	m.loading = true
+	m.list.Title = storyTypeTitle(m.storyType) + " (refreshing...)"
+	return m, m.loadStoriesForce()
+}
+
 func loadItemsFromCache(st api.StoryType, ids []int, db *cache.DB, cfg config.Config) messages.StoriesLoadedMsg {
 	limit := cfg.FetchPageSize
 	if limit > len(ids) {
@@ -182,15 +266,22 @@ func loadItemsFromCache(st api.StoryType, ids []int, db *cache.DB, cfg config.Co
 	return messages.StoriesLoadedMsg{StoryType: st, Items: items}
 }
 
+// staleFromCache serves a cached list after a failed fetch, or reports
+// err if nothing is cached.
+func staleFromCache(st api.StoryType, ids []int, db *cache.DB, cfg config.Config, err error) messages.StoriesLoadedMsg {
+	if len(ids) == 0 {
+		return messages.StoriesLoadedMsg{StoryType: st, Err: err}
+	}
+	msg := loadItemsFromCache(st, ids, db, cfg)
+	msg.Stale = true
+	return msg
+}
+
 func fetchAndCache(st api.StoryType, client *api.Client, db *cache.DB, cfg config.Config, fallbackIDs []int) messages.StoriesLoadedMsg {
 	ctx := context.Background()
-	ids, err := client.GetStoryIDs(ctx, st)
+	ids, err := storyIDs(ctx, st, client, db, cfg)
 	if err != nil {
-		// Use cached IDs if available.
-		if len(fallbackIDs) > 0 {
-			return loadItemsFromCache(st, fallbackIDs, db, cfg)
-		}
-		return messages.StoriesLoadedMsg{StoryType: st, Err: err}
+		return staleFromCache(st, fallbackIDs, db, cfg, err)
 	}
 
 	limit := cfg.FetchPageSize
@@ -201,17 +292,72 @@ func fetchAndCache(st api.StoryType, client *api.Client, db *cache.DB, cfg confi
 
 	fetchIDs := ids[:limit]
 	items, err := client.BatchGetItems(ctx, fetchIDs)
-	if err != nil {
-		return messages.StoriesLoadedMsg{StoryType: st, Err: err}
+	if err != nil && !api.IsPartial(err) {
+		return staleFromCache(st, fetchIDs, db, cfg, err)
 	}
-	for _, item := range items {
+	stale := false
+	for i, item := range items {
 		if item != nil {
 			db.PutItem(item)
+			continue
 		}
+		// Fill in items that failed to fetch from the cache.
+		if cached, _, _ := db.GetItem(fetchIDs[i], cfg.ItemTTL); cached != nil {
+			items[i] = cached
+			stale = true
+		}
+	}
+	return messages.StoriesLoadedMsg{StoryType: st, Items: items, Stale: stale}
+}
+
+// loadPast fetches yesterday's front page from Algolia. Only the ID list
+// is cached: Algolia hits lack kids, so storing them would hide comments
+// of stories already cached from Firebase. Offline, the list is rebuilt
+// from whichever of those stories are cached.
+func loadPast(client *api.Client, db *cache.DB, cfg config.Config) messages.StoriesLoadedMsg {
+	st := api.StoryTypePast
+	items, err := client.GetPastStories(context.Background(), cfg.FetchPageSize)
+	if err != nil {
+		ids, _, _ := db.GetStoryList(string(st), cfg.StoryListTTL)
+		return staleFromCache(st, ids, db, cfg, err)
+	}
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
 	}
+	db.PutStoryList(string(st), ids)
 	return messages.StoriesLoadedMsg{StoryType: st, Items: items}
 }
 
+// storyIDs returns the ranked item IDs for a tab: the Firebase list for the
+// built-in tabs, or the Algolia hit IDs for a saved search.
+func storyIDs(ctx context.Context, st api.StoryType, client *api.Client, db *cache.DB, cfg config.Config) ([]int, error) {
+	name, ok := st.SavedSearchName()
+	if !ok {
+		return client.GetStoryIDs(ctx, st)
+	}
+	saved := db.GetSavedSearch(name)
+	if saved == nil {
+		return nil, fmt.Errorf("no saved search named %q", name)
+	}
+	q, err := api.ParseQuery(saved.Query)
+	if err != nil {
+		return nil, fmt.Errorf("saved search %q: %w", name, err)
+	}
+	params := q.ParamsWithTags(saved.Tags)
+	params.ByDate = saved.ByDate
+	params.HitsPerPage = cfg.FetchPageSize
+	result, err := client.Search(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(result.Items))
+	for _, item := range result.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids, nil
+}
+
 func storyTypeTitle(st api.StoryType) string {
 	switch st {
 	case api.StoryTypeTop:
@@ -229,6 +375,9 @@ func storyTypeTitle(st api.StoryType) string {
 	case api.StoryTypePast:
 		return "Past"
 	default:
+		if name, ok := st.SavedSearchName(); ok {
+			return "Search: " + name
+		}
 		return "Hacker News"
 	}
 }
@@ -0,0 +1,70 @@
+package storyview
+
+// Reading history: the first time a story is loaded in this view, the
+// previous visit's comment IDs are read from the cache and every comment
+// not among them is marked new. The visit is then recorded, so the next
+// visit compares against what is on screen now.
+
+// recordVisit loads the previous visit (once per view) and records this
+// one with every comment of the story currently in the cache.
+func (m *Model) recordVisit() {
+	if m.story == nil {
+		return
+	}
+	m.loadVisit()
+	ids := m.threadIDs()
+	m.newCount = 0
+	if m.visit != nil {
+		for _, id := range ids {
+			if !m.visit.Seen[id] {
+				m.newCount++
+			}
+		}
+	}
+	m.cache.RecordVisit(m.story.ID, ids)
+}
+
+// loadVisit reads the previous visit, once, so comments can be badged
+// while the thread loads.
+func (m *Model) loadVisit() {
+	if !m.visitLoaded {
+		m.visit, _ = m.cache.GetVisit(m.story.ID)
+		m.visitLoaded = true
+	}
+}
+
+// isNew reports whether a comment arrived since the previous visit.
+// Nothing is new on a first visit.
+func (m Model) isNew(id int) bool {
+	return m.visit != nil && id != m.story.ID && !m.visit.Seen[id]
+}
+
+// threadIDs returns the IDs of every cached comment under the story,
+// including ones hidden in collapsed subtrees.
+func (m Model) threadIDs() []int {
+	var ids []int
+	var walk func(kids []int)
+	walk = func(kids []int) {
+		for _, id := range kids {
+			item, _, _ := m.cache.GetItem(id, m.cfg.CommentTTL)
+			if item == nil {
+				continue
+			}
+			ids = append(ids, id)
+			walk(item.Kids())
+		}
+	}
+	walk(m.story.Kids())
+	return ids
+}
+
+// findNew returns the index of the next (dir 1) or previous (dir -1) new
+// comment from the cursor, or -1 if there is none.
+func (m Model) findNew(dir int) int {
+	for i := m.selectedIdx + dir; i >= 0 && i < len(m.comments); i += dir {
+		if m.comments[i].IsNew {
+			return i
+		}
+	}
+	return -1
+}
@@ -9,4 +9,7 @@ type FlatComment struct {
 	IsCollapsed bool
 	ChildCount  int
 	IsOP        bool
+	IsNew       bool // arrived since the previous visit
+	IsMuted     bool // author is in the killfile
+	Failed      bool // couldn't be fetched; Item holds only ID and Parent
 }
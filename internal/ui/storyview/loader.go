@@ -0,0 +1,132 @@
+package storyview
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/tree"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/messages"
+)
+
+// loadBatchSize is how many queued comments one batch fetches before
+// handing them to the view.
+const loadBatchSize = 100
+
+// startLoading queues the story's top-level comments and fetches the
+// first batch. Each batch queues the replies it finds, so the tree loads
+// level by level and the view fills in as batches arrive.
+func (m *Model) startLoading() tea.Cmd {
+	m.loadGen++
+	m.pending = append([]int(nil), m.story.Kids()...)
+	m.loaded = 0
+	m.failed = make(map[int]bool)
+	return m.nextBatch()
+}
+
+// nextBatch fetches the next batch of queued comments, or ends the load
+// and records the visit if none are left.
+func (m *Model) nextBatch() tea.Cmd {
+	if len(m.pending) == 0 {
+		m.loading = false
+		m.reload = false
+		m.recordVisit()
+		return nil
+	}
+	n := min(len(m.pending), loadBatchSize)
+	ids := m.pending[:n]
+	m.pending = m.pending[n:]
+
+	client, db, cfg := m.client, m.cache, m.cfg
+	storyID, gen, reload, prefetched := m.story.ID, m.loadGen, m.reload, m.treeLoaded
+	return func() tea.Msg {
+		l := tree.Loader{Client: client, DB: db, TTL: cfg.CommentTTL, Reuse: func(cached *api.Item, fresh bool) bool {
+			if prefetched {
+				// Prefetched leaves need no request. Comments with
+				// replies are refetched for HN's ordering of them and
+				// for any replies Algolia hasn't indexed yet.
+				return len(cached.Kids()) == 0
+			}
+			return !reload && fresh
+		}}
+		// Failures keep the stale copy, if any; nil marks them failed.
+		items, _ := l.Level(context.Background(), ids)
+
+		msg := messages.CommentsBatchMsg{StoryID: storyID, Gen: gen}
+		for i, item := range items {
+			if item == nil {
+				msg.Failed = append(msg.Failed, ids[i])
+				continue
+			}
+			if !item.Deleted && !item.Dead {
+				msg.Live++
+			}
+			msg.Kids = append(msg.Kids, item.Kids()...)
+		}
+		return msg
+	}
+}
+
+// commentsCached reports whether every comment in ids is fresh in the
+// cache, so prefetching the tree would gain nothing.
+func commentsCached(db *cache.DB, cfg config.Config, ids []int) bool {
+	for _, id := range ids {
+		if _, fresh, _ := db.GetItem(id, cfg.CommentTTL); !fresh {
+			return false
+		}
+	}
+	return true
+}
+
+// Resume restarts a comment load that stopped while the view sat in the
+// app's cache behind another story. Fresh comments come from the cache.
+func (m Model) Resume() (Model, tea.Cmd) {
+	if !m.loading || m.story == nil {
+		return m, nil
+	}
+	cmd := m.startLoading()
+	return m, cmd
+}
+
+// rebuildKeepingPlace rebuilds the list as comments stream in, keeping
+// the selected comment selected and where it was on screen.
+func (m *Model) rebuildKeepingPlace() {
+	if m.selectedIdx >= len(m.comments) || m.selectedIdx >= len(m.offsets) {
+		m.rebuildComments()
+		m.rebuildContent()
+		return
+	}
+	id := m.comments[m.selectedIdx].Item.ID
+	line := m.offsets[m.selectedIdx].startLine
+	m.rebuildComments()
+	for i, fc := range m.comments {
+		if fc.Item.ID == id {
+			m.selectedIdx = i
+			break
+		}
+	}
+	m.rebuildContent()
+	if m.selectedIdx < len(m.offsets) {
+		m.viewport.SetYOffset(m.viewport.YOffset + m.offsets[m.selectedIdx].startLine - line)
+	}
+}
+
+// commentCount describes how much of the thread is loaded. The story's
+// Descendants can lag behind or run ahead of what is actually fetched;
+// once loading finishes the fetched count wins.
+func (m Model) commentCount() string {
+	total := max(m.story.Descendants, m.loaded)
+	switch {
+	case m.loading:
+		return fmt.Sprintf("%d/%d comments", m.loaded, total)
+	case len(m.failed) > 0:
+		return fmt.Sprintf("%d/%d comments, %d failed to load (%s to retry)",
+			m.loaded, total, len(m.failed), keys.Keys.Story.Refresh.Help().Key)
+	}
+	return fmt.Sprintf("%d comments", m.loaded)
+}
@@ -2,13 +2,17 @@ package storyview
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"math"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -16,26 +20,41 @@ import (
 	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/export"
 	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
 var (
-	depthColors = []lipgloss.Color{
-		"#FF6600", "#828282", "#00BFFF", "#32CD32", "#FFD700", "#FF69B4", "#9370DB", "#20B2AA",
-	}
-
-	commentAuthorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	commentMetaStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
-	commentOPStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#000")).Background(lipgloss.Color("#FF6600")).Bold(true)
-	commentSelStyle    = lipgloss.NewStyle().Background(lipgloss.Color("#333333"))
-	commentDelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#555555")).Italic(true)
-	storyHeaderStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFFFFF")).Padding(0, 1)
-	storyMetaStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282")).Padding(0, 1)
-	storyURLStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282")).Padding(0, 1)
-	separatorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#444444"))
+	commentAuthorStyle lipgloss.Style
+	commentMetaStyle   lipgloss.Style
+	commentOPStyle     lipgloss.Style
+	commentSelStyle    lipgloss.Style
+	commentDelStyle    lipgloss.Style
+	commentNewStyle    lipgloss.Style
+	storyHeaderStyle   lipgloss.Style
+	storyMetaStyle     lipgloss.Style
+	storyURLStyle      lipgloss.Style
+	separatorStyle     lipgloss.Style
 )
 
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		commentAuthorStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		commentMetaStyle = lipgloss.NewStyle().Foreground(t.Dim)
+		commentOPStyle = lipgloss.NewStyle().Foreground(t.OnAccent).Background(t.Accent).Bold(true)
+		commentSelStyle = lipgloss.NewStyle().Background(t.Selection)
+		commentDelStyle = lipgloss.NewStyle().Foreground(t.Faint).Italic(true)
+		commentNewStyle = lipgloss.NewStyle().Foreground(t.OnAccent).Background(t.New).Bold(true)
+		storyHeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Title).Padding(0, 1)
+		storyMetaStyle = lipgloss.NewStyle().Foreground(t.Meta).Padding(0, 1)
+		storyURLStyle = lipgloss.NewStyle().Foreground(t.Meta).Padding(0, 1)
+		separatorStyle = lipgloss.NewStyle().Foreground(t.Border)
+	})
+}
+
 const scrollStep = 3
 
 type commentOffset struct {
@@ -56,6 +75,19 @@ type Model struct {
 	cfg         config.Config
 	username    string
 	loading     bool
+	reload      bool         // refetch comments even if cached
+	treeLoaded  bool         // comments were prefetched from Algolia; see nextBatch
+	loadGen     int          // tells batches of a superseded load apart
+	pending     []int        // comment IDs queued for loading, breadth-first
+	loaded      int          // comments loaded so far, not counting deleted ones
+	failed      map[int]bool // comments that failed to load, with their replies
+	pollOpts    []*api.Item
+	pollIdx     int
+	choosing    bool         // picking a poll option to vote for
+	visit       *cache.Visit // previous visit; nil on the first
+	killfile    *cache.Killfile
+	visitLoaded bool
+	newCount    int
 	width       int
 	height      int
 }
@@ -63,6 +95,7 @@ type Model struct {
 // New creates a new story view.
 func New(storyID int, cfg config.Config, client *api.Client, db *cache.DB, username string) Model {
 	vp := viewport.New(0, 0)
+	vp.KeyMap = keys.Keys.Nav.ViewportKeyMap()
 	vp.SetContent("Loading...")
 
 	return Model{
@@ -79,44 +112,61 @@ func New(storyID int, cfg config.Config, client *api.Client, db *cache.DB, usern
 
 // Init loads the story and its comments.
 func (m Model) Init(storyID int) tea.Cmd {
+	return m.load(storyID, false)
+}
+
+// Refresh refetches the story and its comments. Whatever is cached stays
+// on screen if the network is unavailable.
+func (m Model) Refresh() (Model, tea.Cmd) {
+	if m.story == nil {
+		return m, nil
+	}
+	m.loading = true
+	m.reload = true
+	m.viewport.SetContent("  Refreshing...")
+	return m, m.load(m.story.ID, true)
+}
+
+// load fetches the story (if not cached, or always when refresh is set)
+// and its poll options if any into the cache. Uncached comments are
+// prefetched as a whole tree from Algolia where possible; either way they
+// then load in batches, see startLoading. Fetch failures fall back to
+// cached copies, so the view works offline.
+func (m Model) load(storyID int, refresh bool) tea.Cmd {
 	client := m.client
 	db := m.cache
 	cfg := m.cfg
 	return func() tea.Msg {
 		ctx := context.Background()
 		story, _, _ := db.GetItem(storyID, cfg.ItemTTL)
-		if story == nil {
-			var err error
-			story, err = client.GetItem(ctx, storyID)
-			if err != nil {
+		if story == nil || refresh {
+			fetched, err := client.GetItem(ctx, storyID)
+			switch {
+			case err == nil:
+				db.PutItem(fetched)
+				story = fetched
+			case story == nil:
+				if errors.Is(err, api.ErrOffline) {
+					err = errors.New("offline, and this story isn't cached yet")
+				}
 				return messages.CommentsLoadedMsg{StoryID: storyID, Err: err}
 			}
-			db.PutItem(story)
 		}
 
-		// Fetch top-level comments.
-		kids := story.Kids()
-		if len(kids) > 0 {
-			items, _ := client.BatchGetItems(ctx, kids)
-			// Collect all nested kid IDs, then fetch in one batch.
-			var allNestedIDs []int
-			for _, item := range items {
-				if item != nil {
-					db.PutItem(item)
-					allNestedIDs = append(allNestedIDs, item.Kids()...)
-				}
-			}
-			if len(allNestedIDs) > 0 {
-				nested, _ := client.BatchGetItems(ctx, allNestedIDs)
-				for _, n := range nested {
-					if n != nil {
-						db.PutItem(n)
-					}
-				}
+		if parts := story.Parts(); len(parts) > 0 {
+			fetchPollOptions(ctx, client, db, cfg, parts, refresh)
+		}
+
+		// The story itself stays the Firebase copy, with its current
+		// score and kids order.
+		tree := false
+		if kids := story.Kids(); len(kids) > 0 && (refresh || !commentsCached(db, cfg, kids)) {
+			if items, err := client.GetItemTree(ctx, storyID); err == nil && len(items) > 1 {
+				tree = db.PutItems(items[1:]) == nil
 			}
 		}
 
-		return messages.CommentsLoadedMsg{StoryID: storyID, Items: []*api.Item{story}}
+		return messages.CommentsLoadedMsg{StoryID: storyID, Items: []*api.Item{story}, Tree: tree}
 	}
 }
 
@@ -150,15 +200,43 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		if len(msg.Items) > 0 {
 			m.story = msg.Items[0]
 		}
-		m.loading = false
+		m.treeLoaded = msg.Tree
+		m.killfile, _ = m.cache.LoadKillfile()
+		m.loadPollOptions()
+		m.loadVisit()
+		cmd := m.startLoading()
 		m.resizeViewport()
 		m.rebuildComments()
 		m.rebuildContent()
+		return m, cmd
+
+	case messages.CommentsBatchMsg:
+		if m.story == nil || msg.StoryID != m.story.ID || msg.Gen != m.loadGen {
+			return m, nil
+		}
+		m.pending = append(m.pending, msg.Kids...)
+		m.loaded += msg.Live
+		for _, id := range msg.Failed {
+			m.failed[id] = true
+		}
+		cmd := m.nextBatch()
+		m.resizeViewport()
+		m.rebuildKeepingPlace()
+		return m, cmd
+
+	case messages.VoteResultMsg:
+		if msg.Err == nil {
+			m.votedOption(msg.ItemID)
+		}
 		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "j", "down":
+		if m.choosing {
+			return m.updateChoosing(msg)
+		}
+		km, nav := keys.Keys.Story, keys.Keys.Nav
+		switch {
+		case key.Matches(msg, nav.Down):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.offsets) {
 				off := m.offsets[m.selectedIdx]
 				viewBottom := m.viewport.YOffset + m.viewport.Height
@@ -174,7 +252,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.scrollToCursor()
 			}
 			return m, nil
-		case "k", "up":
+		case key.Matches(msg, nav.Up):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.offsets) {
 				off := m.offsets[m.selectedIdx]
 				if off.startLine < m.viewport.YOffset {
@@ -193,26 +271,26 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.scrollToCursor()
 			}
 			return m, nil
-		case "enter":
+		case key.Matches(msg, nav.Open):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
 				storyID := m.comments[m.selectedIdx].Item.ID
 				return m, func() tea.Msg { return messages.OpenStoryMsg{StoryID: storyID} }
 			}
 			return m, nil
-		case " ":
+		case key.Matches(msg, km.Collapse):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
-				id := m.comments[m.selectedIdx].Item.ID
-				m.collapse[id] = !m.collapse[id]
+				fc := m.comments[m.selectedIdx]
+				m.collapse[fc.Item.ID] = !fc.IsCollapsed
 				m.rebuildComments()
 				// After collapsing, advance to next sibling/comment.
-				if m.collapse[id] && m.selectedIdx+1 < len(m.comments) {
+				if !fc.IsCollapsed && m.selectedIdx+1 < len(m.comments) {
 					m.selectedIdx++
 				}
 				m.rebuildContent()
 				m.scrollToCursor()
 			}
 			return m, nil
-		case "Z":
+		case key.Matches(msg, km.CollapseAll):
 			// Collapse all.
 			m.setCollapseAll(true)
 			m.rebuildComments()
@@ -220,7 +298,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.viewport.GotoTop()
 			m.selectedIdx = 0
 			return m, nil
-		case "z":
+		case key.Matches(msg, km.FoldAll):
 			// Toggle: if any expanded, collapse all; otherwise expand all.
 			if m.hasAnyExpanded() {
 				m.setCollapseAll(true)
@@ -234,7 +312,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				m.rebuildContent()
 			}
 			return m, nil
-		case "[", "p", "h":
+		case key.Matches(msg, km.Parent):
 			if idx := FindParentIndex(m.comments, m.selectedIdx); idx >= 0 {
 				m.selectedIdx = idx
 				m.rebuildContent()
@@ -249,18 +327,28 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 			return m, nil
-		case "]":
+		case key.Matches(msg, km.NextSibling):
 			if idx := FindNextSiblingIndex(m.comments, m.selectedIdx); idx >= 0 {
 				m.selectedIdx = idx
 				m.rebuildContent()
 				m.scrollToCursor()
 			}
 			return m, nil
-		case "l":
+		case key.Matches(msg, km.NextNew, km.PrevNew):
+			dir := 1
+			if key.Matches(msg, km.PrevNew) {
+				dir = -1
+			}
+			if idx := m.findNew(dir); idx >= 0 {
+				m.selectedIdx = idx
+				m.rebuildContent()
+				m.scrollToCursor()
+			}
+			return m, nil
+		case key.Matches(msg, km.Child):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
-				id := m.comments[m.selectedIdx].Item.ID
-				if m.collapse[id] {
-					m.collapse[id] = false
+				if fc := m.comments[m.selectedIdx]; fc.IsCollapsed {
+					m.collapse[fc.Item.ID] = false
 					m.rebuildComments()
 					m.rebuildContent()
 				}
@@ -273,25 +361,25 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 			return m, nil
-		case "g", "home":
+		case key.Matches(msg, nav.Home):
 			m.selectedIdx = 0
 			m.rebuildContent()
 			m.viewport.GotoTop()
 			return m, nil
-		case "G", "end":
+		case key.Matches(msg, nav.End):
 			if len(m.comments) > 0 {
 				m.selectedIdx = len(m.comments) - 1
 				m.rebuildContent()
 				m.viewport.GotoBottom()
 			}
 			return m, nil
-		case "r":
+		case key.Matches(msg, km.Reply):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
 				parentID := m.comments[m.selectedIdx].Item.ID
 				return m, func() tea.Msg { return messages.OpenReplyMsg{ParentID: parentID} }
 			}
 			return m, nil
-		case "^":
+		case key.Matches(msg, km.Root):
 			if m.story == nil || m.story.Parent == 0 {
 				return m, nil // already at root
 			}
@@ -313,33 +401,66 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			}
 			rootID := current.ID
 			return m, func() tea.Msg { return messages.OpenStoryMsg{StoryID: rootID} }
-		case "ctrl+r":
-			if m.story != nil {
-				m.loading = true
-				m.cache.InvalidateItem(m.story.ID)
-				m.viewport.SetContent("  Refreshing...")
-				return m, m.Init(m.story.ID)
-			}
-			return m, nil
-		case "o":
+		case key.Matches(msg, km.Refresh):
+			return m.Refresh()
+		case key.Matches(msg, nav.Browser):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
 				id := m.comments[m.selectedIdx].Item.ID
-				return m, openURL(fmt.Sprintf("https://news.ycombinator.com/item?id=%d", id))
+				return m, openURL(m.client.Endpoints().ItemURL(id))
 			}
 			if m.story != nil {
 				if m.story.URL != "" {
 					return m, openURL(m.story.URL)
 				}
-				return m, openURL(fmt.Sprintf("https://news.ycombinator.com/item?id=%d", m.story.ID))
+				return m, openURL(m.client.Endpoints().ItemURL(m.story.ID))
+			}
+			return m, nil
+		case key.Matches(msg, km.Upvote):
+			id := 0
+			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
+				id = m.comments[m.selectedIdx].Item.ID
+			} else if m.story != nil {
+				id = m.story.ID
+			}
+			if id == 0 {
+				return m, nil
+			}
+			return m, func() tea.Msg { return messages.VoteMsg{ItemID: id} }
+		case key.Matches(msg, km.Bookmark):
+			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
+				item := m.comments[m.selectedIdx].Item
+				return m, func() tea.Msg { return messages.ToggleBookmarkMsg{Item: item} }
+			}
+			return m, nil
+		case key.Matches(msg, km.BookmarkStory):
+			if m.story != nil {
+				story := m.story
+				return m, func() tea.Msg { return messages.ToggleBookmarkMsg{Item: story} }
+			}
+			return m, nil
+		case key.Matches(msg, km.Vote):
+			if len(m.pollOpts) > 0 {
+				m.choosing = true
+				m.resizeViewport()
+			}
+			return m, nil
+		case key.Matches(msg, km.Export):
+			if m.story != nil {
+				return m, exportStory(m.client, m.cache, m.cfg, m.story.ID)
 			}
 			return m, nil
-		case "ctrl+d", "pgdown":
+		case key.Matches(msg, nav.PageDown):
 			m.viewport.HalfViewDown()
 			return m, nil
-		case "ctrl+u", "pgup":
+		case key.Matches(msg, nav.PageUp):
 			m.viewport.HalfViewUp()
 			return m, nil
-		case "P":
+		case key.Matches(msg, km.Mute):
+			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
+				return m.toggleMute(m.comments[m.selectedIdx].Item.By)
+			}
+			return m, nil
+		case key.Matches(msg, km.Profile):
 			if m.selectedIdx >= 0 && m.selectedIdx < len(m.comments) {
 				username := m.comments[m.selectedIdx].Item.By
 				if username != "" {
@@ -347,7 +468,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 			return m, nil
-		case "e":
+		case key.Matches(msg, km.Edit):
 			if m.selectedIdx < 0 || m.selectedIdx >= len(m.comments) {
 				return m, nil
 			}
@@ -390,6 +511,29 @@ func (m Model) Story() *api.Item {
 	return m.story
 }
 
+// toggleMute adds or removes a killfile rule muting username, and
+// rebuilds the thread with the new rules.
+func (m Model) toggleMute(username string) (Model, tea.Cmd) {
+	if username == "" {
+		return m, nil
+	}
+	on, err := m.cache.ToggleKillRule(cache.KillUser, username)
+	if err != nil {
+		return m, func() tea.Msg {
+			return messages.StatusMsg{Text: "Mute failed: " + err.Error(), IsError: true}
+		}
+	}
+	m.killfile, _ = m.cache.LoadKillfile()
+	m.rebuildComments()
+	m.rebuildContent()
+	m.scrollToCursor()
+	text := "Muted " + username
+	if !on {
+		text = "Unmuted " + username
+	}
+	return m, func() tea.Msg { return messages.StatusMsg{Text: text} }
+}
+
 // setCollapseAll walks the full comment tree via the cache and sets collapse state.
 func (m *Model) setCollapseAll(collapse bool) {
 	if m.story == nil {
@@ -446,7 +590,7 @@ func (m *Model) rebuildComments() {
 	}
 	if m.story.Type == "comment" {
 		// Include the root comment itself as the first selectable item.
-		kids := FlattenTree(m.story.Kids(), m.story.By, m.cache, m.cfg, m.collapse)
+		kids := FlattenTree(m.story, m.cache, m.cfg, m.collapse, m.killfile, m.failed)
 		root := FlatComment{
 			Item:       m.story,
 			Depth:      0,
@@ -454,7 +598,10 @@ func (m *Model) rebuildComments() {
 		}
 		m.comments = append([]FlatComment{root}, kids...)
 	} else {
-		m.comments = FlattenTree(m.story.Kids(), m.story.By, m.cache, m.cfg, m.collapse)
+		m.comments = FlattenTree(m.story, m.cache, m.cfg, m.collapse, m.killfile, m.failed)
+	}
+	for i := range m.comments {
+		m.comments[i].IsNew = m.isNew(m.comments[i].Item.ID)
 	}
 	if m.selectedIdx >= len(m.comments) {
 		m.selectedIdx = len(m.comments) - 1
@@ -488,13 +635,23 @@ func (m *Model) rebuildContent() {
 		indent := int(math.Min(float64(fc.Depth*2), 30))
 		indentStr := strings.Repeat(" ", indent)
 
-		barColor := depthColors[fc.Depth%len(depthColors)]
+		barColor := theme.Current().DepthColor(fc.Depth)
 		selected := i == m.selectedIdx
 		if selected {
-			barColor = "#00BFFF"
+			barColor = theme.Current().Highlight
 		}
 		bar := lipgloss.NewStyle().Foreground(barColor).Render("│")
 
+		if fc.Failed {
+			line := indentStr + bar + " " + commentDelStyle.Render("[failed to load; "+keys.Keys.Story.Refresh.Help().Key+" to retry]")
+			if selected {
+				line = commentSelStyle.Render(line)
+			}
+			sb.WriteString(line + "\n")
+			lineCount++
+			m.offsets[i] = commentOffset{startLine: startLine, endLine: lineCount - 1}
+			continue
+		}
 		if fc.Item.Deleted {
 			line := indentStr + bar + " " + commentDelStyle.Render("[deleted]")
 			sb.WriteString(line + "\n")
@@ -510,6 +667,20 @@ func (m *Model) rebuildContent() {
 			continue
 		}
 
+		if fc.IsMuted && fc.IsCollapsed {
+			line := indentStr + bar + " " + commentDelStyle.Render("[muted: "+fc.Item.By+"]")
+			if fc.ChildCount > 0 {
+				line += " " + commentMetaStyle.Render(fmt.Sprintf("[+%d]", fc.ChildCount))
+			}
+			if selected {
+				line = commentSelStyle.Render(line)
+			}
+			sb.WriteString(line + "\n")
+			lineCount++
+			m.offsets[i] = commentOffset{startLine: startLine, endLine: lineCount - 1}
+			continue
+		}
+
 		// Header: author + time + score + collapse indicator.
 		header := commentAuthorStyle.Render(fc.Item.By)
 		header += " " + commentMetaStyle.Render(render.TimeAgo(fc.Item.Time))
@@ -519,6 +690,9 @@ func (m *Model) rebuildContent() {
 		if fc.IsOP {
 			header += " " + commentOPStyle.Render(" OP ")
 		}
+		if fc.IsNew {
+			header += " " + commentNewStyle.Render(" NEW ")
+		}
 		if fc.IsCollapsed {
 			header += " " + commentMetaStyle.Render(fmt.Sprintf("[+%d]", fc.ChildCount))
 		}
@@ -581,10 +755,12 @@ func (m Model) renderHeader() string {
 	if m.story.Title != "" {
 		// Story header.
 		parts = append(parts, storyHeaderStyle.Render(html.UnescapeString(m.story.Title)))
-		parts = append(parts, storyMetaStyle.Render(fmt.Sprintf(
-			"%d points | by %s | %s | %d comments",
-			m.story.Score, m.story.By, render.TimeAgo(m.story.Time), m.story.Descendants,
-		)))
+		meta := fmt.Sprintf("%d points | by %s | %s | %s",
+			m.story.Score, m.story.By, render.TimeAgo(m.story.Time), m.commentCount())
+		if m.newCount > 0 {
+			meta += fmt.Sprintf(" | %d new since %s", m.newCount, render.TimeAgo(m.visit.VisitedAt.Unix()))
+		}
+		parts = append(parts, storyMetaStyle.Render(meta))
 		if m.story.URL != "" {
 			if u, err := url.Parse(m.story.URL); err == nil {
 				parts = append(parts, storyURLStyle.Render(u.Host))
@@ -597,12 +773,16 @@ func (m Model) renderHeader() string {
 			}
 			parts = append(parts, storyMetaStyle.Render(render.HNToText(m.story.Text, bodyWidth)))
 		}
+		parts = append(parts, m.renderPoll()...)
 	} else if m.story.Type == "comment" {
 		// Comment root — minimal header since the full text is in the list.
 		meta := fmt.Sprintf("Comment by %s | %s", m.story.By, render.TimeAgo(m.story.Time))
 		kids := m.story.Kids()
 		if len(kids) > 0 {
-			meta += fmt.Sprintf(" | %d replies", len(kids))
+			meta += fmt.Sprintf(" | %d replies | %s", len(kids), m.commentCount())
+		}
+		if m.newCount > 0 {
+			meta += fmt.Sprintf(" | %d new", m.newCount)
 		}
 		parts = append(parts, storyMetaStyle.Render(meta))
 	} else {
@@ -610,11 +790,46 @@ func (m Model) renderHeader() string {
 	}
 
 	parts = append(parts, separatorStyle.Render(strings.Repeat("─", m.width)))
-	hint := commentMetaStyle.Render("j/k:move  h/l:parent/child  ]:sibling  ^:root  enter:open  space:collapse  z:fold all  r:reply  P:profile")
-	parts = append(parts, hint)
+	km := keys.Keys.Story
+	hint := keys.ShortHelp(km.Parent, km.Child, km.NextSibling, km.Collapse, km.Reply, km.Mute)
+	if m.newCount > 0 {
+		hint += "  " + keys.ShortHelp(km.NextNew)
+	}
+	if len(m.pollOpts) > 0 {
+		hint += "  " + keys.ShortHelp(km.Vote)
+	}
+	hint += "  " + keys.ShortHelp(keys.Keys.Global.Help)
+	parts = append(parts, commentMetaStyle.Render(hint))
 	return lipgloss.JoinVertical(lipgloss.Left, parts...)
 }
 
+// exportStory writes the story's full comment tree to cfg.ExportDir.
+func exportStory(client *api.Client, db *cache.DB, cfg config.Config, storyID int) tea.Cmd {
+	return func() tea.Msg {
+		format, err := export.ParseFormat(cfg.ExportFormat)
+		if err != nil {
+			return messages.StatusMsg{Text: err.Error(), IsError: true}
+		}
+		if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+			return messages.StatusMsg{Text: "Export failed: " + err.Error(), IsError: true}
+		}
+		path := filepath.Join(cfg.ExportDir, fmt.Sprintf("story-%d.%s", storyID, format))
+		f, err := os.Create(path)
+		if err != nil {
+			return messages.StatusMsg{Text: "Export failed: " + err.Error(), IsError: true}
+		}
+		err = export.Export(context.Background(), client, db, cfg, storyID, f, format)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return messages.StatusMsg{Text: "Export failed: " + err.Error(), IsError: true}
+		}
+		return messages.StatusMsg{Text: "Exported to " + path}
+	}
+}
+
 func openURL(u string) tea.Cmd {
 	return func() tea.Msg {
 		// Use 'open' on macOS, 'xdg-open' on Linux.
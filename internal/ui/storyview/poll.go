@@ -0,0 +1,179 @@
+package storyview
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/api"
+	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
+)
+
+const pollBarWidth = 20
+
+var pollBarStyle, pollEmptyStyle, pollChosenStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		pollBarStyle = lipgloss.NewStyle().Foreground(t.Accent)
+		pollEmptyStyle = lipgloss.NewStyle().Foreground(t.Border)
+		pollChosenStyle = lipgloss.NewStyle().Background(t.Selection).Bold(true)
+	})
+}
+
+// fetchPollOptions caches a poll's options. Options are refetched when
+// refresh is set or they have gone stale, so scores stay current; ones
+// that fail to fetch keep their cached copy.
+func fetchPollOptions(ctx context.Context, client *api.Client, db *cache.DB, cfg config.Config, parts []int, refresh bool) {
+	var missing []int
+	for _, id := range parts {
+		if item, fresh, _ := db.GetItem(id, cfg.ItemTTL); item == nil || !fresh || refresh {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	items, _ := client.BatchGetItems(ctx, missing)
+	for _, item := range items {
+		if item != nil {
+			db.PutItem(item)
+		}
+	}
+}
+
+// loadPollOptions reads the story's poll options from the cache, in the
+// order HN lists them.
+func (m *Model) loadPollOptions() {
+	m.pollOpts = nil
+	if m.story == nil {
+		return
+	}
+	for _, id := range m.story.Parts() {
+		if item, _, _ := m.cache.GetItem(id, m.cfg.ItemTTL); item != nil {
+			m.pollOpts = append(m.pollOpts, item)
+		}
+	}
+	if m.pollIdx >= len(m.pollOpts) {
+		m.pollIdx = 0
+	}
+}
+
+// Choosing reports whether the view is waiting for a poll option to vote
+// for, in which case it handles digits and Esc itself.
+func (m Model) Choosing() bool {
+	return m.choosing
+}
+
+// updateChoosing handles keys while picking a poll option: up/down or a
+// digit to select, enter to vote, esc to cancel.
+func (m Model) updateChoosing(msg tea.KeyMsg) (Model, tea.Cmd) {
+	km := keys.Keys.Poll
+	switch {
+	case key.Matches(msg, keys.Keys.Nav.Down):
+		if m.pollIdx < len(m.pollOpts)-1 {
+			m.pollIdx++
+		}
+	case key.Matches(msg, keys.Keys.Nav.Up):
+		if m.pollIdx > 0 {
+			m.pollIdx--
+		}
+	case key.Matches(msg, km.Vote):
+		m.choosing = false
+		m.resizeViewport()
+		opt := m.pollOpts[m.pollIdx]
+		pollID := m.story.ID
+		return m, func() tea.Msg { return messages.VoteMsg{ItemID: opt.ID, Poll: pollID} }
+	case key.Matches(msg, km.Cancel):
+		m.choosing = false
+		m.resizeViewport()
+	case key.Matches(msg, km.Pick):
+		// Pick's keys are the option numbers, 1-9 by default.
+		for i, k := range km.Pick.Keys() {
+			if msg.String() == k && i < len(m.pollOpts) {
+				m.pollIdx = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// votedOption bumps an option's score after a successful vote, since the
+// API takes a while to reflect it.
+func (m *Model) votedOption(id int) {
+	for _, opt := range m.pollOpts {
+		if opt.ID == id {
+			opt.Score++
+			m.resizeViewport()
+			return
+		}
+	}
+}
+
+// renderPoll draws one line per option with a bar sized by its share of
+// the votes.
+func (m Model) renderPoll() []string {
+	if len(m.pollOpts) == 0 {
+		if len(m.story.Parts()) > 0 {
+			return []string{storyMetaStyle.Render("Poll options unavailable")}
+		}
+		return nil
+	}
+
+	total := 0
+	for _, opt := range m.pollOpts {
+		total += opt.Score
+	}
+	labelWidth := 0
+	labels := make([]string, len(m.pollOpts))
+	for i, opt := range m.pollOpts {
+		labels[i] = strings.Join(strings.Fields(render.HNToPlainText(opt.Text)), " ")
+		labelWidth = max(labelWidth, lipgloss.Width(labels[i]))
+	}
+	// Number, bar, percentage and score take about 40 columns.
+	labelWidth = max(min(labelWidth, m.width-40), 10)
+
+	var lines []string
+	for i, opt := range m.pollOpts {
+		share := 0.0
+		if total > 0 {
+			share = float64(opt.Score) / float64(total)
+		}
+		filled := int(share*pollBarWidth + 0.5)
+		bar := pollBarStyle.Render(strings.Repeat("█", filled)) +
+			pollEmptyStyle.Render(strings.Repeat("░", pollBarWidth-filled))
+
+		label := truncate(labels[i], labelWidth)
+		label += strings.Repeat(" ", labelWidth-lipgloss.Width(label))
+		line := fmt.Sprintf("%2d. %s %s %3.0f%% %s", i+1, label, bar, share*100,
+			commentMetaStyle.Render(fmt.Sprintf("%d points", opt.Score)))
+		if m.choosing && i == m.pollIdx {
+			line = pollChosenStyle.Render(line)
+		}
+		lines = append(lines, " "+line)
+	}
+	if m.choosing {
+		lines = append(lines, commentMetaStyle.Render(" "+keys.ShortHelp(keys.Keys.Nav.Up, keys.Keys.Nav.Down, keys.Keys.Poll.Pick, keys.Keys.Poll.Vote, keys.Keys.Poll.Cancel)))
+	}
+	return lines
+}
+
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && lipgloss.Width(string(r))+1 > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
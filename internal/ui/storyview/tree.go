@@ -1,6 +1,7 @@
 package storyview
 
 import (
+	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/config"
 )
@@ -8,31 +9,49 @@ import (
 // CollapseState tracks collapsed comment IDs.
 type CollapseState map[int]bool
 
-// FlattenTree converts a nested comment tree into a flat list for display.
-func FlattenTree(rootKids []int, opUser string, db *cache.DB, cfg config.Config, cs CollapseState) []FlatComment {
+// FlattenTree converts root's comment tree into a flat list for display.
+// Comments by authors muted in kf start collapsed; an explicit entry in cs
+// overrides that. Comments in failed that aren't cached become stubs;
+// other uncached comments are left out.
+func FlattenTree(root *api.Item, db *cache.DB, cfg config.Config, cs CollapseState, kf *cache.Killfile, failed map[int]bool) []FlatComment {
 	var result []FlatComment
+	opUser := root.By
 
 	// walk returns the total descendant count for this subtree.
-	var walk func(itemID int, depth int) int
-	walk = func(itemID int, depth int) int {
+	var walk func(itemID, parentID, depth int) int
+	walk = func(itemID, parentID, depth int) int {
 		item, _, _ := db.GetItem(itemID, cfg.CommentTTL)
 		if item == nil {
+			if failed[itemID] {
+				result = append(result, FlatComment{
+					Item:   &api.Item{ID: itemID, Parent: parentID},
+					Depth:  depth,
+					Failed: true,
+				})
+			}
 			return 0
 		}
 
+		muted := kf.MutesUser(item.By)
+		collapsed, ok := cs[item.ID]
+		if !ok {
+			collapsed = muted
+		}
+
 		idx := len(result)
 		// Append placeholder; we'll fill ChildCount after walking children.
 		result = append(result, FlatComment{
 			Item:        item,
 			Depth:       depth,
-			IsCollapsed: cs[item.ID],
+			IsCollapsed: collapsed,
 			IsOP:        item.By == opUser && opUser != "",
+			IsMuted:     muted,
 		})
 
 		descendants := 0
-		if !cs[item.ID] {
+		if !collapsed {
 			for _, kidID := range item.Kids() {
-				descendants += 1 + walk(kidID, depth+1)
+				descendants += 1 + walk(kidID, item.ID, depth+1)
 			}
 		} else {
 			// Collapsed: count kids without walking (for the [+N] badge).
@@ -43,8 +62,8 @@ func FlattenTree(rootKids []int, opUser string, db *cache.DB, cfg config.Config,
 		return descendants
 	}
 
-	for _, kidID := range rootKids {
-		walk(kidID, 0)
+	for _, kidID := range root.Kids() {
+		walk(kidID, root.ID, 0)
 	}
 	return result
 }
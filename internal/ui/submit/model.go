@@ -3,20 +3,27 @@ package submit
 import (
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/fragmede/nitpick/internal/auth"
+	"github.com/fragmede/nitpick/internal/ui/keys"
 	"github.com/fragmede/nitpick/internal/ui/messages"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
-var (
-	titleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true)
-	labelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true).Width(8)
-	hintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282"))
-	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
-)
+var titleStyle, labelStyle, hintStyle, errorStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true)
+		labelStyle = lipgloss.NewStyle().Foreground(t.Title).Bold(true).Width(8)
+		hintStyle = lipgloss.NewStyle().Foreground(t.Meta)
+		errorStyle = lipgloss.NewStyle().Foreground(t.Error)
+	})
+}
 
 type field int
 
@@ -83,14 +90,15 @@ func (m *Model) SetSize(w, h int) {
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "tab":
+		form := keys.Keys.Form
+		switch {
+		case key.Matches(msg, form.NextField):
 			m.focused = (m.focused + 1) % 3
 			return m, m.updateFocus()
-		case "shift+tab":
+		case key.Matches(msg, form.PrevField):
 			m.focused = (m.focused + 2) % 3
 			return m, m.updateFocus()
-		case "ctrl+s":
+		case key.Matches(msg, form.Send):
 			title := strings.TrimSpace(m.titleInput.Value())
 			if title == "" {
 				m.err = "Title is required"
@@ -171,10 +179,12 @@ func (m Model) View() string {
 		sb.WriteString("\n")
 	}
 
+	form := keys.Keys.Form
 	if m.submitting {
 		sb.WriteString("Submitting...")
 	} else {
-		sb.WriteString(hintStyle.Render("Tab to switch fields | Ctrl+S to submit | Esc to cancel"))
+		sb.WriteString(hintStyle.Render(form.NextField.Help().Key + " to switch fields | " +
+			form.Send.Help().Key + " to submit | " + form.Cancel.Help().Key + " to cancel"))
 	}
 
 	content := sb.String()
@@ -0,0 +1,311 @@
+// Package theme holds the UI's color palette. Views build their styles
+// from the current theme in a function registered with Apply, so a theme
+// chosen at startup reaches every view.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/fragmede/nitpick/internal/config"
+)
+
+// Theme is a set of colors by role. The theme tags are the keys used in
+// theme files.
+type Theme struct {
+	Name string
+
+	Accent    lipgloss.Color `theme:"accent"`    // HN orange: titles, authors, key hints
+	OnAccent  lipgloss.Color `theme:"on_accent"` // text on an accent background, e.g. the OP badge
+	Title     lipgloss.Color `theme:"title"`     // story titles and headings
+	Text      lipgloss.Color `theme:"text"`      // body text such as notes and previews
+	Meta      lipgloss.Color `theme:"meta"`      // points, ages, domains, labels
+	Dim       lipgloss.Color `theme:"dim"`       // hints and secondary metadata
+	Faint     lipgloss.Color `theme:"faint"`     // separators and deleted comments
+	Border    lipgloss.Color `theme:"border"`    // unselected borders and empty bars
+	Selection lipgloss.Color `theme:"selection"` // background of the selected row
+	Highlight lipgloss.Color `theme:"highlight"` // selected borders and tags
+	New       lipgloss.Color `theme:"new"`       // NEW badges
+	Error     lipgloss.Color `theme:"error"`     // errors and unread markers
+	Bar       lipgloss.Color `theme:"bar"`       // status bar background
+	BarText   lipgloss.Color `theme:"bar_text"`  // status bar text
+	BarDim    lipgloss.Color `theme:"bar_dim"`   // status bar messages
+	Tab       lipgloss.Color `theme:"tab"`       // inactive tab background
+	TabText   lipgloss.Color `theme:"tab_text"`  // inactive tab text
+	OnTab     lipgloss.Color `theme:"on_tab"`    // active tab text, on Accent
+	User      lipgloss.Color `theme:"user"`      // logged-in username
+	Offline   lipgloss.Color `theme:"offline"`   // offline badge background
+	OnAlert   lipgloss.Color `theme:"on_alert"`  // text on Error and Offline backgrounds
+
+	// Depth colors the bars of nested comments, cycling by depth.
+	Depth []lipgloss.Color `theme:"depth"`
+}
+
+// DepthColor returns the bar color for a comment at depth.
+func (t Theme) DepthColor(depth int) lipgloss.Color {
+	return t.Depth[depth%len(t.Depth)]
+}
+
+// Dark is the default theme, for dark terminal backgrounds.
+var Dark = Theme{
+	Name:      "dark",
+	Accent:    "#FF6600",
+	OnAccent:  "#000000",
+	Title:     "#FFFFFF",
+	Text:      "#CCCCCC",
+	Meta:      "#828282",
+	Dim:       "#666666",
+	Faint:     "#555555",
+	Border:    "#444444",
+	Selection: "#333333",
+	Highlight: "#00BFFF",
+	New:       "#32CD32",
+	Error:     "#FF0000",
+	Bar:       "#333333",
+	BarText:   "#FFFFFF",
+	BarDim:    "#AAAAAA",
+	Tab:       "#555555",
+	TabText:   "#CCCCCC",
+	OnTab:     "#FFFFFF",
+	User:      "#00FF00",
+	Offline:   "#8B0000",
+	OnAlert:   "#FFFFFF",
+	Depth:     []lipgloss.Color{"#FF6600", "#828282", "#00BFFF", "#32CD32", "#FFD700", "#FF69B4", "#9370DB", "#20B2AA"},
+}
+
+// Light is for light terminal backgrounds, where Dark's white titles
+// can't be seen.
+var Light = Theme{
+	Name:      "light",
+	Accent:    "#D35400",
+	OnAccent:  "#FFFFFF",
+	Title:     "#000000",
+	Text:      "#333333",
+	Meta:      "#666666",
+	Dim:       "#777777",
+	Faint:     "#999999",
+	Border:    "#BBBBBB",
+	Selection: "#E4E4E4",
+	Highlight: "#0070C0",
+	New:       "#2E8B57",
+	Error:     "#CC0000",
+	Bar:       "#E0E0E0",
+	BarText:   "#000000",
+	BarDim:    "#555555",
+	Tab:       "#C8C8C8",
+	TabText:   "#333333",
+	OnTab:     "#FFFFFF",
+	User:      "#006400",
+	Offline:   "#CC0000",
+	OnAlert:   "#FFFFFF",
+	Depth:     []lipgloss.Color{"#D35400", "#777777", "#0070C0", "#2E8B57", "#B8860B", "#C71585", "#6A5ACD", "#008B8B"},
+}
+
+// HighContrast uses only bright, saturated colors on black.
+var HighContrast = Theme{
+	Name:      "high-contrast",
+	Accent:    "#FFFF00",
+	OnAccent:  "#000000",
+	Title:     "#FFFFFF",
+	Text:      "#FFFFFF",
+	Meta:      "#D0D0D0",
+	Dim:       "#C0C0C0",
+	Faint:     "#A0A0A0",
+	Border:    "#FFFFFF",
+	Selection: "#0000AA",
+	Highlight: "#00FFFF",
+	New:       "#00FF00",
+	Error:     "#FF5555",
+	Bar:       "#000000",
+	BarText:   "#FFFFFF",
+	BarDim:    "#FFFFFF",
+	Tab:       "#444444",
+	TabText:   "#FFFFFF",
+	OnTab:     "#000000",
+	User:      "#00FF00",
+	Offline:   "#FF0000",
+	OnAlert:   "#FFFFFF",
+	Depth:     []lipgloss.Color{"#FFFF00", "#00FFFF", "#FF00FF", "#00FF00", "#FFFFFF", "#FF8800", "#5599FF", "#FF5555"},
+}
+
+// ANSI16 uses the terminal's own 16-color palette, and its default
+// foreground for text, so it follows the terminal's color scheme.
+var ANSI16 = Theme{
+	Name:      "16color",
+	Accent:    "3",
+	OnAccent:  "0",
+	Title:     "",
+	Text:      "",
+	Meta:      "8",
+	Dim:       "8",
+	Faint:     "8",
+	Border:    "8",
+	Selection: "4",
+	Highlight: "6",
+	New:       "2",
+	Error:     "1",
+	Bar:       "8",
+	BarText:   "15",
+	BarDim:    "7",
+	Tab:       "0",
+	TabText:   "7",
+	OnTab:     "0",
+	User:      "10",
+	Offline:   "1",
+	OnAlert:   "15",
+	Depth:     []lipgloss.Color{"3", "8", "6", "2", "11", "5", "4", "14"},
+}
+
+// Builtin are the themes selectable by name.
+var Builtin = map[string]Theme{
+	Dark.Name:         Dark,
+	Light.Name:        Light,
+	HighContrast.Name: HighContrast,
+	ANSI16.Name:       ANSI16,
+}
+
+var (
+	current  = Dark
+	appliers []func(Theme)
+)
+
+// Current returns the active theme.
+func Current() Theme {
+	return current
+}
+
+// Apply calls fn with the current theme now and again whenever Set
+// changes it. View packages use it to build their styles.
+func Apply(fn func(Theme)) {
+	appliers = append(appliers, fn)
+	fn(current)
+}
+
+// Set makes t the current theme and rebuilds every registered style.
+func Set(t Theme) {
+	current = t
+	for _, fn := range appliers {
+		fn(t)
+	}
+}
+
+// Detect picks Dark or Light from the terminal's background color. It
+// queries the terminal, so call it only before the UI starts.
+func Detect() Theme {
+	if lipgloss.HasDarkBackground() {
+		return Dark
+	}
+	return Light
+}
+
+// Load resolves the theme setting: "auto" detects the background, a
+// built-in name selects that theme, a path loads a theme file, and any
+// other name loads NAME.toml from dir.
+func Load(name, dir string) (Theme, error) {
+	if name == "" || name == "auto" {
+		return Detect(), nil
+	}
+	if t, ok := Builtin[name]; ok {
+		return t, nil
+	}
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) && filepath.Ext(name) != ".toml" {
+		path = filepath.Join(dir, name+".toml")
+	}
+	t, err := LoadFile(path)
+	if os.IsNotExist(err) {
+		return Theme{}, fmt.Errorf("unknown theme %q (want auto, %s, or a file in %s)", name, strings.Join(builtinNames(), ", "), dir)
+	}
+	return t, err
+}
+
+// LoadFile reads a theme file: color = "value" lines using the theme tag
+// names, over the built-in theme named by base (default dark). Colors
+// are #RRGGBB, #RGB or an ANSI color number.
+func LoadFile(path string) (Theme, error) {
+	values, err := config.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	t := Dark
+	if base, ok := values["base"]; ok {
+		name, _ := base.(string)
+		b, ok := Builtin[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("%s: unknown base theme %q (want %s)", path, name, strings.Join(builtinNames(), ", "))
+		}
+		t = b
+		delete(values, "base")
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	t.Depth = append([]lipgloss.Color(nil), t.Depth...)
+
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(&t).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if tag := v.Type().Field(i).Tag.Get("theme"); tag != "" {
+			fields[tag] = v.Field(i)
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		field, ok := fields[k]
+		if !ok {
+			return Theme{}, fmt.Errorf("%s: unknown color %q", path, k)
+		}
+		if err := setColor(field, values[k]); err != nil {
+			return Theme{}, fmt.Errorf("%s: %s: %w", path, k, err)
+		}
+	}
+	return t, nil
+}
+
+func setColor(field reflect.Value, value interface{}) error {
+	if field.Kind() == reflect.Slice {
+		list, ok := value.([]string)
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("expected a non-empty list of colors")
+		}
+		colors := make([]lipgloss.Color, len(list))
+		for i, s := range list {
+			if !validColor(s) {
+				return fmt.Errorf("invalid color %q", s)
+			}
+			colors[i] = lipgloss.Color(s)
+		}
+		field.Set(reflect.ValueOf(colors))
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok || !validColor(s) {
+		return fmt.Errorf("invalid color %v (use #RRGGBB, #RGB or 0-255)", value)
+	}
+	field.Set(reflect.ValueOf(lipgloss.Color(s)))
+	return nil
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+func validColor(s string) bool {
+	if hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func builtinNames() []string {
+	return []string{Dark.Name, Light.Name, HighContrast.Name, ANSI16.Name}
+}
@@ -12,14 +12,19 @@ import (
 	"github.com/fragmede/nitpick/internal/cache"
 	"github.com/fragmede/nitpick/internal/config"
 	"github.com/fragmede/nitpick/internal/render"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 )
 
-var (
-	titleStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6600")).Bold(true).Padding(1, 0)
-	labelStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#828282")).Bold(true)
-	valueStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-	aboutStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#CCCCCC")).Padding(1, 0)
-)
+var titleStyle, labelStyle, valueStyle, aboutStyle lipgloss.Style
+
+func init() {
+	theme.Apply(func(t theme.Theme) {
+		titleStyle = lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Padding(1, 0)
+		labelStyle = lipgloss.NewStyle().Foreground(t.Meta).Bold(true)
+		valueStyle = lipgloss.NewStyle().Foreground(t.Title)
+		aboutStyle = lipgloss.NewStyle().Foreground(t.Text).Padding(1, 0)
+	})
+}
 
 type userLoadedMsg struct {
 	User *api.User
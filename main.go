@@ -2,24 +2,66 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 
 	"github.com/fragmede/nitpick/internal/api"
 	"github.com/fragmede/nitpick/internal/cache"
+	"github.com/fragmede/nitpick/internal/cli"
 	"github.com/fragmede/nitpick/internal/config"
+	"github.com/fragmede/nitpick/internal/transport"
 	"github.com/fragmede/nitpick/internal/ui"
+	"github.com/fragmede/nitpick/internal/ui/keys"
+	"github.com/fragmede/nitpick/internal/ui/theme"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	cfg := config.Default()
+	os.Exit(run())
+}
+
+// run starts nitpick and returns its exit code. Deferred cleanup, like
+// closing the cache so buffered access times are written, runs before
+// main exits.
+func run() int {
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		config.PrintUsage(os.Stdout)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nitpick: %v\n", err)
+		return 2
+	}
+	km, err := keys.Load(cfg.KeyPreset, cfg.KeyBindings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nitpick: config: %v\n", err)
+		return 2
+	}
+	keys.Keys = km
+	if len(args) > 0 && !cli.IsCommand(args[0]) {
+		fmt.Fprintf(os.Stderr, "nitpick: unknown command %q (see nitpick help)\n", args[0])
+		return 2
+	}
 
 	if err := os.MkdirAll(cfg.CacheDir, 0o755); err != nil {
 		log.Fatalf("creating cache dir: %v", err)
 	}
+	if cfg.Replay != "" {
+		dir, err := os.MkdirTemp("", "nitpick-replay-")
+		if err != nil {
+			log.Fatalf("creating replay dir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		isolate(&cfg, dir)
+	}
 
 	db, err := cache.Open(cfg.DBPath)
 	if err != nil {
@@ -27,7 +69,30 @@ func main() {
 	}
 	defer db.Close()
 
-	client := api.NewClient()
+	rt, rec, err := httpTransport(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nitpick: %v\n", err)
+		return 2
+	}
+	if rec != nil {
+		defer reportRecording(rec)
+	}
+	client := api.NewClient(api.Options{
+		Endpoints: api.Endpoints{Firebase: cfg.FirebaseURL, Algolia: cfg.AlgoliaURL, HN: cfg.HNURL},
+		Transport: rt,
+	})
+
+	if len(args) > 0 {
+		return runCommand(cfg, client, db, args)
+	}
+
+	// Pick colors before the UI starts; "auto" queries the terminal.
+	th, err := theme.Load(cfg.Theme, config.ThemeDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nitpick: config: %v\n", err)
+		return 2
+	}
+	theme.Set(th)
 
 	// Prefetch top stories into cache on startup.
 	go prefetch(client, db)
@@ -42,8 +107,63 @@ func main() {
 	app.SetProgram(p)
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+	return 0
+}
+
+// httpTransport returns the transport for the API client and login
+// session: a replayed cassette, the network with a recorder in front, or
+// just the network. rec is set when recording.
+func httpTransport(cfg config.Config) (rt http.RoundTripper, rec *transport.Recorder, err error) {
+	switch {
+	case cfg.Record != "" && cfg.Replay != "":
+		return nil, nil, errors.New("--record and --replay can't be used together")
+	case cfg.Replay != "":
+		r, err := transport.NewReplayer(cfg.Replay)
+		if err != nil {
+			return nil, nil, fmt.Errorf("replay: %w", err)
+		}
+		return r, nil, nil
+	case cfg.Record != "":
+		rec, err := transport.NewRecorder(cfg.Record, transport.Default)
+		if err != nil {
+			return nil, nil, fmt.Errorf("record: %w", err)
+		}
+		return rec, rec, nil
+	}
+	return transport.Default, nil, nil
+}
+
+// isolate points the cache and the saved session at dir, so a replayed
+// session starts empty and leaves the real ones as they were.
+func isolate(cfg *config.Config, dir string) {
+	cfg.DBPath = filepath.Join(dir, "cache.db")
+	cfg.SessionPath = filepath.Join(dir, "session.json")
+}
+
+// reportRecording closes the cassette and says where it is.
+func reportRecording(rec *transport.Recorder) {
+	rec.Close()
+	fmt.Fprintf(os.Stderr, "nitpick: recorded %d requests to %s\n", rec.Count(), rec.Path())
+}
+
+// runCommand runs a non-interactive subcommand and returns the exit code.
+func runCommand(cfg config.Config, client *api.Client, db *cache.DB, args []string) int {
+	if f, err := os.OpenFile(cfg.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+		log.SetOutput(f)
+		defer f.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env := &cli.Env{Cfg: cfg, Client: client, DB: db, Out: os.Stdout}
+	if err := cli.Run(ctx, env, args); err != nil {
+		fmt.Fprintf(os.Stderr, "nitpick: %v\n", err)
+		return 1
 	}
+	return 0
 }
 
 func prefetch(client *api.Client, db *cache.DB) {